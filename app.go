@@ -1,27 +1,56 @@
 package main
 
 import (
+	"bufio"
 	"database/sql"
 	"encoding/json"
+	"errors"
+	"expvar"
+	"flag"
 	"fmt"
-	"io/ioutil"
+	"io"
 	"log"
+	"net"
 	"net/http"
-	"net/url"
 	"os"
-	"regexp"
+	"path/filepath"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
-	_ "github.com/go-sql-driver/mysql"
+	"github.com/asticode/go-astisub"
+	"github.com/go-sql-driver/mysql"
 	"github.com/maxence-charriere/go-app/v7/pkg/app"
 )
 
 const (
 	Server = "mokky:mokky04120@@tcp(127.0.0.1)/jamak_history"
 	Port   = 8080
+
+	// 기록 테이블의 ip 칸 크기, IPv6 (IPv4 가 섞인 모양 포함) 가장 긴 주소가 45자
+	historyIPLen = 45
+
+	// DataDirEnv 환경 변수로 데이터 폴더를 바꿀 수 있다, 자막, 영상, 계정과 설정 파일이 모두 이 아래에 있다
+	DataDirEnv     = "JAMAK_DATA_DIR"
+	DefaultDataDir = "/home/ubuntu/jamak"
+)
+
+var (
+	DataDir     = dataDir()
+	MediaDir    = filepath.Join(DataDir, "media")
+	SubtitleDir = filepath.Join(DataDir, "subtitle")
+	GlossaryDir = filepath.Join(DataDir, "glossary")
 )
 
+func dataDir() string {
+	if dir := os.Getenv(DataDirEnv); len(dir) != 0 {
+		return filepath.Clean(dir)
+	}
+
+	return DefaultDataDir
+}
+
 type Subdomains map[string]http.Handler
 
 type ErrorJSON struct {
@@ -29,19 +58,46 @@ type ErrorJSON struct {
 	Code int    `json:"code"`
 }
 
+type RateLimitJSON struct {
+	Msg        string `json:"msg"`
+	Code       int    `json:"code"`
+	RetryAfter int    `json:"retryAfter"`
+}
+
 type YoutubeJSON struct {
-	URL  string `json:"url"`
-	Code int    `json:"code"`
+	URL     string   `json:"url"`
+	Streams []Stream `json:"streams"`
+	Code    int      `json:"code"`
 }
 
 type SubtitleJSON struct {
 	Subtitle string `json:"subtitle"`
+	Version  string `json:"version"`
+	Draft    string `json:"draft,omitempty"`
 	Code     int    `json:"code"`
 }
 
+// SaveJSON 의 Pending 은 검토 대기로 올라간 저장의 ID, 이때 Version 은 그대로인 게시 버전
 type SaveJSON struct {
-	Version string `json:"version"`
-	Code    int    `json:"code"`
+	Version     string   `json:"version"`
+	Pending     string   `json:"pending,omitempty"`
+	Quarantined string   `json:"quarantined,omitempty"`
+	Reasons     []string `json:"reasons,omitempty"`
+	Code        int      `json:"code"`
+}
+
+type VideoJSON struct {
+	URL      string         `json:"url"`
+	Streams  []Stream       `json:"streams"`
+	Metadata *VideoMetadata `json:"metadata"`
+	Code     int            `json:"code"`
+}
+
+type VadJSON struct {
+	Subtitle string          `json:"subtitle"`
+	Segments []SpeechSegment `json:"segments"`
+	Snapped  int             `json:"snapped"`
+	Code     int             `json:"code"`
 }
 
 func (subdomains Subdomains) ServeHTTP(w http.ResponseWriter, r *http.Request) {
@@ -55,25 +111,98 @@ func (subdomains Subdomains) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 }
 
 func Error(w http.ResponseWriter, err error, code int) {
-	w.WriteHeader(http.StatusBadRequest)
+	ErrorStatus(w, http.StatusBadRequest, err, code)
+}
+
+func ErrorStatus(w http.ResponseWriter, status int, err error, code int) {
+	w.WriteHeader(status)
 	_ = json.NewEncoder(w).Encode(ErrorJSON{
 		Msg:  err.Error(),
 		Code: code,
 	})
 }
 
-func AddSubtitle(id, ip, lang string) string {
-	database, err := sql.Open("mysql", Server)
+// RateLimited 는 429 와 함께 Retry-After 헤더와 retryAfter (초) 를 돌려준다
+func RateLimited(w http.ResponseWriter, call string, wait time.Duration) {
+	seconds := RetryAfterSeconds(wait)
+
+	w.Header().Set("Retry-After", strconv.Itoa(seconds))
+	w.WriteHeader(http.StatusTooManyRequests)
+	_ = json.NewEncoder(w).Encode(RateLimitJSON{
+		Msg:        fmt.Sprintf("too many %s requests, retry after %d seconds", call, seconds),
+		Code:       4,
+		RetryAfter: seconds,
+	})
+}
+
+// historyDriver 는 저장 기록 DB 의 드라이버, 테스트에서는 가짜 드라이버로 바꾼다
+var historyDriver = "mysql"
+
+// historyTable 은 영상마다 있는 기록 테이블 이름, 테이블 이름은 ? 로 넘길 수 없어서 다시 확인하고 백틱으로 감싼다
+func historyTable(id string) (string, error) {
+	if !mediaIDRegx.MatchString(id) {
+		return "", fmt.Errorf("invalid id: %s", id)
+	}
+
+	return "`" + id + "`", nil
+}
+
+// historyMigrated 는 이 서버가 뜬 뒤 만들거나 고친 기록 테이블, 저장할 때마다 테이블을 고치지 않도록 한 번만 한다
+var historyMigrated sync.Map
+
+// migrateHistoryTable 은 기록 테이블이 없으면 만들고, 예전 모양의 테이블이면 지금 모양으로 고친다
+func migrateHistoryTable(database *sql.DB, id, table string) error {
+	_, err := database.Exec(fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (version int(10) NOT NULL AUTO_INCREMENT PRIMARY KEY, ip varchar(%d) NOT NULL, date DATETIME NOT NULL, lang varchar(15) NOT NULL, user varchar(32) NOT NULL DEFAULT '') DEFAULT CHARACTER SET utf8 COLLATE utf8_general_ci`, table, historyIPLen))
+	if err != nil {
+		return err
+	}
+
+	// user 칸이 생기기 전에 만든 테이블은 user 를 더하면서 ip 칸도 IPv6 가 들어가게 늘린다
+	_, err = database.Exec(fmt.Sprintf(`ALTER TABLE %s ADD COLUMN user varchar(32) NOT NULL DEFAULT '', MODIFY ip varchar(%d) NOT NULL`, table, historyIPLen))
+	var mysqlErr *mysql.MySQLError
+	if !errors.As(err, &mysqlErr) || mysqlErr.Number != 1060 {
+		return err
+	}
+
+	// user 칸은 있지만 ip 칸이 15자일 때 만든 테이블
+	var ipLen int
+	err = database.QueryRow(`SELECT CHARACTER_MAXIMUM_LENGTH FROM information_schema.COLUMNS WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = ? AND COLUMN_NAME = 'ip'`, id).Scan(&ipLen)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if ipLen < historyIPLen {
+		_, err = database.Exec(fmt.Sprintf(`ALTER TABLE %s MODIFY ip varchar(%d) NOT NULL`, table, historyIPLen))
+	}
+
+	return err
+}
+
+// AddSubtitle 은 저장 기록을 남긴다, 로그인하지 않은 저장은 user 가 비어 있고 서버가 본 IP 만 남는다
+func AddSubtitle(id, user, ip, lang string) string {
+	table, err := historyTable(id)
 	if err != nil {
 		fmt.Println(err)
+		return ""
 	}
 
-	_, err = database.Exec(fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (version int(10) NOT NULL AUTO_INCREMENT PRIMARY KEY, ip varchar(15) NOT NULL, date DATETIME NOT NULL, lang varchar(15) NOT NULL) DEFAULT CHARACTER SET utf8 COLLATE utf8_general_ci`, id))
+	database, err := sql.Open(historyDriver, Server)
 	if err != nil {
 		fmt.Println(err)
 	}
 
-	_, err = database.Exec(fmt.Sprintf(`INSERT INTO %s (ip, date, lang) VALUE ('%s', '%s', '%s')`, id, ip, time.Now().Format("2006-01-02 15:04:05"), lang))
+	if _, migrated := historyMigrated.Load(table); !migrated {
+		if err := migrateHistoryTable(database, id, table); err != nil {
+			fmt.Println(err)
+		} else {
+			historyMigrated.Store(table, true)
+		}
+	}
+
+	_, err = database.Exec(`INSERT INTO `+table+` (ip, date, lang, user) VALUES (?, ?, ?, ?)`, ip, time.Now().Format("2006-01-02 15:04:05"), lang, user)
 	if err != nil {
 		fmt.Println(err)
 	}
@@ -84,11 +213,17 @@ func AddSubtitle(id, ip, lang string) string {
 }
 
 func GetLastVersion(id string) int {
-	database, _ := sql.Open("mysql", Server)
+	table, err := historyTable(id)
+	if err != nil {
+		fmt.Println(err)
+		return 0
+	}
+
+	database, _ := sql.Open(historyDriver, Server)
 	defer database.Close()
 
 	var version int
-	err := database.QueryRow(fmt.Sprintf(`SELECT version FROM %s ORDER BY version DESC LIMIT 1;`, id)).Scan(&version)
+	err = database.QueryRow(`SELECT version FROM ` + table + ` ORDER BY version DESC LIMIT 1`).Scan(&version)
 	if err != nil {
 		fmt.Println(err)
 	}
@@ -96,10 +231,94 @@ func GetLastVersion(id string) int {
 	return version
 }
 
+// GetLastLangVersion 은 그 언어로 마지막에 저장한 버전, 저장한 적이 없으면 0
+func GetLastLangVersion(id, lang string) int {
+	table, err := historyTable(id)
+	if err != nil {
+		fmt.Println(err)
+		return 0
+	}
+
+	database, _ := sql.Open(historyDriver, Server)
+	defer database.Close()
+
+	var version int
+	err = database.QueryRow(`SELECT version FROM `+table+` WHERE lang = ? ORDER BY version DESC LIMIT 1`, lang).Scan(&version)
+	if err != nil && err != sql.ErrNoRows {
+		fmt.Println(err)
+	}
+
+	return version
+}
+
+// GetHistory 는 저장 횟수와 마지막으로 저장한 사람(로그인하지 않았으면 가린 IP)을 가져온다
+func GetHistory(id string) (int, string) {
+	table, err := historyTable(id)
+	if err != nil {
+		fmt.Println(err)
+		return 0, ""
+	}
+
+	database, _ := sql.Open(historyDriver, Server)
+	defer database.Close()
+
+	var revisions int
+	err = database.QueryRow(`SELECT COUNT(*) FROM ` + table).Scan(&revisions)
+	if err != nil {
+		fmt.Println(err)
+		return 0, ""
+	}
+
+	var ip, user string
+	err = database.QueryRow(`SELECT ip, user FROM `+table+` ORDER BY version DESC LIMIT 1`).Scan(&ip, &user)
+	if err != nil {
+		// user 칸이 생기기 전에 만든 테이블
+		if err = database.QueryRow(`SELECT ip FROM ` + table + ` ORDER BY version DESC LIMIT 1`).Scan(&ip); err != nil {
+			fmt.Println(err)
+		}
+	}
+
+	if len(user) != 0 {
+		return revisions, user
+	}
+
+	return revisions, MaskIP(ip)
+}
+
+// ValidateIP 는 저장 기록에 남길 IP 가 IPv4 나 IPv6 주소인지 확인한다
+func ValidateIP(ip string) error {
+	if parsed := net.ParseIP(ip); parsed == nil || len(ip) > historyIPLen {
+		return fmt.Errorf("invalid ip: %s", ip)
+	}
+
+	return nil
+}
+
+// RequestIP 는 기록에 남길 주소, 클라이언트가 보낸 값이 아니라 서버가 본 주소를 쓴다
+// IPv4 가 섞인 IPv6 주소 (::ffff:1.2.3.4) 는 IPv4 로 적고, 주소가 아니면 비워둔다
+func RequestIP(r *http.Request) string {
+	ip := ClientAddr(r)
+	if err := ValidateIP(ip); err != nil {
+		return ""
+	}
+
+	return net.ParseIP(ip).String()
+}
+
 func API(w http.ResponseWriter, r *http.Request) {
+	limits := LoadRateLimitConfig()
+	r.Body = http.MaxBytesReader(w, r.Body, limits.MaxBodyBytes)
+
 	err := r.ParseForm()
 	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			ErrorStatus(w, http.StatusRequestEntityTooLarge, err, 6)
+			return
+		}
+
 		Error(w, err, 999)
+		return
 	}
 
 	if r.Method != "POST" {
@@ -110,6 +329,42 @@ func API(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 	call := r.FormValue("call")
 
+	if limits.DeniedIP(ClientAddr(r)) {
+		ErrorStatus(w, http.StatusForbidden, fmt.Errorf("access denied"), 5)
+		return
+	}
+
+	if len(r.FormValue("subtitle")) > limits.MaxSubtitleBytes {
+		ErrorStatus(w, http.StatusRequestEntityTooLarge, fmt.Errorf("subtitle is larger than %d bytes", limits.MaxSubtitleBytes), 6)
+		return
+	}
+
+	// 만료된 쿠키로도 로그인, 로그아웃은 할 수 있어야 하므로 account 는 그대로 넘긴다
+	user, authErr := Authenticate(r)
+
+	if limits.DeniedUser(user) {
+		ErrorStatus(w, http.StatusForbidden, fmt.Errorf("access denied"), 5)
+		return
+	}
+
+	// 로그인하지 않았거나 인증에 실패했으면 (비밀번호를 계속 넣어보는 경우) 주소로 센다
+	class := limits.Class(call)
+	if ok, wait := APILimiter.Allow(RateLimitClient(r, user), class, limits.Limit(class)); !ok {
+		RateLimited(w, call, wait)
+		return
+	}
+
+	if authErr != nil && call != "account" {
+		Error(w, authErr, 2)
+		return
+	}
+
+	role, err := Authorize(r, user)
+	if err != nil {
+		Error(w, err, 3)
+		return
+	}
+
 	switch call {
 	case "youtube": // 100
 		id := r.FormValue("id")
@@ -119,43 +374,21 @@ func API(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
-		regx, _ := regexp.Compile(`"itag":18,"url":"(.*?)"`)
-
-		fmt.Printf("다운로드: https://www.youtube.com/get_video_info?video_id=%s&eurl=https://youtube.googleapis.com/v/%s\n", id, id)
-
-		client := http.Client{}
-
-		req, err := http.NewRequest("GET", fmt.Sprintf("https://www.youtube.com/get_video_info?video_id=%s&eurl=https://youtube.googleapis.com/v/%s", id, id), nil)
-		if err != nil {
+		if _, err := GetSource("youtube", id); err != nil {
 			Error(w, err, 101)
 			return
 		}
 
-		req.Header.Add("Content-Type", "text/html; charset=utf-8")
-		req.Header.Add("Access-Control-Allow-Origin", "*")
-		req.Header.Add("Access-Control-Allow-Headers", "Content-Type, Origin, Accept, token")
-		req.Header.Add("Access-Control-Allow-Methods", "GET, OPTIONS")
-
-		resp, err := client.Do(req)
-		if err != nil || resp.StatusCode == 404 {
+		streams, err := Streams.Resolve("youtube", id, 0)
+		if err != nil {
 			Error(w, err, 102)
 			return
 		}
 
-		body, _ := ioutil.ReadAll(resp.Body)
-		resp.Body.Close()
-
-		decodedBody, _ := url.QueryUnescape(string(body))
-		ytURLs := regx.FindStringSubmatch(decodedBody)
-
-		if len(ytURLs) < 2 {
-			Error(w, err, 103)
-			return
-		}
-
 		err = json.NewEncoder(w).Encode(YoutubeJSON{
-			URL:  strings.ReplaceAll(ytURLs[1], "\\u0026", "&"),
-			Code: 0,
+			URL:     streams[0].URL,
+			Streams: streams,
+			Code:    0,
 		})
 		if err != nil {
 			Error(w, err, 199)
@@ -171,7 +404,25 @@ func API(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
-		file, err := ioutil.ReadFile(fmt.Sprintf("/home/ubuntu/jamak/subtitle/%s/%s/%s.srt", platform, id, lang))
+		if _, err := GetSource(platform, id); err != nil {
+			Error(w, err, 202)
+			return
+		}
+
+		if err := ValidateLang(lang); err != nil {
+			Error(w, err, 203)
+			return
+		}
+
+		var draft string
+
+		// 아직 저장된 자막이 없으면 기계 번역 초안을 대신 준다
+		file, err := SubtitleStore.ReadFile(platform, id, lang+".srt")
+		if os.IsNotExist(err) {
+			if file, err = SubtitleStore.ReadFile(platform, id, "draft", lang+".srt"); err == nil {
+				draft = "machine"
+			}
+		}
 		if err != nil {
 			Error(w, err, 201)
 			return
@@ -179,6 +430,8 @@ func API(w http.ResponseWriter, r *http.Request) {
 
 		err = json.NewEncoder(w).Encode(SubtitleJSON{
 			Subtitle: string(file),
+			Version:  fmt.Sprintf("r%d", GetLastVersion(id)),
+			Draft:    draft,
 			Code:     0,
 		})
 		if err != nil {
@@ -186,38 +439,97 @@ func API(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 	case "save": // 300
-		ip := r.FormValue("ip")
 		platform := r.FormValue("platform")
 		id := r.FormValue("id")
 		lang := r.FormValue("lang")
 		subtitle := r.FormValue("subtitle")
+		base := r.FormValue("base")
 
-		if len(ip) == 0 || len(platform) == 0 || len(id) == 0 || len(lang) == 0 || len(subtitle) == 0 {
+		if len(platform) == 0 || len(id) == 0 || len(lang) == 0 || len(subtitle) == 0 {
 			Error(w, fmt.Errorf(""), 300)
 			return
 		}
 
-		if err := os.MkdirAll(fmt.Sprintf("/home/ubuntu/jamak/subtitle/%s/%s/version", platform, id), 0777); err != nil {
-			fmt.Println(err)
+		if _, err := GetSource(platform, id); err != nil {
+			Error(w, err, 302)
+			return
+		}
 
-			Error(w, fmt.Errorf(""), 301)
+		if err := ValidateLang(lang); err != nil {
+			Error(w, err, 307)
 			return
 		}
 
-		AddSubtitle(id, ip, lang)
-		version := GetLastVersion(id)
+		source := r.FormValue("source")
+		if len(source) != 0 {
+			if err := ValidateLang(source); err != nil {
+				Error(w, err, 309)
+				return
+			}
+		}
+
+		// 오프라인에서 쌓아둔 저장은 그 사이 다른 사람이 같은 언어로 저장했으면 덮어쓰지 않는다
+		// base 는 불러올 때의 전체 버전이므로 다른 언어 저장은 충돌로 보지 않는다
+		if len(base) != 0 {
+			from, err := strconv.Atoi(strings.TrimPrefix(base, "r"))
+			if err != nil || !strings.HasPrefix(base, "r") {
+				Error(w, fmt.Errorf("invalid base: %s", base), 306)
+				return
+			}
+
+			if head := GetLastLangVersion(id, lang); head > from {
+				Error(w, fmt.Errorf("conflict: %s -> r%d", base, head), 306)
+				return
+			}
+		}
 
-		orgFile := fmt.Sprintf("/home/ubuntu/jamak/subtitle/%s/%s/version/r%d-%s.srt", platform, id, version, lang)
-		file := fmt.Sprintf("/home/ubuntu/jamak/subtitle/%s/%s/%s.srt", platform, id, lang)
+		// 게시된 자막을 훼손한 것으로 보이면 관리자가 확인할 때까지 격리한다 (관리자 저장은 보지 않는다)
+		if !RoleAtLeast(role, RoleAdmin) {
+			report, err := CheckVandalism(platform, id, lang, []byte(subtitle))
+			if err != nil {
+				fmt.Println(err)
+			} else if report.Suspicious() {
+				proposal, err := QuarantineSubtitle(platform, id, lang, source, []byte(subtitle), editorName(user), RequestIP(r), report)
+				if err != nil {
+					Error(w, err, 305)
+					return
+				}
 
-		input, _ := ioutil.ReadFile(file)
+				fmt.Printf("저장 격리 (%s/%s %s): %s\n", platform, id, proposal.ID, strings.Join(report.Reasons, ", "))
 
-		if err := ioutil.WriteFile(orgFile, input, 0777); err != nil {
-			Error(w, err, 304)
+				err = json.NewEncoder(w).Encode(SaveJSON{
+					Version:     proposal.Base,
+					Quarantined: proposal.ID,
+					Reasons:     report.Reasons,
+					Code:        0,
+				})
+				if err != nil {
+					Error(w, err, 399)
+				}
+				return
+			}
+		}
+
+		// 검토자보다 낮은 역할의 저장은 바로 게시하지 않고 검토 대기로 올린다
+		if !RoleAtLeast(role, RoleReviewer) {
+			proposal, err := ProposeSubtitle(platform, id, lang, source, []byte(subtitle), editorName(user), RequestIP(r))
+			if err != nil {
+				Error(w, err, 305)
+				return
+			}
+
+			err = json.NewEncoder(w).Encode(SaveJSON{
+				Version: proposal.Base,
+				Pending: proposal.ID,
+				Code:    0,
+			})
+			if err != nil {
+				Error(w, err, 399)
+			}
 			return
 		}
 
-		err = ioutil.WriteFile(file, []byte(subtitle), 0777)
+		version, err := PublishSubtitle(platform, id, lang, source, []byte(subtitle), editorName(user), RequestIP(r))
 		if err != nil {
 			Error(w, err, 305)
 			return
@@ -231,26 +543,953 @@ func API(w http.ResponseWriter, r *http.Request) {
 			Error(w, err, 399)
 			return
 		}
-	default:
-		Error(w, fmt.Errorf("%s", "Not Found"), 1)
-	}
-}
+	case "waveform": // 400
+		platform := r.FormValue("platform")
+		id := r.FormValue("id")
+		level, _ := strconv.Atoi(r.FormValue("level"))
 
-func main() {
-	h := &app.Handler{
-		Title: "자막 편집기",
-		Styles: []string{
-			"/web/app.css",
-		},
-	}
+		if len(platform) == 0 || len(id) == 0 {
+			Error(w, fmt.Errorf(""), 400)
+			return
+		}
 
-	subdomains := make(Subdomains)
-	subdomains["editor"] = h
+		if _, err := GetSource(platform, id); err != nil {
+			Error(w, err, 403)
+			return
+		}
+
+		if level < 0 || level >= len(WaveformLevels) {
+			Error(w, fmt.Errorf("level must be between 0 and %d", len(WaveformLevels)-1), 401)
+			return
+		}
+
+		waveform, err := GetWaveform(platform, id)
+		if err != nil {
+			Error(w, err, 402)
+			return
+		}
+
+		err = json.NewEncoder(w).Encode(WaveformJSON{
+			Rate:           waveform.Rate,
+			Duration:       waveform.Duration,
+			SamplesPerPeak: waveform.Levels[level].SamplesPerPeak,
+			Min:            waveform.Levels[level].Min,
+			Max:            waveform.Levels[level].Max,
+			Code:           0,
+		})
+		if err != nil {
+			Error(w, err, 499)
+			return
+		}
+	case "vad": // 500
+		platform := r.FormValue("platform")
+		id := r.FormValue("id")
+		lang := r.FormValue("lang")
+		mode := r.FormValue("mode")
+		subtitle := r.FormValue("subtitle")
+
+		if len(platform) == 0 || len(id) == 0 || (mode != "create" && mode != "snap") {
+			Error(w, fmt.Errorf(""), 500)
+			return
+		}
+
+		if _, err := GetSource(platform, id); err != nil {
+			Error(w, err, 505)
+			return
+		}
+
+		if len(lang) != 0 {
+			if err := ValidateLang(lang); err != nil {
+				Error(w, err, 506)
+				return
+			}
+		}
+
+		tolerance := 500 * time.Millisecond
+		if ms, err := strconv.Atoi(r.FormValue("tolerance")); err == nil && ms > 0 {
+			tolerance = time.Duration(ms) * time.Millisecond
+		}
+
+		audio, err := LoadAudio(platform, id)
+		if err != nil {
+			Error(w, err, 501)
+			return
+		}
+
+		segments := DetectSpeech(audio)
+
+		var subs *astisub.Subtitles
+		var snapped int
+
+		if mode == "create" {
+			if len(segments) == 0 {
+				Error(w, fmt.Errorf("no speech detected"), 502)
+				return
+			}
+
+			subs = SegmentsToSubtitles(segments)
+		} else {
+			if len(subtitle) != 0 {
+				subs, err = ParseSRT(subtitle)
+			} else if len(lang) != 0 {
+				subs, err = ReadSubtitle(platform, id, lang)
+			} else {
+				err = fmt.Errorf("subtitle or lang is required")
+			}
+
+			if err != nil {
+				Error(w, err, 503)
+				return
+			}
+
+			snapped = SnapSubtitles(subs, segments, tolerance)
+		}
+
+		srt, err := FormatSRT(subs)
+		if err != nil {
+			Error(w, err, 504)
+			return
+		}
+
+		err = json.NewEncoder(w).Encode(VadJSON{
+			Subtitle: srt,
+			Segments: segments,
+			Snapped:  snapped,
+			Code:     0,
+		})
+		if err != nil {
+			Error(w, err, 599)
+			return
+		}
+	case "video": // 600
+		platform := r.FormValue("platform")
+		id := r.FormValue("id")
+
+		if len(platform) == 0 || len(id) == 0 {
+			Error(w, fmt.Errorf(""), 600)
+			return
+		}
+
+		if _, err := GetSource(platform, id); err != nil {
+			Error(w, err, 601)
+			return
+		}
+
+		streams, err := Streams.Resolve(platform, id, 0)
+		if err != nil || len(streams) == 0 {
+			if err == nil {
+				err = fmt.Errorf("no playable stream")
+			}
+
+			Error(w, err, 602)
+			return
+		}
+
+		metadata, err := GetMetadata(platform, id)
+		if err != nil {
+			fmt.Println(err)
+		}
+
+		err = json.NewEncoder(w).Encode(VideoJSON{
+			URL:      streams[0].URL,
+			Streams:  streams,
+			Metadata: metadata,
+			Code:     0,
+		})
+		if err != nil {
+			Error(w, err, 699)
+			return
+		}
+	case "videos": // 700
+		platform := r.FormValue("platform")
+		query := r.FormValue("q")
+		sortBy := r.FormValue("sort")
+
+		if len(platform) != 0 && Sources[platform] == nil {
+			Error(w, fmt.Errorf("unknown platform: %s", platform), 701)
+			return
+		}
+
+		offset, _ := strconv.Atoi(r.FormValue("offset"))
+		limit, err := strconv.Atoi(r.FormValue("limit"))
+		if err != nil || limit <= 0 || limit > 200 {
+			limit = 50
+		}
+
+		if offset < 0 {
+			Error(w, fmt.Errorf(""), 700)
+			return
+		}
+
+		videos, err := ListVideos(platform)
+		if err != nil {
+			Error(w, err, 702)
+			return
+		}
+
+		videos = SearchVideos(videos, query)
+		if err := SortVideos(videos, sortBy); err != nil {
+			Error(w, err, 703)
+			return
+		}
+
+		total := len(videos)
+		if offset > total {
+			offset = total
+		}
+		if offset+limit < total {
+			videos = videos[offset : offset+limit]
+		} else {
+			videos = videos[offset:]
+		}
+
+		err = json.NewEncoder(w).Encode(VideosJSON{
+			Videos: videos,
+			Total:  total,
+			Code:   0,
+		})
+		if err != nil {
+			Error(w, err, 799)
+			return
+		}
+	case "completion": // 800
+		platform := r.FormValue("platform")
+		id := r.FormValue("id")
+		source := r.FormValue("source")
+
+		if len(platform) == 0 || len(id) == 0 {
+			Error(w, fmt.Errorf(""), 800)
+			return
+		}
+
+		if _, err := GetSource(platform, id); err != nil {
+			Error(w, err, 801)
+			return
+		}
+
+		if len(source) != 0 {
+			if err := ValidateLang(source); err != nil {
+				Error(w, err, 803)
+				return
+			}
+		}
+
+		// completion.json 은 저장할 때만 쓴다, 없거나 원문 언어가 다르면 계산만 해서 돌려준다
+		completion, err := LoadCompletion(platform, id)
+		if err != nil || (len(source) != 0 && source != completion.SourceLang) {
+			completion, err = ComputeVideoCompletion(platform, id, source)
+		}
+		if errors.Is(err, ErrNoSubtitles) {
+			ErrorStatus(w, http.StatusNotFound, err, 804)
+			return
+		}
+		if err != nil {
+			Error(w, err, 802)
+			return
+		}
+
+		err = json.NewEncoder(w).Encode(CompletionJSON{
+			SourceLang: completion.SourceLang,
+			Languages:  completion.Languages,
+			Code:       0,
+		})
+		if err != nil {
+			Error(w, err, 899)
+			return
+		}
+	case "search": // 900
+		query := SearchQuery{
+			Text:      r.FormValue("q"),
+			Platform:  r.FormValue("platform"),
+			Lang:      r.FormValue("lang"),
+			Revisions: r.FormValue("revisions") == "1",
+		}
+		as := r.FormValue("as")
+		asLang := r.FormValue("asLang")
+
+		if len(strings.TrimSpace(query.Text)) == 0 {
+			Error(w, fmt.Errorf(""), 900)
+			return
+		}
+
+		if len(Tokenize(query.Text)) == 0 || (len(as) != 0 && len(Tokenize(as)) == 0) {
+			Error(w, fmt.Errorf("query has no searchable words"), 901)
+			return
+		}
+
+		if !Search.Ready() {
+			Error(w, fmt.Errorf("search index is building"), 902)
+			return
+		}
+
+		limit, err := strconv.Atoi(r.FormValue("limit"))
+		if err != nil || limit <= 0 || limit > 500 {
+			limit = 100
+		}
+
+		hits := Search.Search(query)
+
+		// as 가 있으면 q 를 as 로 번역한 곳만 찾는다
+		if len(as) != 0 {
+			hits = PairHits(hits, Search.Search(SearchQuery{
+				Text:      as,
+				Platform:  query.Platform,
+				Lang:      asLang,
+				Revisions: query.Revisions,
+			}))
+		}
+
+		total := len(hits)
+		if total > limit {
+			hits = hits[:limit]
+		}
+
+		err = json.NewEncoder(w).Encode(SearchJSON{
+			Hits:  hits,
+			Total: total,
+			Code:  0,
+		})
+		if err != nil {
+			Error(w, err, 999)
+			return
+		}
+	case "memory": // 1000
+		source := r.FormValue("source")
+		sourceLang := r.FormValue("sourceLang")
+		targetLang := r.FormValue("targetLang")
+
+		if len(strings.TrimSpace(source)) == 0 || len(sourceLang) == 0 || len(targetLang) == 0 {
+			Error(w, fmt.Errorf(""), 1000)
+			return
+		}
+
+		if !Memory.Ready() {
+			Error(w, fmt.Errorf("translation memory is building"), 1001)
+			return
+		}
+
+		limit, err := strconv.Atoi(r.FormValue("limit"))
+		if err != nil || limit <= 0 || limit > memoryMaxLimit {
+			limit = 5
+		}
+
+		err = json.NewEncoder(w).Encode(MemoryJSON{
+			Matches: Memory.Suggest(source, sourceLang, targetLang, limit),
+			Code:    0,
+		})
+		if err != nil {
+			Error(w, err, 1099)
+			return
+		}
+	case "glossary": // 1100
+		action := r.FormValue("action")
+		project := r.FormValue("project")
+
+		if len(action) == 0 || len(project) == 0 {
+			Error(w, fmt.Errorf(""), 1100)
+			return
+		}
+
+		if err := ValidateProject(project); err != nil {
+			Error(w, err, 1101)
+			return
+		}
+
+		var glossary *Glossary
+
+		switch action {
+		case "list":
+			glossary, err = LoadGlossary(project)
+		case "set":
+			glossary, err = SetGlossaryTerm(project, GlossaryTerm{
+				Source:    r.FormValue("source"),
+				Target:    r.FormValue("target"),
+				Forbidden: r.Form["forbidden"],
+			})
+		case "delete":
+			glossary, err = DeleteGlossaryTerm(project, r.FormValue("source"))
+		case "assign":
+			platform := r.FormValue("platform")
+			id := r.FormValue("id")
+
+			if _, err := GetSource(platform, id); err != nil {
+				Error(w, err, 1104)
+				return
+			}
+
+			if err := SetProject(platform, id, project); err != nil {
+				Error(w, err, 1103)
+				return
+			}
+
+			glossary, err = LoadGlossary(project)
+		default:
+			Error(w, fmt.Errorf("unknown action: %s", action), 1102)
+			return
+		}
+
+		if err != nil {
+			Error(w, err, 1103)
+			return
+		}
+
+		err = json.NewEncoder(w).Encode(GlossaryJSON{
+			Project: glossary.Project,
+			Terms:   glossary.Terms,
+			Code:    0,
+		})
+		if err != nil {
+			Error(w, err, 1199)
+			return
+		}
+	case "lint": // 1200
+		platform := r.FormValue("platform")
+		id := r.FormValue("id")
+		lang := r.FormValue("lang")
+		source := r.FormValue("source")
+		subtitle := r.FormValue("subtitle")
+		project := r.FormValue("project")
+
+		if len(platform) == 0 || len(id) == 0 || len(lang) == 0 {
+			Error(w, fmt.Errorf(""), 1200)
+			return
+		}
+
+		if _, err := GetSource(platform, id); err != nil {
+			Error(w, err, 1201)
+			return
+		}
+
+		if len(source) == 0 {
+			source = DefaultSourceLang
+		}
+
+		if err := ValidateLang(lang); err != nil {
+			Error(w, err, 1205)
+			return
+		}
+
+		if err := ValidateLang(source); err != nil {
+			Error(w, err, 1205)
+			return
+		}
+
+		// 프로젝트를 따로 주지 않으면 영상이 묶인 프로젝트를 쓴다
+		if len(project) == 0 {
+			if metadata, err := LoadMetadata(platform, id); err == nil {
+				project = metadata.Project
+			}
+		}
+
+		issues := []LintIssue{}
+
+		if len(project) != 0 {
+			if err := ValidateProject(project); err != nil {
+				Error(w, err, 1202)
+				return
+			}
+
+			glossary, err := LoadGlossary(project)
+			if err != nil {
+				Error(w, err, 1203)
+				return
+			}
+
+			var subs *astisub.Subtitles
+			if len(subtitle) != 0 {
+				subs, err = ParseSRT(subtitle)
+			} else {
+				subs, err = ReadSubtitle(platform, id, lang)
+			}
+			if err != nil {
+				Error(w, err, 1204)
+				return
+			}
+
+			var sourceSubs *astisub.Subtitles
+			if source != lang {
+				if sourceSubs, err = ReadSubtitle(platform, id, source); err != nil {
+					sourceSubs = nil
+				}
+			}
+
+			issues = LintSubtitles(glossary, subs, sourceSubs)
+		}
+
+		err = json.NewEncoder(w).Encode(LintJSON{
+			Project: project,
+			Issues:  issues,
+			Code:    0,
+		})
+		if err != nil {
+			Error(w, err, 1299)
+			return
+		}
+	case "translate": // 1300
+		platform := r.FormValue("platform")
+		id := r.FormValue("id")
+		source := r.FormValue("source")
+		lang := r.FormValue("lang")
+
+		if len(platform) == 0 || len(id) == 0 || len(source) == 0 || len(lang) == 0 || source == lang {
+			Error(w, fmt.Errorf(""), 1300)
+			return
+		}
+
+		if _, err := GetSource(platform, id); err != nil {
+			Error(w, err, 1301)
+			return
+		}
+
+		if err := ValidateLang(lang); err != nil {
+			Error(w, err, 1307)
+			return
+		}
+
+		if err := ValidateLang(source); err != nil {
+			Error(w, err, 1307)
+			return
+		}
+
+		translator, err := GetTranslator()
+		if err != nil {
+			Error(w, err, 1302)
+			return
+		}
+
+		subs, err := ReadSubtitle(platform, id, source)
+		if err != nil {
+			Error(w, err, 1303)
+			return
+		}
+
+		translated, err := TranslateSubtitles(translator, subs, source, lang)
+		if err != nil {
+			Error(w, err, 1304)
+			return
+		}
+
+		srt, err := FormatSRT(translated)
+		if err != nil {
+			Error(w, err, 1305)
+			return
+		}
+
+		err = StoreMachineDraft(platform, id, lang, srt, MachineDraft{
+			Provider:   translator.Name(),
+			SourceLang: source,
+			CreatedAt:  time.Now(),
+		})
+		if err != nil {
+			Error(w, err, 1306)
+			return
+		}
+
+		err = json.NewEncoder(w).Encode(TranslateJSON{
+			Subtitle: srt,
+			Draft:    "machine",
+			Provider: translator.Name(),
+			Code:     0,
+		})
+		if err != nil {
+			Error(w, err, 1399)
+			return
+		}
+	case "restore": // 1500
+		platform := r.FormValue("platform")
+		id := r.FormValue("id")
+		lang := r.FormValue("lang")
+		revision := r.FormValue("revision")
+
+		if len(platform) == 0 || len(id) == 0 || len(lang) == 0 || len(revision) == 0 {
+			Error(w, fmt.Errorf(""), 1500)
+			return
+		}
+
+		if _, err := GetSource(platform, id); err != nil {
+			Error(w, err, 1501)
+			return
+		}
+
+		if err := ValidateLang(lang); err != nil {
+			Error(w, err, 1502)
+			return
+		}
+
+		// rN 은 N 번째 저장 직전의 자막 (검색 결과의 revision 과 같다)
+		n, err := strconv.Atoi(strings.TrimPrefix(revision, "r"))
+		if err != nil || !strings.HasPrefix(revision, "r") || n <= 0 {
+			Error(w, fmt.Errorf("invalid revision: %s", revision), 1503)
+			return
+		}
+
+		file, err := SubtitleStore.ReadFile(platform, id, "version", fmt.Sprintf("r%d-%s.srt", n, lang))
+		if err != nil || len(file) == 0 {
+			Error(w, fmt.Errorf("revision not found: %s", revision), 1503)
+			return
+		}
+
+		version, err := PublishSubtitle(platform, id, lang, "", file, editorName(user), RequestIP(r))
+		if err != nil {
+			Error(w, err, 1504)
+			return
+		}
+
+		err = json.NewEncoder(w).Encode(SaveJSON{
+			Version: fmt.Sprintf("r%d", version),
+			Code:    0,
+		})
+		if err != nil {
+			Error(w, err, 1599)
+			return
+		}
+	case "review": // 1600
+		action := r.FormValue("action")
+		platform := r.FormValue("platform")
+		id := r.FormValue("id")
+		pid := r.FormValue("proposal")
+
+		if len(action) == 0 || len(platform) == 0 || len(id) == 0 {
+			Error(w, fmt.Errorf(""), 1600)
+			return
+		}
+
+		if _, err := GetSource(platform, id); err != nil {
+			Error(w, err, 1601)
+			return
+		}
+
+		var result ReviewJSON
+
+		switch action {
+		case "list":
+			lang := r.FormValue("lang")
+			if len(lang) != 0 {
+				if err := ValidateLang(lang); err != nil {
+					Error(w, err, 1601)
+					return
+				}
+			}
+
+			result.Proposals, err = ListProposals(platform, id, lang, r.FormValue("status"))
+			if err != nil {
+				Error(w, err, 1604)
+				return
+			}
+
+			// 격리된 저장은 관리자만 본다
+			if !RoleAtLeast(role, RoleAdmin) {
+				proposals := []*Proposal{}
+				for _, proposal := range result.Proposals {
+					if proposal.Status != ReviewQuarantined && proposal.Status != ReviewDiscarded {
+						proposals = append(proposals, proposal)
+					}
+				}
+				result.Proposals = proposals
+			}
+		case "diff":
+			result.Proposal, result.Published, result.Proposed, result.Diff, err = DiffProposal(platform, id, pid)
+			if err != nil {
+				Error(w, err, 1602)
+				return
+			}
+
+			if (result.Proposal.Status == ReviewQuarantined || result.Proposal.Status == ReviewDiscarded) && !RoleAtLeast(role, RoleAdmin) {
+				Error(w, fmt.Errorf("quarantined proposal requires admin role"), 3)
+				return
+			}
+		case "approve", "reject", "changes", "release", "discard":
+			proposal, _, err := LoadProposal(platform, id, pid)
+			if err != nil {
+				Error(w, err, 1602)
+				return
+			}
+
+			// 언어 범위로 받은 검토자, 관리자는 그 언어만 처리할 수 있다
+			required := RequiredRole(call, action)
+			if !RoleAtLeast(RoleFor(user, LoadAuthConfig(), platform, id, proposal.Lang), required) {
+				Error(w, fmt.Errorf("%s requires %s role for %s", action, required, proposal.Lang), 3)
+				return
+			}
+
+			status := map[string]string{
+				"approve": ReviewApproved,
+				"reject":  ReviewRejected,
+				"changes": ReviewChanges,
+				"release": ReviewApproved,
+				"discard": ReviewDiscarded,
+			}[action]
+
+			if action == "release" || action == "discard" {
+				result.Proposal, err = ReleaseProposal(platform, id, pid, status, editorName(user), r.FormValue("comment"))
+			} else {
+				result.Proposal, err = ReviewProposal(platform, id, pid, status, editorName(user), r.FormValue("comment"))
+			}
+			if err != nil {
+				Error(w, err, 1605)
+				return
+			}
+		default:
+			Error(w, fmt.Errorf("unknown action: %s", action), 1603)
+			return
+		}
+
+		err = json.NewEncoder(w).Encode(result)
+		if err != nil {
+			Error(w, err, 1699)
+			return
+		}
+	case "comment": // 1700
+		action := r.FormValue("action")
+		platform := r.FormValue("platform")
+		id := r.FormValue("id")
+		lang := r.FormValue("lang")
+
+		if len(action) == 0 || len(platform) == 0 || len(id) == 0 || len(lang) == 0 {
+			Error(w, fmt.Errorf(""), 1700)
+			return
+		}
+
+		if _, err := GetSource(platform, id); err != nil {
+			Error(w, err, 1701)
+			return
+		}
+
+		if err := ValidateLang(lang); err != nil {
+			Error(w, err, 1701)
+			return
+		}
+
+		var result CommentJSON
+
+		switch action {
+		case "list":
+			result.Threads, err = ListComments(platform, id, lang)
+		case "add":
+			startAt, endAt, err := ParseCueRange(r.FormValue("start"), r.FormValue("end"))
+			if err != nil {
+				Error(w, err, 1702)
+				return
+			}
+
+			result.Thread, err = AddComment(platform, id, lang, startAt, endAt, editorName(user), r.FormValue("text"))
+			if err != nil {
+				Error(w, err, 1704)
+				return
+			}
+		case "reply":
+			result.Thread, err = ReplyComment(platform, id, lang, r.FormValue("thread"), editorName(user), r.FormValue("text"))
+		case "resolve", "unresolve":
+			result.Thread, err = ResolveComment(platform, id, lang, r.FormValue("thread"), editorName(user), action == "resolve")
+		default:
+			Error(w, fmt.Errorf("unknown action: %s", action), 1703)
+			return
+		}
+
+		if err != nil {
+			Error(w, err, 1704)
+			return
+		}
+
+		err = json.NewEncoder(w).Encode(result)
+		if err != nil {
+			Error(w, err, 1799)
+			return
+		}
+	case "account": // 1400
+		action := r.FormValue("action")
+
+		if len(action) == 0 {
+			Error(w, fmt.Errorf(""), 1400)
+			return
+		}
+
+		config := LoadAuthConfig()
+
+		// 만료된 쿠키는 로그아웃한 것으로 보고, 토큰 관리만 막는다
+		result := AccountJSON{
+			Role:      role,
+			Anonymous: config.Anonymous,
+			Signup:    config.SignupOpen(),
+			Code:      0,
+		}
+
+		switch action {
+		case "me":
+			if authErr != nil {
+				EndSession(w, r)
+			}
+		case "signup":
+			if !config.SignupOpen() {
+				Error(w, fmt.Errorf("signup is disabled"), 1402)
+				return
+			}
+
+			user, err = CreateUser(r.FormValue("name"), r.FormValue("password"))
+			if os.IsExist(err) {
+				Error(w, fmt.Errorf("name is taken"), 1403)
+				return
+			}
+			if err != nil {
+				Error(w, err, 1404)
+				return
+			}
+
+			if err := StartSession(w, user.Name); err != nil {
+				Error(w, err, 1405)
+				return
+			}
+		case "login":
+			user, err = CheckPassword(r.FormValue("name"), r.FormValue("password"))
+			if err != nil {
+				Error(w, err, 1401)
+				return
+			}
+
+			if err := StartSession(w, user.Name); err != nil {
+				Error(w, err, 1405)
+				return
+			}
+		case "logout":
+			EndSession(w, r)
+			user = nil
+		case "users":
+			users, err := ListUsers(config)
+			if err != nil {
+				Error(w, err, 1405)
+				return
+			}
+
+			result.Users = users
+		case "role":
+			target, err := SetUserRole(r.FormValue("name"), decodeGrant(r))
+			if err != nil {
+				Error(w, err, 1408)
+				return
+			}
+
+			result.Users = []AccountUser{target.Account(config)}
+		case "tokens", "token", "revoke":
+			if authErr != nil {
+				Error(w, authErr, 1401)
+				return
+			}
+
+			if user == nil {
+				Error(w, fmt.Errorf("login required"), 1406)
+				return
+			}
+
+			switch action {
+			case "token":
+				token, _, err := CreateToken(user.Name, r.FormValue("label"))
+				if err != nil {
+					Error(w, err, 1405)
+					return
+				}
+
+				result.Token = token
+			case "revoke":
+				if err := RevokeToken(user.Name, r.FormValue("token")); err != nil {
+					Error(w, err, 1405)
+					return
+				}
+			}
+
+			if user, err = LoadUser(user.Name); err != nil {
+				Error(w, err, 1405)
+				return
+			}
+
+			result.Tokens = user.PublicTokens()
+		default:
+			Error(w, fmt.Errorf("unknown action: %s", action), 1407)
+			return
+		}
+
+		// 로그인, 로그아웃하면 역할도 바뀐다
+		if user != nil {
+			result.Name = user.Name
+		}
+		result.Role = RoleFor(user, config, r.FormValue("platform"), r.FormValue("id"), r.FormValue("lang"))
+
+		err = json.NewEncoder(w).Encode(result)
+		if err != nil {
+			Error(w, err, 1499)
+			return
+		}
+	default:
+		Error(w, fmt.Errorf("%s", "Not Found"), 1)
+	}
+}
+
+// DebugVars 는 expvar 통계를 서버에서 직접 (프록시를 거치지 않고) 부르거나 관리자가 부를 때만 보여준다
+func DebugVars(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host, _, _ := net.SplitHostPort(r.RemoteAddr)
+		direct := len(r.Header.Get("X-Forwarded-For")) == 0 && len(r.Header.Get("X-Real-IP")) == 0
+		if ip := net.ParseIP(host); ip != nil && ip.IsLoopback() && direct {
+			handler.ServeHTTP(w, r)
+			return
+		}
+
+		user, err := Authenticate(r)
+		if err != nil || user == nil || !RoleAtLeast(RoleFor(user, LoadAuthConfig(), "", "", ""), RoleAdmin) {
+			http.Error(w, "Forbidden", http.StatusForbidden)
+			return
+		}
+
+		handler.ServeHTTP(w, r)
+	})
+}
+
+// createAdmin 은 -create-admin 으로 받은 이름과 표준 입력의 첫 줄 (비밀번호) 로 관리자를 만든다
+func createAdmin(name string) {
+	password, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && err != io.EOF {
+		log.Fatal(err)
+	}
+
+	user, err := CreateAdmin(name, strings.TrimRight(password, "\r\n"))
+	if os.IsExist(err) {
+		log.Fatalf("%s: name is taken", name)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	log.Printf("Created admin %s", user.Name)
+}
+
+func main() {
+	adminName := flag.String("create-admin", "", "관리자를 만들고 끝낸다, 비밀번호는 표준 입력의 첫 줄")
+	flag.Parse()
+
+	if len(*adminName) != 0 {
+		createAdmin(*adminName)
+		return
+	}
+
+	h := &app.Handler{
+		Title: "자막 편집기",
+		Styles: []string{
+			"/web/app.css",
+		},
+	}
+
+	subdomains := make(Subdomains)
+	subdomains["editor"] = h
+
+	go Search.Build()
+	go Memory.Build()
 
 	mux := http.NewServeMux()
 	mux.Handle("/", subdomains)
 	mux.HandleFunc("/api", API)
+	mux.Handle("/media/proxy", NewMediaProxy(Streams))
+	mux.Handle("/debug/vars", DebugVars(expvar.Handler()))
 
+	log.Printf("Data directory: %s", DataDir)
 	log.Printf("Running server on %d port!", Port)
 
 	if err := http.ListenAndServe(fmt.Sprintf("localhost:%d", Port), mux); err != nil {
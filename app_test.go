@@ -0,0 +1,326 @@
+package main
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// stubHistory 는 저장 기록 DB 대신 쓰는 드라이버, 받은 쿼리를 모아두고 결과는 비어 있다
+type stubHistory struct {
+	mu      sync.Mutex
+	queries []string
+}
+
+var (
+	historyStub     = &stubHistory{}
+	historyStubOnce sync.Once
+)
+
+func (d *stubHistory) Open(name string) (driver.Conn, error) {
+	return stubConn{d}, nil
+}
+
+func (d *stubHistory) reset() []string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	queries := d.queries
+	d.queries = nil
+
+	return queries
+}
+
+type stubConn struct {
+	d *stubHistory
+}
+
+func (c stubConn) Prepare(query string) (driver.Stmt, error) {
+	c.d.mu.Lock()
+	c.d.queries = append(c.d.queries, query)
+	c.d.mu.Unlock()
+
+	return stubStmt{}, nil
+}
+
+func (c stubConn) Close() error {
+	return nil
+}
+
+func (c stubConn) Begin() (driver.Tx, error) {
+	return nil, errors.New("transactions are not supported")
+}
+
+type stubStmt struct{}
+
+func (stubStmt) Close() error {
+	return nil
+}
+
+func (stubStmt) NumInput() int {
+	return -1
+}
+
+func (stubStmt) Exec(args []driver.Value) (driver.Result, error) {
+	return driver.RowsAffected(1), nil
+}
+
+func (stubStmt) Query(args []driver.Value) (driver.Rows, error) {
+	return stubRows{}, nil
+}
+
+type stubRows struct{}
+
+func (stubRows) Columns() []string {
+	return nil
+}
+
+func (stubRows) Close() error {
+	return nil
+}
+
+func (stubRows) Next(dest []driver.Value) error {
+	return io.EOF
+}
+
+// stubSource 는 youtube 대신 쓴다, ID 확인은 그대로 하고 밖으로 요청하지 않는다
+type stubSource struct {
+	youtubeSource
+}
+
+func (stubSource) Resolve(id string) ([]Stream, error) {
+	return nil, errors.New("stub source")
+}
+
+func (stubSource) Metadata(id string) (*VideoMetadata, error) {
+	return nil, errors.New("stub source")
+}
+
+// setupAPI 는 저장소를 임시 디렉터리로, 기록 DB 를 stubHistory 로 바꾼다
+// 요청 제한은 부를 때마다 한 시간씩 흘려보내서 걸리지 않게 한다
+func setupAPI(t testing.TB) {
+	t.Helper()
+
+	historyStubOnce.Do(func() {
+		sql.Register("historystub", historyStub)
+	})
+
+	root := t.TempDir()
+	stores := map[*Storage]string{
+		SubtitleStore: "subtitle",
+		MediaStore:    "media",
+		GlossaryStore: "glossary",
+		AccountStore:  "account",
+	}
+
+	for store, dir := range stores {
+		previous := store.Root
+		store.Root = filepath.Join(root, dir)
+		t.Cleanup(func() { store.Root = previous })
+	}
+
+	previousDriver, previousSource, previousLimiter := historyDriver, Sources["youtube"], APILimiter
+	t.Cleanup(func() {
+		historyDriver, Sources["youtube"], APILimiter = previousDriver, previousSource, previousLimiter
+	})
+
+	historyDriver = "historystub"
+	Sources["youtube"] = stubSource{}
+
+	clock := time.Now()
+	APILimiter = NewRateLimiter()
+	APILimiter.now = func() time.Time {
+		clock = clock.Add(time.Hour)
+		return clock
+	}
+
+	historyStub.reset()
+}
+
+var backtickRegx = regexp.MustCompile("`([^`]*)`")
+
+// checkHistoryQueries 는 기록 테이블 이름이 확인한 영상 ID 하나만 백틱으로 감쌌는지 본다
+func checkHistoryQueries(t testing.TB) {
+	t.Helper()
+
+	for _, query := range historyStub.reset() {
+		names := backtickRegx.FindAllStringSubmatch(query, -1)
+		if strings.Count(query, "`") != 2*len(names) {
+			t.Fatalf("unbalanced table name: %s", query)
+		}
+
+		for _, name := range names {
+			if !mediaIDRegx.MatchString(name[1]) {
+				t.Fatalf("unsafe table name %q: %s", name[1], query)
+			}
+		}
+
+		if strings.Contains(query, ";") {
+			t.Fatalf("multiple statements: %s", query)
+		}
+	}
+}
+
+const fuzzSRT = "1\n00:00:01,000 --> 00:00:02,000\n안녕하세요\n\n2\n00:00:03,000 --> 00:00:04,000\n반갑습니다\n"
+
+func FuzzAPI(f *testing.F) {
+	f.Add("subtitle", "youtube", "jNQXAC9IVRw", "ko", "", "")
+	f.Add("save", "youtube", "jNQXAC9IVRw", "ko", "", fuzzSRT)
+	f.Add("save", "local", "clip", "en-US", "", fuzzSRT)
+	f.Add("save", "youtube", "`; DROP TABLE x; --", "ko", "", fuzzSRT)
+	f.Add("subtitle", "local", "../../etc/passwd", "ko", "", "")
+	f.Add("subtitle", "http", "clip", "../ko", "", "")
+	f.Add("video", "youtube", "jNQXAC9IVRw", "ko", "", "")
+	f.Add("completion", "youtube", "jNQXAC9IVRw", "ko", "", "")
+	f.Add("lint", "youtube", "jNQXAC9IVRw", "ko", "", "1\n00:00:02,000 --> 00:00:01,000\n\n")
+	f.Add("comment", "youtube", "jNQXAC9IVRw", "ko", "add", "")
+	f.Add("review", "youtube", "jNQXAC9IVRw", "ko", "list", "")
+	f.Add("glossary", "", "", "", "list", "")
+	f.Add("account", "", "", "", "login", "")
+	f.Add("restore", "youtube", "jNQXAC9IVRw", "ko", "", "")
+	f.Add("", "", "", "", "", "")
+
+	f.Fuzz(func(t *testing.T, call, platform, id, lang, action, subtitle string) {
+		setupAPI(t)
+
+		form := url.Values{
+			"call":     {call},
+			"platform": {platform},
+			"id":       {id},
+			"lang":     {lang},
+			"action":   {action},
+			"subtitle": {subtitle},
+			"start":    {"1000"},
+			"end":      {"2000"},
+			"text":     {"메모"},
+		}
+
+		r := httptest.NewRequest("POST", "/api", strings.NewReader(form.Encode()))
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		w := httptest.NewRecorder()
+
+		API(w, r)
+
+		switch w.Code {
+		case http.StatusOK, http.StatusBadRequest, http.StatusForbidden, http.StatusNotFound, http.StatusRequestEntityTooLarge, http.StatusTooManyRequests:
+		default:
+			t.Fatalf("status = %d: %s", w.Code, w.Body)
+		}
+
+		var result map[string]interface{}
+		if err := json.Unmarshal(w.Body.Bytes(), &result); err != nil {
+			t.Fatalf("response is not JSON (%s): %q", err, w.Body)
+		}
+
+		if w.Code != http.StatusOK {
+			if code, _ := result["code"].(float64); code == 0 {
+				t.Fatalf("status %d without error code: %s", w.Code, w.Body)
+			}
+		}
+
+		checkHistoryQueries(t)
+	})
+}
+
+func FuzzHistoryTable(f *testing.F) {
+	for _, id := range []string{"jNQXAC9IVRw", "clip", "", "a`b", "x; DROP TABLE y", strings.Repeat("a", 65), "../x"} {
+		f.Add(id)
+	}
+
+	f.Fuzz(func(t *testing.T, id string) {
+		table, err := historyTable(id)
+		if err != nil {
+			return
+		}
+
+		if table != "`"+id+"`" || strings.ContainsAny(id, "`;'\" \\/") || len(id) > 64 {
+			t.Fatalf("historyTable(%q) = %q", id, table)
+		}
+	})
+}
+
+func FuzzValidateLang(f *testing.F) {
+	for _, lang := range []string{"ko", "en-US", "zh-Hant", "", "..", "ko/../en", "ko\x00", "KO"} {
+		f.Add(lang)
+	}
+
+	f.Fuzz(func(t *testing.T, lang string) {
+		if ValidateLang(lang) != nil {
+			return
+		}
+
+		// 언어는 파일 이름 (lang.srt) 에 그대로 들어가므로 이스케이프할 글자가 없어야 한다
+		encoded, err := EncodeSegment(lang + ".srt")
+		if err != nil || encoded != lang+".srt" {
+			t.Fatalf("ValidateLang accepted %q (encoded %q, %v)", lang, encoded, err)
+		}
+	})
+}
+
+func FuzzGetSource(f *testing.F) {
+	for _, seed := range [][2]string{
+		{"youtube", "jNQXAC9IVRw"},
+		{"youtube", "short"},
+		{"http", "clip"},
+		{"local", "../clip"},
+		{"local", "clip.mp4"},
+		{"ftp", "clip"},
+		{"", ""},
+	} {
+		f.Add(seed[0], seed[1])
+	}
+
+	f.Fuzz(func(t *testing.T, platform, id string) {
+		source, err := GetSource(platform, id)
+		if err != nil {
+			return
+		}
+
+		if source == nil || Sources[platform] == nil {
+			t.Fatalf("GetSource(%q, %q) returned no source", platform, id)
+		}
+
+		// 확인한 platform, id 로 만든 경로는 저장소 밖으로 나가지 않고 한 칸씩만 쓴다
+		store := &Storage{Root: t.TempDir()}
+		path, err := store.Path(platform, id)
+		if err != nil {
+			t.Fatalf("GetSource accepted %q/%q but Path failed: %v", platform, id, err)
+		}
+
+		if path != filepath.Join(store.Root, platform, id) {
+			t.Fatalf("GetSource accepted %q/%q that needs escaping: %s", platform, id, path)
+		}
+	})
+}
+
+// 기록 테이블은 처음 저장할 때 한 번만 만들고 고친다
+func TestAddSubtitleMigratesOnce(t *testing.T) {
+	setupAPI(t)
+
+	historyMigrated.Delete("`" + proxyTestID + "`")
+	t.Cleanup(func() { historyMigrated.Delete("`" + proxyTestID + "`") })
+
+	AddSubtitle(proxyTestID, "kim", "203.0.113.7", "ko")
+
+	queries := historyStub.reset()
+	if len(queries) != 3 || !strings.HasPrefix(queries[0], "CREATE TABLE") || !strings.HasPrefix(queries[1], "ALTER TABLE") || !strings.HasPrefix(queries[2], "INSERT") {
+		t.Fatalf("first save queries = %q", queries)
+	}
+
+	AddSubtitle(proxyTestID, "kim", "203.0.113.7", "en")
+
+	if queries := historyStub.reset(); len(queries) != 1 || !strings.HasPrefix(queries[0], "INSERT") {
+		t.Errorf("second save queries = %q", queries)
+	}
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"os"
+)
+
+const (
+	wavFormatPCM        = 1
+	wavFormatFloat      = 3
+	wavFormatExtensible = 0xFFFE
+)
+
+// Audio 는 모노로 합쳐진 -1~1 범위의 샘플
+type Audio struct {
+	Rate    int
+	Samples []float32
+}
+
+func (a *Audio) Duration() float64 {
+	if a.Rate == 0 {
+		return 0
+	}
+
+	return float64(len(a.Samples)) / float64(a.Rate)
+}
+
+func MediaPath(platform, id string) (string, error) {
+	return MediaStore.Path(platform, id+".wav")
+}
+
+func LoadAudio(platform, id string) (*Audio, error) {
+	path, err := MediaPath(platform, id)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return DecodeWAV(file)
+}
+
+// DecodeWAV 는 RIFF/WAVE 파일의 PCM(8/16/24/32bit) 또는 float32 데이터를 읽어 모노로 합친다
+func DecodeWAV(r io.Reader) (*Audio, error) {
+	var header [12]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return nil, err
+	}
+
+	if string(header[0:4]) != "RIFF" || string(header[8:12]) != "WAVE" {
+		return nil, fmt.Errorf("not a wave file")
+	}
+
+	var (
+		format, channels, bits uint16
+		rate                   uint32
+		fmtFound               bool
+	)
+
+	for {
+		var chunk [8]byte
+		if _, err := io.ReadFull(r, chunk[:]); err != nil {
+			return nil, fmt.Errorf("data chunk not found")
+		}
+
+		size := binary.LittleEndian.Uint32(chunk[4:8])
+
+		switch string(chunk[0:4]) {
+		case "fmt ":
+			if size < 16 {
+				return nil, fmt.Errorf("invalid fmt chunk")
+			}
+
+			body := make([]byte, size+size%2)
+			if _, err := io.ReadFull(r, body); err != nil {
+				return nil, err
+			}
+
+			format = binary.LittleEndian.Uint16(body[0:2])
+			channels = binary.LittleEndian.Uint16(body[2:4])
+			rate = binary.LittleEndian.Uint32(body[4:8])
+			bits = binary.LittleEndian.Uint16(body[14:16])
+
+			if format == wavFormatExtensible && size >= 26 {
+				format = binary.LittleEndian.Uint16(body[24:26])
+			}
+
+			fmtFound = true
+		case "data":
+			if !fmtFound {
+				return nil, fmt.Errorf("fmt chunk missing")
+			}
+
+			if channels == 0 || rate == 0 {
+				return nil, fmt.Errorf("invalid channel count or sample rate")
+			}
+
+			data := make([]byte, size)
+			n, err := io.ReadFull(r, data)
+			if err != nil && err != io.ErrUnexpectedEOF {
+				return nil, err
+			}
+
+			samples, err := decodePCM(data[:n], format, bits, int(channels))
+			if err != nil {
+				return nil, err
+			}
+
+			return &Audio{
+				Rate:    int(rate),
+				Samples: samples,
+			}, nil
+		default:
+			if _, err := io.CopyN(ioutil.Discard, r, int64(size+size%2)); err != nil {
+				return nil, err
+			}
+		}
+	}
+}
+
+func decodePCM(data []byte, format, bits uint16, channels int) ([]float32, error) {
+	width := int(bits) / 8
+
+	var sample func(b []byte) float32
+
+	switch {
+	case format == wavFormatPCM && bits == 8:
+		sample = func(b []byte) float32 { return (float32(b[0]) - 128) / 128 }
+	case format == wavFormatPCM && bits == 16:
+		sample = func(b []byte) float32 { return float32(int16(binary.LittleEndian.Uint16(b))) / 32768 }
+	case format == wavFormatPCM && bits == 24:
+		sample = func(b []byte) float32 {
+			v := int32(b[0]) | int32(b[1])<<8 | int32(int8(b[2]))<<16
+			return float32(v) / 8388608
+		}
+	case format == wavFormatPCM && bits == 32:
+		sample = func(b []byte) float32 { return float32(int32(binary.LittleEndian.Uint32(b))) / 2147483648 }
+	case format == wavFormatFloat && bits == 32:
+		sample = func(b []byte) float32 { return math.Float32frombits(binary.LittleEndian.Uint32(b)) }
+	default:
+		return nil, fmt.Errorf("unsupported wave format %d (%d bits)", format, bits)
+	}
+
+	frame := width * channels
+	samples := make([]float32, len(data)/frame)
+
+	for i := range samples {
+		var sum float32
+		for c := 0; c < channels; c++ {
+			offset := i*frame + c*width
+			sum += sample(data[offset : offset+width])
+		}
+		samples[i] = sum / float32(channels)
+	}
+
+	return samples, nil
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"os"
+	"testing"
+)
+
+// wavFile 은 fmt 와 data 청크 하나씩으로 된 wave 파일을 만든다
+func wavFile(format uint16, bits, channels, rate int, data []byte) []byte {
+	var fmtChunk bytes.Buffer
+	binary.Write(&fmtChunk, binary.LittleEndian, format)
+	binary.Write(&fmtChunk, binary.LittleEndian, uint16(channels))
+	binary.Write(&fmtChunk, binary.LittleEndian, uint32(rate))
+	binary.Write(&fmtChunk, binary.LittleEndian, uint32(rate*channels*bits/8))
+	binary.Write(&fmtChunk, binary.LittleEndian, uint16(channels*bits/8))
+	binary.Write(&fmtChunk, binary.LittleEndian, uint16(bits))
+
+	if format == wavFormatExtensible {
+		// cbSize, validBits, channelMask 다음에 실제 형식이 온다
+		binary.Write(&fmtChunk, binary.LittleEndian, uint16(22))
+		binary.Write(&fmtChunk, binary.LittleEndian, uint16(bits))
+		binary.Write(&fmtChunk, binary.LittleEndian, uint32(0))
+		binary.Write(&fmtChunk, binary.LittleEndian, uint16(wavFormatFloat))
+		fmtChunk.Write(make([]byte, 14))
+	}
+
+	var chunks bytes.Buffer
+	chunks.WriteString("fmt ")
+	binary.Write(&chunks, binary.LittleEndian, uint32(fmtChunk.Len()))
+	chunks.Write(fmtChunk.Bytes())
+	chunks.WriteString("data")
+	binary.Write(&chunks, binary.LittleEndian, uint32(len(data)))
+	chunks.Write(data)
+
+	var file bytes.Buffer
+	file.WriteString("RIFF")
+	binary.Write(&file, binary.LittleEndian, uint32(4+chunks.Len()))
+	file.WriteString("WAVE")
+	file.Write(chunks.Bytes())
+
+	return file.Bytes()
+}
+
+func littleEndian(values ...interface{}) []byte {
+	var buf bytes.Buffer
+	for _, v := range values {
+		binary.Write(&buf, binary.LittleEndian, v)
+	}
+
+	return buf.Bytes()
+}
+
+func TestDecodeWAV(t *testing.T) {
+	tests := []struct {
+		name string
+		file []byte
+		rate int
+		want []float32
+	}{
+		{"pcm8", wavFile(wavFormatPCM, 8, 1, 8000, []byte{128, 192, 0}), 8000, []float32{0, 0.5, -1}},
+		{"pcm16", wavFile(wavFormatPCM, 16, 1, 16000, littleEndian(int16(0), int16(16384), int16(-32768))), 16000, []float32{0, 0.5, -1}},
+		{"pcm24", wavFile(wavFormatPCM, 24, 1, 48000, []byte{0, 0, 0x40, 0, 0, 0x80, 0, 0, 0xe0}), 48000, []float32{0.5, -1, -0.25}},
+		{"pcm32", wavFile(wavFormatPCM, 32, 1, 44100, littleEndian(int32(1<<30), int32(math.MinInt32))), 44100, []float32{0.5, -1}},
+		{"float32", wavFile(wavFormatFloat, 32, 1, 22050, littleEndian(float32(0.25), float32(-0.75))), 22050, []float32{0.25, -0.75}},
+		{"extensible float32", wavFile(wavFormatExtensible, 32, 1, 22050, littleEndian(float32(0.25))), 22050, []float32{0.25}},
+		{"stereo mixdown", wavFile(wavFormatPCM, 16, 2, 8000, littleEndian(int16(16384), int16(-16384), int16(16384), int16(16384))), 8000, []float32{0, 0.5}},
+		{"trailing partial frame", wavFile(wavFormatPCM, 16, 2, 8000, littleEndian(int16(16384), int16(16384), int16(1))), 8000, []float32{0.5}},
+	}
+
+	for _, test := range tests {
+		audio, err := DecodeWAV(bytes.NewReader(test.file))
+		if err != nil {
+			t.Errorf("%s: %v", test.name, err)
+			continue
+		}
+
+		if audio.Rate != test.rate || len(audio.Samples) != len(test.want) {
+			t.Errorf("%s: rate = %d, samples = %v, want %d, %v", test.name, audio.Rate, audio.Samples, test.rate, test.want)
+			continue
+		}
+
+		for i, want := range test.want {
+			if math.Abs(float64(audio.Samples[i]-want)) > 1e-4 {
+				t.Errorf("%s: samples = %v, want %v", test.name, audio.Samples, test.want)
+				break
+			}
+		}
+	}
+}
+
+// data 청크가 적힌 크기보다 짧으면 있는 만큼만 읽는다
+func TestDecodeWAVTruncatedData(t *testing.T) {
+	file := wavFile(wavFormatPCM, 16, 1, 8000, littleEndian(int16(16384), int16(16384), int16(16384), int16(16384)))
+
+	audio, err := DecodeWAV(bytes.NewReader(file[:len(file)-4]))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(audio.Samples) != 2 {
+		t.Errorf("samples = %v, want 2", audio.Samples)
+	}
+}
+
+func TestDecodeWAVInvalid(t *testing.T) {
+	pcm16 := wavFile(wavFormatPCM, 16, 1, 8000, littleEndian(int16(1)))
+
+	// fmt 청크 없이 data 만 있는 파일
+	dataOnly := append([]byte("RIFF\x00\x00\x00\x00WAVE"), []byte("data\x02\x00\x00\x00\x01\x00")...)
+	// fmt 청크가 16바이트보다 짧은 파일
+	shortFmt := append([]byte("RIFF\x00\x00\x00\x00WAVE"), []byte("fmt \x04\x00\x00\x00\x01\x00\x01\x00")...)
+
+	tests := []struct {
+		name string
+		file []byte
+	}{
+		{"empty", nil},
+		{"truncated header", []byte("RIFF\x00\x00")},
+		{"garbage", []byte("this is not a wave file at all")},
+		{"not wave", append([]byte("RIFF\x00\x00\x00\x00AVI "), pcm16[12:]...)},
+		{"no data chunk", pcm16[:36]},
+		{"data before fmt", dataOnly},
+		{"short fmt", shortFmt},
+		{"unsupported bits", wavFile(wavFormatPCM, 12, 1, 8000, []byte{0, 0})},
+		{"float64", wavFile(wavFormatFloat, 64, 1, 8000, make([]byte, 8))},
+		{"no channels", wavFile(wavFormatPCM, 16, 0, 8000, []byte{0, 0})},
+		{"no rate", wavFile(wavFormatPCM, 16, 1, 0, []byte{0, 0})},
+	}
+
+	for _, test := range tests {
+		if audio, err := DecodeWAV(bytes.NewReader(test.file)); err == nil {
+			t.Errorf("%s: decoded %d samples, want error", test.name, len(audio.Samples))
+		}
+	}
+}
+
+// testdata/tone.wav 는 8kHz 16bit 모노, 0.5초 무음 뒤에 0.5초 동안 크기 0.5 의 440Hz, data 앞에 LIST 청크가 있다
+func TestDecodeWAVFixture(t *testing.T) {
+	file, err := os.Open("testdata/tone.wav")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	audio, err := DecodeWAV(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if audio.Rate != 8000 || len(audio.Samples) != 8000 || audio.Duration() != 1 {
+		t.Fatalf("rate = %d, samples = %d, duration = %v", audio.Rate, len(audio.Samples), audio.Duration())
+	}
+
+	var silence, peak float32
+	for i, s := range audio.Samples {
+		if i < 4000 {
+			silence = float32(math.Max(float64(silence), math.Abs(float64(s))))
+		} else {
+			peak = float32(math.Max(float64(peak), math.Abs(float64(s))))
+		}
+	}
+
+	if silence != 0 || math.Abs(float64(peak-0.5)) > 0.01 {
+		t.Errorf("silence peak = %v, tone peak = %v, want 0 and 0.5", silence, peak)
+	}
+}
@@ -0,0 +1,454 @@
+package main
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+var (
+	AccountDir     = filepath.Join(DataDir, "account")
+	AuthConfigPath = filepath.Join(DataDir, "auth.json")
+)
+
+const (
+	SessionCookie = "jamak_session"
+	SessionMaxAge = 30 * 24 * time.Hour
+
+	minPasswordLen = 8
+	// bcrypt 는 72바이트 뒤를 무시한다
+	maxPasswordLen = 72
+)
+
+// 익명 저장 정책
+const (
+	AnonymousAllow = "allow" // 로그인하지 않아도 저장, 기록에는 서버가 본 IP 가 남는다
+	AnonymousDeny  = "deny"  // 로그인해야 저장
+)
+
+// AuthConfig 는 {"anonymous": "allow" 또는 "deny", "signup": true, "defaultRole": "contributor"} 형식
+// 파일이 없으면 익명 저장과 가입을 허용하고, 가입한 사람은 contributor 가 된다
+// 가입은 관리자가 있을 때만 열린다, 첫 관리자는 서버에서 -create-admin 으로 만든다
+type AuthConfig struct {
+	Anonymous   string `json:"anonymous"`
+	Signup      bool   `json:"signup"`
+	DefaultRole string `json:"defaultRole"`
+}
+
+// User 는 AccountDir/users/<name>.json 에 저장한다, API 토큰은 해시만 들고 있는다
+// Role 이 비어 있으면 설정의 defaultRole 을 쓴다
+type User struct {
+	Name         string      `json:"name"`
+	PasswordHash string      `json:"passwordHash"`
+	CreatedAt    time.Time   `json:"createdAt"`
+	Role         string      `json:"role,omitempty"`
+	Grants       []RoleGrant `json:"grants,omitempty"`
+	Tokens       []APIToken  `json:"tokens"`
+}
+
+type APIToken struct {
+	ID         string    `json:"id"`
+	Label      string    `json:"label"`
+	Hash       string    `json:"hash,omitempty"`
+	CreatedAt  time.Time `json:"createdAt"`
+	LastUsedAt time.Time `json:"lastUsedAt"`
+}
+
+// session 은 AccountDir/sessions/<쿠키 값의 해시>.json 에 저장한다
+type session struct {
+	Name      string    `json:"name"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+// AccountJSON 의 Role 은 요청에 platform, id, lang 이 있으면 그 범위에서의 역할
+type AccountJSON struct {
+	Name      string        `json:"name"`
+	Role      string        `json:"role"`
+	Anonymous string        `json:"anonymous"`
+	Signup    bool          `json:"signup"`
+	Token     string        `json:"token,omitempty"`
+	Tokens    []APIToken    `json:"tokens,omitempty"`
+	Users     []AccountUser `json:"users,omitempty"`
+	Code      int           `json:"code"`
+}
+
+var (
+	AccountStore = &Storage{Root: AccountDir}
+
+	userNameRegx = regexp.MustCompile(`^[A-Za-z0-9_-]{3,32}$`)
+
+	accountMu sync.Mutex
+)
+
+// SignupOpen 은 설정에서 가입을 허용했고 관리자가 한 명이라도 있을 때 true
+// 관리자가 없을 때 가입을 받으면 아무도 역할을 관리할 수 없고, 누가 먼저 가입하느냐로 관리자가 정해지면 안 된다
+func (c AuthConfig) SignupOpen() bool {
+	return c.Signup && hasAdmin()
+}
+
+func LoadAuthConfig() AuthConfig {
+	config := AuthConfig{
+		Anonymous:   AnonymousAllow,
+		Signup:      true,
+		DefaultRole: RoleContributor,
+	}
+
+	file, err := ioutil.ReadFile(AuthConfigPath)
+	if err != nil {
+		return config
+	}
+
+	if err := json.Unmarshal(file, &config); err != nil {
+		fmt.Println(err)
+	}
+
+	if config.Anonymous != AnonymousDeny {
+		config.Anonymous = AnonymousAllow
+	}
+
+	if ValidateRole(config.DefaultRole) != nil {
+		config.DefaultRole = RoleContributor
+	}
+
+	return config
+}
+
+func ValidateUserName(name string) error {
+	if !userNameRegx.MatchString(name) {
+		return fmt.Errorf("invalid user name: %s", name)
+	}
+
+	return nil
+}
+
+func ValidatePassword(password string) error {
+	if len(password) < minPasswordLen || len(password) > maxPasswordLen {
+		return fmt.Errorf("password must be %d-%d bytes", minPasswordLen, maxPasswordLen)
+	}
+
+	return nil
+}
+
+// randomSecret 은 쿠키와 토큰에 쓰는 추측할 수 없는 값
+func randomSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(b), nil
+}
+
+func hashSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+func LoadUser(name string) (*User, error) {
+	if err := ValidateUserName(name); err != nil {
+		return nil, err
+	}
+
+	file, err := AccountStore.ReadFile("users", name+".json")
+	if err != nil {
+		return nil, err
+	}
+
+	var user User
+	if err := json.Unmarshal(file, &user); err != nil {
+		return nil, err
+	}
+
+	return &user, nil
+}
+
+func storeUser(user *User) error {
+	file, err := json.Marshal(user)
+	if err != nil {
+		return err
+	}
+
+	return AccountStore.WriteFile(file, "users", user.Name+".json")
+}
+
+// CreateUser 는 가입한 사람을 만든다, 역할은 설정의 defaultRole 을 따른다
+// 이름이 이미 있으면 os.ErrExist 를 돌려준다
+func CreateUser(name, password string) (*User, error) {
+	return createUser(name, password, "")
+}
+
+// CreateAdmin 은 서버에서 (-create-admin) 관리자를 만든다, 가입은 관리자가 생긴 뒤에 열린다
+func CreateAdmin(name, password string) (*User, error) {
+	return createUser(name, password, RoleAdmin)
+}
+
+func createUser(name, password, role string) (*User, error) {
+	if err := ValidateUserName(name); err != nil {
+		return nil, err
+	}
+
+	if err := ValidatePassword(password); err != nil {
+		return nil, err
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, err
+	}
+
+	accountMu.Lock()
+	defer accountMu.Unlock()
+
+	// 대소문자만 다른 이름은 같은 사람으로 보이므로 소문자로 확인한다
+	if _, err := AccountStore.ReadFile("names", strings.ToLower(name)); err == nil {
+		return nil, os.ErrExist
+	}
+
+	user := &User{
+		Name:         name,
+		PasswordHash: string(hash),
+		CreatedAt:    time.Now(),
+		Role:         role,
+		Tokens:       []APIToken{},
+	}
+
+	if err := storeUser(user); err != nil {
+		return nil, err
+	}
+
+	if err := AccountStore.WriteFile([]byte(name), "names", strings.ToLower(name)); err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+// CheckPassword 는 이름이 없을 때도 비밀번호가 틀렸을 때와 같은 에러를 돌려준다
+func CheckPassword(name, password string) (*User, error) {
+	user, err := LoadUser(name)
+	if err != nil {
+		return nil, fmt.Errorf("wrong name or password")
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password)); err != nil {
+		return nil, fmt.Errorf("wrong name or password")
+	}
+
+	return user, nil
+}
+
+// StartSession 은 세션을 만들고 쿠키를 건다
+func StartSession(w http.ResponseWriter, name string) error {
+	secret, err := randomSecret()
+	if err != nil {
+		return err
+	}
+
+	file, err := json.Marshal(session{
+		Name:      name,
+		ExpiresAt: time.Now().Add(SessionMaxAge),
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := AccountStore.WriteFile(file, "sessions", hashSecret(secret)+".json"); err != nil {
+		return err
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     SessionCookie,
+		Value:    secret,
+		Path:     "/",
+		MaxAge:   int(SessionMaxAge / time.Second),
+		Secure:   true,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+
+	return nil
+}
+
+func EndSession(w http.ResponseWriter, r *http.Request) {
+	if cookie, err := r.Cookie(SessionCookie); err == nil && len(cookie.Value) != 0 {
+		if path, err := AccountStore.Path("sessions", hashSecret(cookie.Value)+".json"); err == nil {
+			os.Remove(path)
+		}
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     SessionCookie,
+		Value:    "",
+		Path:     "/",
+		MaxAge:   -1,
+		Secure:   true,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+func sessionUser(secret string) (*User, error) {
+	file, err := AccountStore.ReadFile("sessions", hashSecret(secret)+".json")
+	if err != nil {
+		return nil, fmt.Errorf("invalid session")
+	}
+
+	var s session
+	if err := json.Unmarshal(file, &s); err != nil {
+		return nil, err
+	}
+
+	if time.Now().After(s.ExpiresAt) {
+		if path, err := AccountStore.Path("sessions", hashSecret(secret)+".json"); err == nil {
+			os.Remove(path)
+		}
+
+		return nil, fmt.Errorf("session expired")
+	}
+
+	return LoadUser(s.Name)
+}
+
+// CreateToken 은 "<이름>.<비밀값>" 형식의 토큰을 만든다, 토큰 원문은 이때 한 번만 돌려준다
+func CreateToken(name, label string) (string, *APIToken, error) {
+	secret, err := randomSecret()
+	if err != nil {
+		return "", nil, err
+	}
+
+	id, err := randomSecret()
+	if err != nil {
+		return "", nil, err
+	}
+
+	accountMu.Lock()
+	defer accountMu.Unlock()
+
+	user, err := LoadUser(name)
+	if err != nil {
+		return "", nil, err
+	}
+
+	token := APIToken{
+		ID:        id[:12],
+		Label:     strings.TrimSpace(label),
+		Hash:      hashSecret(secret),
+		CreatedAt: time.Now(),
+	}
+	user.Tokens = append(user.Tokens, token)
+
+	if err := storeUser(user); err != nil {
+		return "", nil, err
+	}
+
+	token.Hash = ""
+
+	return user.Name + "." + secret, &token, nil
+}
+
+func RevokeToken(name, id string) error {
+	accountMu.Lock()
+	defer accountMu.Unlock()
+
+	user, err := LoadUser(name)
+	if err != nil {
+		return err
+	}
+
+	tokens := user.Tokens[:0]
+	for _, token := range user.Tokens {
+		if token.ID != id {
+			tokens = append(tokens, token)
+		}
+	}
+
+	if len(tokens) == len(user.Tokens) {
+		return fmt.Errorf("unknown token: %s", id)
+	}
+	user.Tokens = tokens
+
+	return storeUser(user)
+}
+
+// PublicTokens 는 해시를 뺀 토큰 목록
+func (user *User) PublicTokens() []APIToken {
+	tokens := []APIToken{}
+	for _, token := range user.Tokens {
+		token.Hash = ""
+		tokens = append(tokens, token)
+	}
+
+	return tokens
+}
+
+func tokenUser(token string) (*User, error) {
+	i := strings.LastIndex(token, ".")
+	if i < 0 {
+		return nil, fmt.Errorf("invalid token")
+	}
+
+	name, hash := token[:i], hashSecret(token[i+1:])
+
+	accountMu.Lock()
+	defer accountMu.Unlock()
+
+	user, err := LoadUser(name)
+	if err != nil {
+		return nil, fmt.Errorf("invalid token")
+	}
+
+	for i := range user.Tokens {
+		if subtle.ConstantTimeCompare([]byte(user.Tokens[i].Hash), []byte(hash)) == 1 {
+			// 마지막 사용 시간은 하루에 한 번만 기록한다
+			if time.Since(user.Tokens[i].LastUsedAt) > 24*time.Hour {
+				user.Tokens[i].LastUsedAt = time.Now()
+				if err := storeUser(user); err != nil {
+					fmt.Println(err)
+				}
+			}
+
+			return user, nil
+		}
+	}
+
+	return nil, fmt.Errorf("invalid token")
+}
+
+func editorName(user *User) string {
+	if user == nil {
+		return ""
+	}
+
+	return user.Name
+}
+
+// Authenticate 는 Authorization: Bearer 토큰 -> 세션 쿠키 순으로 사용자를 찾는다
+// 둘 다 없으면 nil, nil 이고, 있는데 맞지 않으면 에러
+func Authenticate(r *http.Request) (*User, error) {
+	if header := r.Header.Get("Authorization"); len(header) != 0 {
+		if !strings.HasPrefix(header, "Bearer ") {
+			return nil, fmt.Errorf("unsupported authorization")
+		}
+
+		return tokenUser(strings.TrimSpace(strings.TrimPrefix(header, "Bearer ")))
+	}
+
+	if cookie, err := r.Cookie(SessionCookie); err == nil && len(cookie.Value) != 0 {
+		return sessionUser(cookie.Value)
+	}
+
+	return nil, nil
+}
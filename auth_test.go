@@ -0,0 +1,67 @@
+package main
+
+import (
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestSignupNeedsAdmin(t *testing.T) {
+	setupAPI(t)
+
+	config := AuthConfig{Signup: true}
+	if config.SignupOpen() {
+		t.Fatal("signup is open before an admin exists")
+	}
+
+	user, err := CreateUser("first", "password1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 먼저 가입했다고 관리자가 되지 않는다
+	if user.Role == RoleAdmin {
+		t.Fatal("first user became admin")
+	}
+
+	if config.SignupOpen() {
+		t.Fatal("signup is open without an admin")
+	}
+
+	admin, err := CreateAdmin("root", "password1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if admin.Role != RoleAdmin {
+		t.Fatalf("admin role = %q", admin.Role)
+	}
+
+	if !config.SignupOpen() {
+		t.Error("signup is closed after an admin was created")
+	}
+
+	if (AuthConfig{Signup: false}).SignupOpen() {
+		t.Error("signup is open when disabled in config")
+	}
+}
+
+func TestSignupAPIWithoutAdmin(t *testing.T) {
+	setupAPI(t)
+
+	form := url.Values{"call": {"account"}, "action": {"signup"}, "name": {"first"}, "password": {"password1"}}
+	r := httptest.NewRequest("POST", "/api", strings.NewReader(form.Encode()))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w := httptest.NewRecorder()
+
+	API(w, r)
+
+	if !strings.Contains(w.Body.String(), `"code":1402`) {
+		t.Errorf("signup without admin = %d %s, want code 1402", w.Code, w.Body)
+	}
+
+	if _, err := LoadUser("first"); err == nil {
+		t.Error("user was created while signup is closed")
+	}
+}
@@ -0,0 +1,234 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+
+	"github.com/maxence-charriere/go-app/v7/pkg/app"
+)
+
+const (
+	// 서버의 인증, 권한, 요청 제한 에러 코드 (모든 호출 공통)
+	apiAuthCode      = 2
+	apiForbiddenCode = 3
+	apiRateLimitCode = 4
+
+	roleContributor = "contributor"
+)
+
+// 서버와 같은 역할 순서
+var roleRanks = map[string]int{
+	"viewer":        1,
+	roleContributor: 2,
+	"reviewer":      3,
+	"admin":         4,
+}
+
+type accountJSON struct {
+	Name   string `json:"name"`
+	Role   string `json:"role"`
+	Signup bool   `json:"signup"`
+	Msg    string `json:"msg"`
+	Code   int    `json:"code"`
+}
+
+// PostAccount 는 account API 를 부른다, 세션 쿠키는 같은 도메인이라 브라우저가 알아서 붙인다
+func PostAccount(data url.Values) (*accountJSON, error) {
+	data.Set("call", "account")
+
+	resp, err := http.PostForm(ApiServer, data)
+	if err != nil {
+		return nil, err
+	}
+
+	body, _ := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+
+	var result accountJSON
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, err
+	}
+
+	if result.Code != 0 {
+		return &result, fmt.Errorf("%s", result.Msg)
+	}
+
+	return &result, nil
+}
+
+func (p *player) setAccount(result *accountJSON) {
+	p.user.name = result.Name
+	p.user.role = result.Role
+	p.user.signup = result.Signup
+}
+
+// accountData 는 지금 편집 중인 영상과 언어에서의 역할을 받도록 범위를 붙인다
+func (p *player) accountData(action string) url.Values {
+	data := url.Values{}
+	data.Add("action", action)
+	data.Add("platform", p.platform)
+	data.Add("id", p.youtubeID)
+	data.Add("lang", "ko")
+
+	return data
+}
+
+// LoadAccount 는 로그인했는지, 이 영상에서 무엇을 할 수 있는지 확인한다
+func (p *player) LoadAccount() {
+	result, err := PostAccount(p.accountData("me"))
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	p.setAccount(result)
+}
+
+// Login 은 action 이 signup 이면 가입하고 바로 로그인한다
+func (p *player) Login(action string) {
+	data := p.accountData(action)
+	data.Add("name", p.user.formName)
+	data.Add("password", p.user.formPassword)
+
+	result, err := PostAccount(data)
+	if err != nil {
+		app.Window().Call("alert", err.Error())
+		return
+	}
+
+	p.setAccount(result)
+	p.user.formPassword = ""
+	p.user.open = false
+	p.LoadReviews()
+
+	// 로그인을 기다리던 저장을 보낸다
+	if p.offline.queued != 0 {
+		p.ReplaySaves()
+	}
+}
+
+func (p *player) Logout() {
+	result, err := PostAccount(p.accountData("logout"))
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	p.setAccount(result)
+	p.LoadReviews()
+}
+
+// CanEdit 은 저장할 수 있는 역할인지 확인한다, 서버에서 다시 확인하므로 화면에만 쓴다
+// 역할을 아직 모르면(오프라인) 저장 대기열에 넣을 수 있게 true
+func (p *player) CanEdit() bool {
+	return len(p.user.role) == 0 || roleRanks[p.user.role] >= roleRanks[roleContributor]
+}
+
+// NeedLogin 은 저장할 수 없으면 true, 로그인하지 않았으면 로그인 창을 연다
+func (p *player) NeedLogin() bool {
+	if p.CanEdit() {
+		return false
+	}
+
+	if len(p.user.name) == 0 {
+		p.user.open = true
+	}
+
+	return true
+}
+
+// DeniedMessage 는 저장할 수 없을 때 보여줄 말
+func (p *player) DeniedMessage() string {
+	if len(p.user.name) == 0 {
+		return "로그인해야 저장할 수 있습니다"
+	}
+
+	return "이 자막을 저장할 권한이 없습니다"
+}
+
+func (p *player) RenderAccount() app.UI {
+	if len(p.user.name) != 0 {
+		return app.Div().Body(
+			app.Span().Body(
+				app.Text(p.user.name),
+			).
+				Class("account-name").
+				Title(p.user.role),
+			app.Button().Body(
+				app.Text("로그아웃"),
+			).
+				Class("btn btn-gray").
+				Type("button").
+				OnClick(func(ctx app.Context, e app.Event) {
+					p.Logout()
+					p.Update()
+				}),
+		).
+			Class("account")
+	}
+
+	if !p.user.open {
+		return app.Div().Body(
+			app.Button().Body(
+				app.Text("로그인"),
+			).
+				Class("btn btn-gray").
+				Type("button").
+				OnClick(func(ctx app.Context, e app.Event) {
+					p.user.open = true
+					p.Update()
+				}),
+		).
+			Class("account")
+	}
+
+	return app.Div().Body(
+		app.Input().
+			Class("account-input").
+			Placeholder("이름").
+			Value(p.user.formName).
+			OnInput(func(ctx app.Context, e app.Event) {
+				p.user.formName = ctx.JSSrc.JSValue().Get("value").String()
+			}),
+		app.Input().
+			Type("password").
+			Class("account-input").
+			Placeholder("비밀번호").
+			Value(p.user.formPassword).
+			OnInput(func(ctx app.Context, e app.Event) {
+				p.user.formPassword = ctx.JSSrc.JSValue().Get("value").String()
+			}).
+			OnKeyDown(func(ctx app.Context, e app.Event) {
+				if e.Get("key").String() != "Enter" {
+					return
+				}
+
+				p.Login("login")
+				p.Update()
+			}),
+		app.Button().Body(
+			app.Text("로그인"),
+		).
+			Class("btn btn-blue").
+			Type("button").
+			OnClick(func(ctx app.Context, e app.Event) {
+				p.Login("login")
+				p.Update()
+			}),
+		app.If(p.user.signup,
+			app.Button().Body(
+				app.Text("가입"),
+			).
+				Class("btn btn-gray").
+				Type("button").
+				OnClick(func(ctx app.Context, e app.Event) {
+					p.Login("signup")
+					p.Update()
+				}),
+		),
+	).
+		Class("account")
+}
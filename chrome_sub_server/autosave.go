@@ -0,0 +1,240 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/maxence-charriere/go-app/v7/pkg/app"
+)
+
+const draftDelay = time.Second
+
+type autosave struct {
+	app.Compo
+
+	baseVersion string
+	timer       *time.Timer
+
+	recovered *draft
+	diff      []diffLine
+}
+
+// draft 는 브라우저에 임시 저장되는 편집 중인 자막
+type draft struct {
+	Platform    string    `json:"platform"`
+	ID          string    `json:"id"`
+	Lang        string    `json:"lang"`
+	BaseVersion string    `json:"baseVersion"`
+	Subtitle    string    `json:"subtitle"`
+	SavedAt     time.Time `json:"savedAt"`
+}
+
+type diffLine struct {
+	Op   string
+	Text string
+}
+
+func DraftKey(platform, id, lang string) string {
+	return fmt.Sprintf("draft:%s:%s:%s", platform, id, lang)
+}
+
+// ScheduleDraft 는 마지막 수정 후 draftDelay 가 지나면 임시 저장하고 용어 검사를 다시 한다
+func (p *player) ScheduleDraft() {
+	p.TrackUndo()
+
+	if p.autosave.timer != nil {
+		p.autosave.timer.Stop()
+	}
+
+	p.autosave.timer = time.AfterFunc(draftDelay, func() {
+		app.Dispatch(func() {
+			p.SaveDraft()
+			p.Lint()
+		})
+	})
+}
+
+func (p *player) SaveDraft() {
+	if len(p.youtubeID) == 0 {
+		return
+	}
+
+	err := app.LocalStorage.Set(DraftKey(p.platform, p.youtubeID, "ko"), draft{
+		Platform:    p.platform,
+		ID:          p.youtubeID,
+		Lang:        "ko",
+		BaseVersion: p.autosave.baseVersion,
+		Subtitle:    FormatSrtSub(p.subtitle.youtubeSrtSub),
+		SavedAt:     time.Now(),
+	})
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	fmt.Println("임시 저장")
+}
+
+func (p *player) DelDraft() {
+	if p.autosave.timer != nil {
+		p.autosave.timer.Stop()
+	}
+
+	app.LocalStorage.Del(DraftKey(p.platform, p.youtubeID, "ko"))
+}
+
+// CheckDraft 는 서버 자막과 다른 임시 저장본이 있으면 복구할지 물어볼 수 있게 차이를 계산해 둔다
+func (p *player) CheckDraft() {
+	var saved draft
+	if err := app.LocalStorage.Get(DraftKey(p.platform, p.youtubeID, "ko"), &saved); err != nil || len(saved.Subtitle) == 0 {
+		return
+	}
+
+	if saved.Subtitle == FormatSrtSub(p.subtitle.youtubeSrtSub) {
+		p.DelDraft()
+		return
+	}
+
+	subs, err := ParseSrtSub(saved.Subtitle)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	fmt.Printf("임시 저장본 발견 (%s, 기준 버전: %s)\n", saved.SavedAt.Format("2006-01-02 15:04:05"), saved.BaseVersion)
+
+	p.autosave.recovered = &saved
+	p.autosave.diff = DiffSrtSub(p.subtitle.youtubeSrtSub, subs)
+}
+
+func (p *player) RestoreDraft() {
+	subs, err := ParseSrtSub(p.autosave.recovered.Subtitle)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	fmt.Println("임시 저장본 복구")
+
+	p.subtitle.youtubeSrtSub = subs
+	p.subtitle.content = p.LoadSubList()
+	p.autosave.recovered = nil
+	p.autosave.diff = nil
+}
+
+func (p *player) DiscardDraft() {
+	fmt.Println("임시 저장본 삭제")
+
+	p.DelDraft()
+	p.autosave.recovered = nil
+	p.autosave.diff = nil
+}
+
+// DiffSrtSub 는 자막 단위로 LCS 를 구해 바뀐 자막만 돌려준다
+func DiffSrtSub(a, b []*srtSub) []diffLine {
+	line := func(item *srtSub) string {
+		return fmt.Sprintf("%s --> %s  %s", item.StartAt, item.EndAt, item.Text)
+	}
+
+	linesA := make([]string, len(a))
+	for i, item := range a {
+		linesA[i] = line(item)
+	}
+
+	linesB := make([]string, len(b))
+	for i, item := range b {
+		linesB[i] = line(item)
+	}
+
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if linesA[i] == linesB[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	var diff []diffLine
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && linesA[i] == linesB[j]:
+			i++
+			j++
+		case j < len(b) && (i == len(a) || lcs[i][j+1] >= lcs[i+1][j]):
+			diff = append(diff, diffLine{Op: "+", Text: linesB[j]})
+			j++
+		default:
+			diff = append(diff, diffLine{Op: "-", Text: linesA[i]})
+			i++
+		}
+	}
+
+	return diff
+}
+
+func (p *player) RenderDraft() app.UI {
+	var base string
+	if p.autosave.recovered != nil {
+		base = p.autosave.recovered.BaseVersion
+	}
+
+	return app.If(p.autosave.recovered != nil,
+		app.Div().Body(
+			app.Div().Body(
+				app.P().Body(
+					app.Text("저장하지 않은 임시 저장본이 있습니다. 복구할까요?"),
+				),
+				app.If(base != p.autosave.baseVersion,
+					app.P().Body(
+						app.Text(fmt.Sprintf("임시 저장 후 서버 자막이 바뀌었습니다 (%s -> %s)", base, p.autosave.baseVersion)),
+					).
+						Class("draft-warning"),
+				),
+				app.Div().Body(
+					app.Range(p.autosave.diff).Slice(func(i int) app.UI {
+						class := "draft-diff-add"
+						if p.autosave.diff[i].Op == "-" {
+							class = "draft-diff-del"
+						}
+
+						return app.Div().Body(
+							app.Text(p.autosave.diff[i].Op + " " + p.autosave.diff[i].Text),
+						).
+							Class(class)
+					}),
+				).
+					Class("draft-diff"),
+				app.Button().Body(
+					app.Text("복구"),
+				).
+					Class("btn btn-blue").
+					Type("button").
+					OnClick(func(ctx app.Context, e app.Event) {
+						p.RestoreDraft()
+						p.Update()
+					}),
+				app.Button().Body(
+					app.Text("삭제"),
+				).
+					Class("btn btn-gray").
+					Type("button").
+					OnClick(func(ctx app.Context, e app.Event) {
+						p.DiscardDraft()
+						p.Update()
+					}),
+			).
+				Class("draft-dialog"),
+		).
+			Class("draft-overlay"),
+	)
+}
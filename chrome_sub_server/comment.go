@@ -0,0 +1,351 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/maxence-charriere/go-app/v7/pkg/app"
+)
+
+type cueComment struct {
+	ID        string    `json:"id"`
+	Author    string    `json:"author"`
+	Text      string    `json:"text"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// commentThread 는 서버와 같은 형식, StartAt, EndAt 은 밀리초
+type commentThread struct {
+	ID         string       `json:"id"`
+	StartAt    int64        `json:"startAt"`
+	EndAt      int64        `json:"endAt"`
+	Comments   []cueComment `json:"comments"`
+	Resolved   bool         `json:"resolved"`
+	ResolvedBy string       `json:"resolvedBy"`
+}
+
+type commentJSON struct {
+	Threads []commentThread `json:"threads"`
+	Msg     string          `json:"msg"`
+	Code    int             `json:"code"`
+}
+
+type comments struct {
+	app.Compo
+
+	threads []commentThread
+	byCue   map[int][]int // 자막 번호 -> threads 의 번호
+
+	open    bool
+	cue     int
+	text    string
+	replies map[string]string
+}
+
+func (p *player) postComment(action string, extra url.Values) (*commentJSON, error) {
+	data := url.Values{}
+	data.Add("call", "comment")
+	data.Add("action", action)
+	data.Add("platform", p.platform)
+	data.Add("id", p.youtubeID)
+	data.Add("lang", "ko")
+	for key, values := range extra {
+		data[key] = values
+	}
+
+	resp, err := http.PostForm(ApiServer, data)
+	if err != nil {
+		return nil, err
+	}
+
+	body, _ := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+
+	var result commentJSON
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, err
+	}
+
+	if result.Code != 0 {
+		return &result, fmt.Errorf("%s", result.Msg)
+	}
+
+	return &result, nil
+}
+
+// LoadComments 는 이 영상의 한국어 자막 메모를 가져와서 자막 목록을 다시 그린다
+func (p *player) LoadComments() {
+	if len(p.youtubeID) == 0 {
+		return
+	}
+
+	result, err := p.postComment("list", nil)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	p.comments.threads = result.Threads
+	p.subtitle.content = p.LoadSubList()
+}
+
+// MatchComments 는 메모마다 시간 구간이 가장 많이 겹치는 자막을 찾는다
+// 겹치는 자막이 없으면 (자막을 지웠거나 시간을 많이 옮겼으면) 시작 시간이 가장 가까운 자막에 붙인다
+func (p *player) MatchComments() {
+	p.comments.byCue = make(map[int][]int)
+
+	subs := p.subtitle.youtubeSrtSub
+	if len(subs) == 0 {
+		return
+	}
+
+	for t, thread := range p.comments.threads {
+		best, bestOverlap, bestDistance := 0, int64(-1), int64(-1)
+
+		for i, sub := range subs {
+			startAt, endAt := sub.StartAt.Milliseconds(), sub.EndAt.Milliseconds()
+
+			overlap := minInt64(endAt, thread.EndAt) - maxInt64(startAt, thread.StartAt)
+			distance := startAt - thread.StartAt
+			if distance < 0 {
+				distance = -distance
+			}
+
+			if overlap > bestOverlap || (overlap == bestOverlap && distance < bestDistance) {
+				best, bestOverlap, bestDistance = i, overlap, distance
+			}
+		}
+
+		p.comments.byCue[best] = append(p.comments.byCue[best], t)
+	}
+}
+
+func minInt64(a, b int64) int64 {
+	if a < b {
+		return a
+	}
+
+	return b
+}
+
+func maxInt64(a, b int64) int64 {
+	if a > b {
+		return a
+	}
+
+	return b
+}
+
+// CommentMarker 는 자막 옆에 붙는 메모 표시, 해결되지 않은 메모가 있으면 강조한다
+func (p *player) CommentMarker(i int) app.UI {
+	threads := p.comments.byCue[i]
+
+	class := "sub-comment"
+	for _, t := range threads {
+		if !p.comments.threads[t].Resolved {
+			class += " sub-comment-open"
+			break
+		}
+	}
+
+	count := ""
+	if len(threads) != 0 {
+		count = strconv.Itoa(len(threads))
+	}
+
+	return app.A().Body(
+		app.Text(count),
+	).
+		Class(class).
+		Href("#").
+		Title("메모").
+		OnClick(func(ctx app.Context, e app.Event) {
+			p.comments.open = true
+			p.comments.cue = i
+			p.comments.text = ""
+			p.comments.replies = make(map[string]string)
+			p.Update()
+		})
+}
+
+// AddComment 는 열려 있는 자막의 지금 시간 구간에 메모를 남긴다
+func (p *player) AddComment() {
+	if p.comments.cue >= len(p.subtitle.youtubeSrtSub) {
+		return
+	}
+
+	sub := p.subtitle.youtubeSrtSub[p.comments.cue]
+
+	_, err := p.postComment("add", url.Values{
+		"start": {strconv.FormatInt(sub.StartAt.Milliseconds(), 10)},
+		"end":   {strconv.FormatInt(sub.EndAt.Milliseconds(), 10)},
+		"text":  {p.comments.text},
+	})
+	if err != nil {
+		app.Window().Call("alert", err.Error())
+		return
+	}
+
+	p.comments.text = ""
+	p.LoadComments()
+}
+
+func (p *player) ReplyComment(thread string) {
+	_, err := p.postComment("reply", url.Values{
+		"thread": {thread},
+		"text":   {p.comments.replies[thread]},
+	})
+	if err != nil {
+		app.Window().Call("alert", err.Error())
+		return
+	}
+
+	delete(p.comments.replies, thread)
+	p.LoadComments()
+}
+
+// ResolveComment 는 action 이 resolve 또는 unresolve
+func (p *player) ResolveComment(thread, action string) {
+	_, err := p.postComment(action, url.Values{"thread": {thread}})
+	if err != nil {
+		app.Window().Call("alert", err.Error())
+		return
+	}
+
+	p.LoadComments()
+}
+
+func commentAuthor(comment cueComment) string {
+	if len(comment.Author) == 0 {
+		return "익명"
+	}
+
+	return comment.Author
+}
+
+func (p *player) RenderComments() app.UI {
+	if !p.comments.open || p.comments.cue >= len(p.subtitle.youtubeSrtSub) {
+		return app.If(false)
+	}
+
+	sub := p.subtitle.youtubeSrtSub[p.comments.cue]
+	threads := p.comments.byCue[p.comments.cue]
+
+	return app.Div().Body(
+		app.Div().Body(
+			app.P().Body(
+				app.Text(fmt.Sprintf("%d번 자막 메모 (%s ~ %s)", p.comments.cue+1, sub.StartAt, sub.EndAt)),
+			),
+			app.P().Body(
+				app.Text(sub.Text),
+			).
+				Class("comment-cue"),
+			app.Div().Body(
+				app.Range(threads).Slice(func(n int) app.UI {
+					thread := p.comments.threads[threads[n]]
+
+					class := "comment-thread"
+					if thread.Resolved {
+						class += " comment-resolved"
+					}
+
+					return app.Div().Body(
+						app.Range(thread.Comments).Slice(func(c int) app.UI {
+							comment := thread.Comments[c]
+
+							return app.P().Body(
+								app.Span().Body(
+									app.Text(fmt.Sprintf("%s · %s", commentAuthor(comment), comment.CreatedAt.Local().Format("01-02 15:04"))),
+								).
+									Class("comment-author"),
+								app.Text(comment.Text),
+							).
+								Class("comment-item")
+						}),
+						app.If(thread.Resolved,
+							app.P().Body(
+								app.Text(fmt.Sprintf("%s 님이 해결함", thread.ResolvedBy)),
+							).
+								Class("comment-author"),
+						),
+						app.If(p.CanEdit(),
+							app.Textarea().
+								Class("review-input").
+								Placeholder("답글").
+								Text(p.comments.replies[thread.ID]).
+								OnInput(func(ctx app.Context, e app.Event) {
+									p.comments.replies[thread.ID] = ctx.JSSrc.JSValue().Get("value").String()
+								}),
+							app.Button().Body(
+								app.Text("답글"),
+							).
+								Class("btn btn-gray").
+								Type("button").
+								OnClick(func(ctx app.Context, e app.Event) {
+									p.ReplyComment(thread.ID)
+									p.Update()
+								}),
+						),
+						app.If(p.CanReview() && !thread.Resolved,
+							app.Button().Body(
+								app.Text("해결"),
+							).
+								Class("btn btn-gray").
+								Type("button").
+								OnClick(func(ctx app.Context, e app.Event) {
+									p.ResolveComment(thread.ID, "resolve")
+									p.Update()
+								}),
+						).ElseIf(p.CanReview(),
+							app.Button().Body(
+								app.Text("다시 열기"),
+							).
+								Class("btn btn-gray").
+								Type("button").
+								OnClick(func(ctx app.Context, e app.Event) {
+									p.ResolveComment(thread.ID, "unresolve")
+									p.Update()
+								}),
+						),
+					).
+						Class(class)
+				}),
+			).
+				Class("comment-threads"),
+			app.If(p.CanEdit(),
+				app.Textarea().
+					Class("review-input").
+					Placeholder("새 메모 (예: 타이밍이 늦음, 존댓말 확인)").
+					Text(p.comments.text).
+					OnInput(func(ctx app.Context, e app.Event) {
+						p.comments.text = ctx.JSSrc.JSValue().Get("value").String()
+					}),
+				app.Button().Body(
+					app.Text("메모 남기기"),
+				).
+					Class("btn btn-blue").
+					Type("button").
+					OnClick(func(ctx app.Context, e app.Event) {
+						p.AddComment()
+						p.Update()
+					}),
+			),
+			app.Button().Body(
+				app.Text("닫기"),
+			).
+				Class("btn btn-gray").
+				Type("button").
+				OnClick(func(ctx app.Context, e app.Event) {
+					p.comments.open = false
+					p.Update()
+				}),
+		).
+			Class("draft-dialog"),
+	).
+		Class("draft-overlay")
+}
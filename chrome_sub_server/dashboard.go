@@ -0,0 +1,293 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/maxence-charriere/go-app/v7/pkg/app"
+)
+
+const dashboardPageSize = 50
+
+type videoMetadata struct {
+	Title     string        `json:"title"`
+	Duration  time.Duration `json:"duration"`
+	Channel   string        `json:"channel"`
+	Thumbnail string        `json:"thumbnail"`
+	FirstSeen time.Time     `json:"firstSeen"`
+}
+
+type completion struct {
+	Duration   time.Duration `json:"duration"`
+	Cued       time.Duration `json:"cued"`
+	Coverage   float64       `json:"coverage"`
+	Cues       int           `json:"cues"`
+	EmptyCues  int           `json:"emptyCues"`
+	LargestGap time.Duration `json:"largestGap"`
+	GapStartAt time.Duration `json:"gapStartAt"`
+	Translated float64       `json:"translated"`
+}
+
+type videoCompletion struct {
+	SourceLang string                 `json:"sourceLang"`
+	Languages  map[string]*completion `json:"languages"`
+}
+
+type videoInfo struct {
+	Platform   string           `json:"platform"`
+	ID         string           `json:"id"`
+	Metadata   *videoMetadata   `json:"metadata"`
+	Languages  []string         `json:"languages"`
+	EditedAt   time.Time        `json:"editedAt"`
+	Revisions  int              `json:"revisions"`
+	LastEditor string           `json:"lastEditor"`
+	Completion *videoCompletion `json:"completion"`
+}
+
+type videosJSON struct {
+	Videos []videoInfo `json:"videos"`
+	Total  int         `json:"total"`
+	Code   int         `json:"code"`
+}
+
+// dashboard 는 자막이 있는 영상 목록 (/dashboard)
+type dashboard struct {
+	app.Compo
+
+	videos []videoInfo
+	total  int
+
+	query  string
+	sortBy string
+	err    string
+}
+
+var dashboardSorts = []struct {
+	value string
+	label string
+}{
+	{"edited", "최근 수정순"},
+	{"title", "제목순"},
+	{"revisions", "저장 횟수순"},
+	{"languages", "언어 수순"},
+}
+
+func (v videoInfo) Title() string {
+	if v.Metadata != nil && len(v.Metadata.Title) != 0 {
+		return v.Metadata.Title
+	}
+
+	return v.ID
+}
+
+// Label 은 "커버율 (번역률)" 형태, 원문 언어는 번역률을 빼고 보여준다
+func (c *completion) Label() string {
+	label := fmt.Sprintf("%.0f%%", c.Coverage*100)
+	if c.Translated >= 0 {
+		label += fmt.Sprintf(" (번역 %.0f%%)", c.Translated*100)
+	}
+
+	return label
+}
+
+func (c *completion) Detail() string {
+	return fmt.Sprintf("자막 %d개, 빈 자막 %d개\n가장 긴 빈 구간: %s부터 %s",
+		c.Cues,
+		c.EmptyCues,
+		c.GapStartAt.Round(time.Second),
+		c.LargestGap.Round(time.Second),
+	)
+}
+
+func (v videoInfo) EditorURL() string {
+	data := url.Values{}
+	data.Add("platform", v.Platform)
+	data.Add("id", v.ID)
+
+	return "/?" + data.Encode()
+}
+
+func LoadVideos(query, sortBy string, offset int) (*videosJSON, error) {
+	data := url.Values{}
+	data.Add("call", "videos")
+	data.Add("q", query)
+	data.Add("sort", sortBy)
+	data.Add("offset", strconv.Itoa(offset))
+	data.Add("limit", strconv.Itoa(dashboardPageSize))
+
+	body, statusCode, err := CachedPostForm(data)
+	if err != nil {
+		return nil, err
+	}
+
+	var resultJSON videosJSON
+	if err := json.Unmarshal(body, &resultJSON); err != nil {
+		return nil, err
+	}
+
+	if statusCode != 200 {
+		return nil, fmt.Errorf("%d", resultJSON.Code)
+	}
+
+	return &resultJSON, nil
+}
+
+func (d *dashboard) OnNav(_ app.Context, u *url.URL) {
+	d.query = u.Query().Get("q")
+	d.sortBy = u.Query().Get("sort")
+
+	d.Reload()
+}
+
+// Reload 는 검색어나 정렬이 바뀌면 처음부터 다시 불러온다
+func (d *dashboard) Reload() {
+	d.videos = nil
+	d.total = 0
+	d.LoadMore()
+}
+
+func (d *dashboard) LoadMore() {
+	fmt.Println("영상 목록 불러오는 중...")
+
+	result, err := LoadVideos(d.query, d.sortBy, len(d.videos))
+	if err != nil {
+		fmt.Println(err)
+		d.err = "목록을 불러오지 못했습니다"
+		d.Update()
+
+		return
+	}
+
+	d.err = ""
+	d.videos = append(d.videos, result.Videos...)
+	d.total = result.Total
+	d.Update()
+}
+
+func (d *dashboard) Render() app.UI {
+	return app.Div().Body(
+		app.Div().Body(
+			app.Input().
+				Class("find-input").
+				Placeholder("제목, 채널, ID 검색").
+				Value(d.query).
+				OnKeyDown(func(ctx app.Context, e app.Event) {
+					if e.Get("key").String() != "Enter" {
+						return
+					}
+
+					d.query = ctx.JSSrc.JSValue().Get("value").String()
+					d.Reload()
+				}),
+			app.Select().Body(
+				app.Range(dashboardSorts).Slice(func(i int) app.UI {
+					return app.Option().
+						Value(dashboardSorts[i].value).
+						Selected(dashboardSorts[i].value == d.sortBy).
+						Text(dashboardSorts[i].label)
+				}),
+			).
+				Class("play-quality").
+				OnChange(func(ctx app.Context, e app.Event) {
+					d.sortBy = ctx.JSSrc.JSValue().Get("value").String()
+					d.Reload()
+				}),
+			app.Span().Body(
+				app.Text(fmt.Sprintf("%d개", d.total)),
+			).
+				Class("find-count"),
+		).
+			Class("find-bar"),
+		app.If(len(d.err) != 0,
+			app.Div().Body(
+				app.Text(d.err),
+			).
+				Class("dashboard-error"),
+		),
+		app.Table().Body(
+			app.THead().Body(
+				app.Tr().Body(
+					app.Th().Text(""),
+					app.Th().Text("영상"),
+					app.Th().Text("언어"),
+					app.Th().Text("진행률"),
+					app.Th().Text("저장"),
+					app.Th().Text("마지막 수정"),
+				),
+			),
+			app.TBody().Body(
+				app.Range(d.videos).Slice(func(i int) app.UI {
+					return d.renderVideo(d.videos[i])
+				}),
+			),
+		).
+			Class("dashboard-table"),
+		app.If(len(d.videos) < d.total,
+			app.Button().Body(
+				app.Text("더 보기"),
+			).
+				Class("btn btn-gray").
+				Type("button").
+				OnClick(func(ctx app.Context, e app.Event) {
+					d.LoadMore()
+				}),
+		),
+	).
+		Class("dashboard")
+}
+
+func (d *dashboard) renderVideo(v videoInfo) app.UI {
+	var thumbnail, channel string
+	if v.Metadata != nil {
+		thumbnail = v.Metadata.Thumbnail
+		channel = v.Metadata.Channel
+	}
+
+	return app.Tr().Body(
+		app.Td().Body(
+			app.If(len(thumbnail) != 0,
+				app.Img().
+					Src(thumbnail).
+					Class("dashboard-thumbnail"),
+			),
+		),
+		app.Td().Body(
+			app.A().
+				Href(v.EditorURL()).
+				Text(v.Title()),
+			app.Div().Body(
+				app.Text(strings.TrimSpace(v.Platform+" · "+channel)),
+			).
+				Class("dashboard-sub"),
+		),
+		app.Td().Text(strings.Join(v.Languages, ", ")),
+		app.Td().Body(
+			app.Range(v.Languages).Slice(func(i int) app.UI {
+				var c *completion
+				if v.Completion != nil {
+					c = v.Completion.Languages[v.Languages[i]]
+				}
+
+				if c == nil {
+					return app.Div().Text(v.Languages[i] + " -")
+				}
+
+				return app.Div().
+					Title(c.Detail()).
+					Text(v.Languages[i] + " " + c.Label())
+			}),
+		),
+		app.Td().Text(strconv.Itoa(v.Revisions)),
+		app.Td().Body(
+			app.Text(v.EditedAt.Local().Format("2006-01-02 15:04")),
+			app.Div().Body(
+				app.Text(v.LastEditor),
+			).
+				Class("dashboard-sub"),
+		),
+	)
+}
@@ -0,0 +1,340 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/maxence-charriere/go-app/v7/pkg/app"
+)
+
+const maxUndo = 50
+
+type finder struct {
+	app.Compo
+
+	query           string
+	replacement     string
+	caseInsensitive bool
+	regex           bool
+
+	matches []int
+	current int
+	err     string
+
+	undo []undoState
+}
+
+// undoState 는 바꾸기 전 자막과 바꾼 직후 자막 (SRT), 그 뒤에 다른 편집이 있었는지 after 로 확인한다
+type undoState struct {
+	before []*srtSub
+	after  string
+}
+
+// FindPattern 은 일반 검색어도 정규식으로 바꿔서 같은 방식으로 찾고 바꾼다
+func (p *player) FindPattern() (*regexp.Regexp, error) {
+	if len(p.finder.query) == 0 {
+		return nil, nil
+	}
+
+	pattern := p.finder.query
+	if !p.finder.regex {
+		pattern = regexp.QuoteMeta(pattern)
+	}
+
+	if p.finder.caseInsensitive {
+		pattern = "(?i)" + pattern
+	}
+
+	return regexp.Compile(pattern)
+}
+
+// Find 는 결과를 다시 찾는다. 선택된 결과 순번은 가능하면 유지한다
+func (p *player) Find() {
+	p.finder.matches = nil
+	p.finder.err = ""
+
+	regx, err := p.FindPattern()
+	if err != nil || regx == nil {
+		if err != nil {
+			p.finder.err = err.Error()
+		}
+
+		p.finder.current = -1
+		return
+	}
+
+	for i, item := range p.subtitle.youtubeSrtSub {
+		if regx.MatchString(item.Text) {
+			p.finder.matches = append(p.finder.matches, i)
+		}
+	}
+
+	if p.finder.current >= len(p.finder.matches) {
+		p.finder.current = len(p.finder.matches) - 1
+	}
+}
+
+// FindMove 는 다음(1)/이전(-1) 결과로 이동하고 영상을 그 자막으로 옮긴다
+func (p *player) FindMove(step int) {
+	if len(p.finder.matches) == 0 {
+		return
+	}
+
+	p.finder.current = (p.finder.current + step + len(p.finder.matches)) % len(p.finder.matches)
+	p.subtitle.content = p.LoadSubList()
+	p.SeekSub(p.finder.matches[p.finder.current])
+}
+
+func (p *player) SeekSub(i int) {
+	item := p.subtitle.youtubeSrtSub[i]
+
+	fmt.Printf("[%s~%s] 자막 이동: %s\n", item.StartAt.String(), item.EndAt.String(), item.Text)
+
+	p.subtitle.youtubeSubtitle = item.Text
+	p.youtubeStart = item.StartAt.Seconds()
+
+	if p.video != nil {
+		p.video.Set("currentTime", p.youtubeStart)
+	} else {
+		subTextClicked = true
+	}
+
+	app.Window().ScrollToID(fmt.Sprintf("sub-%d", i))
+}
+
+// PushUndo 는 지금 자막을 복사해서 되돌리기 목록에 넣는다, 바꾼 뒤의 자막은 TrackUndo 에서 적는다
+func (p *player) PushUndo() {
+	snapshot := make([]*srtSub, len(p.subtitle.youtubeSrtSub))
+	for i, item := range p.subtitle.youtubeSrtSub {
+		copied := *item
+		snapshot[i] = &copied
+	}
+
+	p.finder.undo = append(p.finder.undo, undoState{before: snapshot})
+	if len(p.finder.undo) > maxUndo {
+		p.finder.undo = p.finder.undo[1:]
+	}
+}
+
+// TrackUndo 는 편집할 때마다 (ScheduleDraft) 불린다
+// 방금 넣은 되돌리기면 바꾼 뒤의 자막을 적고, 되돌리기로 돌아갈 수 없는 편집이 있었으면 목록을 비운다
+func (p *player) TrackUndo() {
+	if len(p.finder.undo) == 0 {
+		return
+	}
+
+	top := &p.finder.undo[len(p.finder.undo)-1]
+	current := FormatSrtSub(p.subtitle.youtubeSrtSub)
+
+	switch {
+	case len(top.after) == 0:
+		top.after = current
+	case top.after != current:
+		fmt.Println("다른 편집이 있어서 되돌리기 목록을 비움")
+		p.finder.undo = nil
+	}
+}
+
+// Undo 는 마지막 바꾸기 뒤에 다른 편집이 없을 때만 되돌린다 (그 뒤의 편집을 지우지 않는다)
+func (p *player) Undo() {
+	if len(p.finder.undo) == 0 {
+		return
+	}
+
+	top := p.finder.undo[len(p.finder.undo)-1]
+	if len(top.after) != 0 && top.after != FormatSrtSub(p.subtitle.youtubeSrtSub) {
+		fmt.Println("다른 편집이 있어서 되돌릴 수 없음")
+		p.finder.undo = nil
+		return
+	}
+
+	fmt.Println("되돌리기")
+
+	p.subtitle.youtubeSrtSub = top.before
+	p.finder.undo = p.finder.undo[:len(p.finder.undo)-1]
+	p.subtitle.content = p.LoadSubList()
+	p.ScheduleDraft()
+	p.Find()
+}
+
+func (p *player) replaceText(regx *regexp.Regexp, text string) string {
+	if p.finder.regex {
+		return regx.ReplaceAllString(text, p.finder.replacement)
+	}
+
+	return regx.ReplaceAllLiteralString(text, p.finder.replacement)
+}
+
+// Replace 는 지금 선택된 결과 하나만 바꾸고 다음 결과로 넘어간다
+func (p *player) Replace() {
+	regx, err := p.FindPattern()
+	if err != nil || regx == nil || p.finder.current < 0 || p.finder.current >= len(p.finder.matches) {
+		return
+	}
+
+	i := p.finder.matches[p.finder.current]
+
+	p.PushUndo()
+	p.subtitle.youtubeSrtSub[i].Text = p.replaceText(regx, p.subtitle.youtubeSrtSub[i].Text)
+	p.subtitle.content = p.LoadSubList()
+	p.ScheduleDraft()
+
+	current := p.finder.current
+	p.Find()
+
+	if len(p.finder.matches) != 0 {
+		p.finder.current = current - 1
+		p.FindMove(1)
+	}
+}
+
+// ReplaceAll 은 모든 결과를 한 번에 바꾸고, 되돌리기 한 번으로 전부 돌아온다
+func (p *player) ReplaceAll() {
+	regx, err := p.FindPattern()
+	if err != nil || regx == nil || len(p.finder.matches) == 0 {
+		return
+	}
+
+	fmt.Printf("모두 바꾸기: %d개\n", len(p.finder.matches))
+
+	p.PushUndo()
+	for _, i := range p.finder.matches {
+		p.subtitle.youtubeSrtSub[i].Text = p.replaceText(regx, p.subtitle.youtubeSrtSub[i].Text)
+	}
+
+	p.subtitle.content = p.LoadSubList()
+	p.ScheduleDraft()
+	p.Find()
+}
+
+func (p *player) IsFindCurrent(i int) bool {
+	return p.finder.current >= 0 && p.finder.current < len(p.finder.matches) && p.finder.matches[p.finder.current] == i
+}
+
+func (p *player) RenderFinder() app.UI {
+	var count string
+
+	switch {
+	case len(p.finder.err) != 0:
+		count = "정규식 오류"
+	case len(p.finder.query) == 0:
+		count = ""
+	case len(p.finder.matches) == 0:
+		count = "0/0"
+	default:
+		count = fmt.Sprintf("%d/%d", p.finder.current+1, len(p.finder.matches))
+	}
+
+	toggleClass := func(on bool) string {
+		if on {
+			return "find-toggle find-toggle-on"
+		}
+
+		return "find-toggle"
+	}
+
+	return app.Div().Body(
+		app.Div().Body(
+			app.Input().
+				Class("find-input").
+				Placeholder("찾기").
+				Value(p.finder.query).
+				OnInput(func(ctx app.Context, e app.Event) {
+					p.finder.query = ctx.JSSrc.JSValue().Get("value").String()
+					p.finder.current = -1
+					p.Find()
+					p.Update()
+				}).
+				OnKeyDown(func(ctx app.Context, e app.Event) {
+					if e.Get("key").String() != "Enter" {
+						return
+					}
+
+					if e.Get("shiftKey").Bool() {
+						p.FindMove(-1)
+					} else {
+						p.FindMove(1)
+					}
+					p.Update()
+				}),
+			app.Span().Body(
+				app.Text("Aa"),
+			).
+				Class(toggleClass(p.finder.caseInsensitive)).
+				Title("대소문자 무시").
+				OnClick(func(ctx app.Context, e app.Event) {
+					p.finder.caseInsensitive = !p.finder.caseInsensitive
+					p.finder.current = -1
+					p.Find()
+					p.Update()
+				}),
+			app.Span().Body(
+				app.Text(".*"),
+			).
+				Class(toggleClass(p.finder.regex)).
+				Title("정규식").
+				OnClick(func(ctx app.Context, e app.Event) {
+					p.finder.regex = !p.finder.regex
+					p.finder.current = -1
+					p.Find()
+					p.Update()
+				}),
+			app.Span().Body(
+				app.Text(count),
+			).
+				Class("find-count").
+				Title(p.finder.err),
+			app.Span().
+				Class("find-prev").
+				Title("이전").
+				OnClick(func(ctx app.Context, e app.Event) {
+					p.FindMove(-1)
+					p.Update()
+				}),
+			app.Span().
+				Class("find-next").
+				Title("다음").
+				OnClick(func(ctx app.Context, e app.Event) {
+					p.FindMove(1)
+					p.Update()
+				}),
+		).
+			Class("find-row"),
+		app.Div().Body(
+			app.Input().
+				Class("find-input").
+				Placeholder("바꾸기 ($1 로 그룹 사용)").
+				Value(p.finder.replacement).
+				OnInput(func(ctx app.Context, e app.Event) {
+					p.finder.replacement = ctx.JSSrc.JSValue().Get("value").String()
+				}),
+			app.Span().Body(
+				app.Text("바꾸기"),
+			).
+				Class("find-button").
+				OnClick(func(ctx app.Context, e app.Event) {
+					p.Replace()
+					p.Update()
+				}),
+			app.Span().Body(
+				app.Text("모두"),
+			).
+				Class("find-button").
+				Title("모두 바꾸기").
+				OnClick(func(ctx app.Context, e app.Event) {
+					p.ReplaceAll()
+					p.Update()
+				}),
+			app.Span().Body(
+				app.Text("되돌리기"),
+			).
+				Class("find-button").
+				OnClick(func(ctx app.Context, e app.Event) {
+					p.Undo()
+					p.Update()
+				}),
+		).
+			Class("find-row"),
+	).
+		Class("find-bar")
+}
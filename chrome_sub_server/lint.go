@@ -0,0 +1,129 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"reflect"
+	"regexp"
+	"strings"
+
+	"github.com/maxence-charriere/go-app/v7/pkg/app"
+)
+
+type lintIssue struct {
+	Index   int    `json:"index"`
+	Rule    string `json:"rule"`
+	Term    string `json:"term"`
+	Target  string `json:"target"`
+	Found   string `json:"found"`
+	Message string `json:"message"`
+}
+
+type lint struct {
+	app.Compo
+
+	project string
+	issues  map[int][]lintIssue
+}
+
+// Lint 는 프로젝트 용어집으로 편집 중인 자막을 검사한다, 결과가 바뀌었을 때만 다시 그린다
+func (p *player) Lint() {
+	if len(p.youtubeID) == 0 {
+		return
+	}
+
+	data := url.Values{}
+	data.Add("call", "lint")
+	data.Add("platform", p.platform)
+	data.Add("id", p.youtubeID)
+	data.Add("lang", TargetLang)
+	data.Add("source", SourceLang)
+	data.Add("subtitle", FormatSrtSub(p.subtitle.youtubeSrtSub))
+
+	resp, err := http.PostForm(ApiServer, data)
+	if err != nil || resp.StatusCode != 200 {
+		fmt.Println(err)
+		return
+	}
+
+	body, _ := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+
+	var resultJSON struct {
+		Project string      `json:"project"`
+		Issues  []lintIssue `json:"issues"`
+	}
+	if err := json.Unmarshal(body, &resultJSON); err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	issues := make(map[int][]lintIssue)
+	for _, issue := range resultJSON.Issues {
+		issues[issue.Index] = append(issues[issue.Index], issue)
+	}
+
+	p.lint.project = resultJSON.Project
+	if reflect.DeepEqual(issues, p.lint.issues) {
+		return
+	}
+
+	if len(resultJSON.Issues) != 0 {
+		fmt.Printf("용어 검사: %d개\n", len(resultJSON.Issues))
+	}
+
+	p.lint.issues = issues
+	p.subtitle.content = p.LoadSubList()
+	p.Update()
+}
+
+// LintMessage 는 자막에 마우스를 올렸을 때 보이는 설명
+func (p *player) LintMessage(i int) string {
+	var messages []string
+	for _, issue := range p.lint.issues[i] {
+		messages = append(messages, issue.Message)
+	}
+
+	return strings.Join(messages, "\n")
+}
+
+// LintClass 는 원문 용어를 번역하지 않은 자막에 테두리를 표시한다
+func (p *player) LintClass(i int) string {
+	for _, issue := range p.lint.issues[i] {
+		if issue.Rule == "missing" {
+			return "lint-wrap lint-missing"
+		}
+	}
+
+	return "lint-wrap"
+}
+
+// RenderHighlight 는 textarea 뒤에 같은 글자를 투명하게 깔고, 쓰면 안 되는 표현에만 배경을 칠한다
+func (p *player) RenderHighlight(i int) app.UI {
+	var variants []string
+	for _, issue := range p.lint.issues[i] {
+		if issue.Rule == "forbidden" {
+			variants = append(variants, regexp.QuoteMeta(issue.Found))
+		}
+	}
+
+	if len(variants) == 0 {
+		return app.Div().Class("lint-highlight")
+	}
+
+	text := p.subtitle.youtubeSrtSub[i].Text
+	regx := regexp.MustCompile("(?i)" + strings.Join(variants, "|"))
+
+	var parts []app.UI
+	var last int
+	for _, loc := range regx.FindAllStringIndex(text, -1) {
+		parts = append(parts, app.Text(text[last:loc[0]]), app.Mark().Text(text[loc[0]:loc[1]]))
+		last = loc[1]
+	}
+	parts = append(parts, app.Text(text[last:]))
+
+	return app.Div().Body(parts...).Class("lint-highlight")
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+
+	"github.com/maxence-charriere/go-app/v7/pkg/app"
+)
+
+const (
+	// 번역 메모리에서 원문으로 쓰는 언어와 편집 중인 언어
+	SourceLang = "en"
+	TargetLang = "ko"
+
+	memoryLimit = 3
+)
+
+type memoryMatch struct {
+	Platform string  `json:"platform"`
+	ID       string  `json:"id"`
+	Source   string  `json:"source"`
+	Target   string  `json:"target"`
+	Score    float64 `json:"score"`
+}
+
+type memory struct {
+	app.Compo
+
+	source []*srtSub
+
+	active      int
+	suggestions []memoryMatch
+}
+
+// LoadMemorySource 는 지금 영상의 원문 자막을 불러온다, 없으면 추천을 보여주지 않는다
+func (p *player) LoadMemorySource() {
+	p.memory.source = nil
+	p.memory.active = -1
+	p.memory.suggestions = nil
+
+	body, _, statusCode := IsSubExist(p.platform, p.youtubeID, SourceLang)
+	if statusCode != 200 {
+		return
+	}
+
+	subs, err := ParseSrtSub(body)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	p.memory.source = subs
+}
+
+// MemorySourceText 는 i 번 자막과 가장 많이 겹치는 원문 자막
+func (p *player) MemorySourceText(i int) string {
+	item := p.subtitle.youtubeSrtSub[i]
+
+	var text string
+	var best float64
+	for _, s := range p.memory.source {
+		start, end := s.StartAt, s.EndAt
+		if item.StartAt > start {
+			start = item.StartAt
+		}
+		if item.EndAt < end {
+			end = item.EndAt
+		}
+
+		if overlap := (end - start).Seconds(); overlap > best {
+			best = overlap
+			text = s.Text
+		}
+	}
+
+	return text
+}
+
+// Suggest 는 i 번 자막이 선택되면 원문과 비슷한 이전 번역을 가져온다
+func (p *player) Suggest(i int) {
+	if i == p.memory.active {
+		return
+	}
+
+	p.memory.active = i
+	p.memory.suggestions = nil
+
+	// 자막 목록은 만들 때 그려지므로 추천이 바뀌면 다시 만든다
+	defer func() {
+		p.subtitle.content = p.LoadSubList()
+	}()
+
+	source := p.MemorySourceText(i)
+	if len(source) == 0 {
+		return
+	}
+
+	data := url.Values{}
+	data.Add("call", "memory")
+	data.Add("source", source)
+	data.Add("sourceLang", SourceLang)
+	data.Add("targetLang", TargetLang)
+	data.Add("limit", fmt.Sprintf("%d", memoryLimit))
+
+	resp, err := http.PostForm(ApiServer, data)
+	if err != nil || resp.StatusCode != 200 {
+		fmt.Println(err)
+		return
+	}
+
+	body, _ := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+
+	var resultJSON struct {
+		Matches []memoryMatch `json:"matches"`
+	}
+	if err := json.Unmarshal(body, &resultJSON); err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	p.memory.suggestions = resultJSON.Matches
+}
+
+// UseSuggestion 은 추천 번역으로 자막을 바꾼다, 되돌리기 가능
+func (p *player) UseSuggestion(i int, match memoryMatch) {
+	fmt.Printf("%d번 자막에 추천 번역 사용: %s\n", i+1, match.Target)
+
+	p.PushUndo()
+	p.subtitle.youtubeSrtSub[i].Text = match.Target
+	p.subtitle.content = p.LoadSubList()
+	p.ScheduleDraft()
+	p.Find()
+}
+
+func (p *player) RenderSuggestions(i int) app.UI {
+	return app.If(i == p.memory.active && len(p.memory.suggestions) != 0,
+		app.Div().Body(
+			app.Range(p.memory.suggestions).Slice(func(j int) app.UI {
+				match := p.memory.suggestions[j]
+
+				return app.Div().Body(
+					app.Span().Body(
+						app.Text(fmt.Sprintf("%.0f%%", match.Score*100)),
+					).
+						Class("tm-score"),
+					app.Text(match.Target),
+				).
+					Class("tm-suggestion").
+					Title(match.Source).
+					OnClick(func(ctx app.Context, e app.Event) {
+						p.UseSuggestion(i, match)
+						p.Update()
+					})
+			}),
+		).
+			Class("tm-suggestions"),
+	)
+}
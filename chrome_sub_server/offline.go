@@ -0,0 +1,342 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/maxence-charriere/go-app/v7/pkg/app"
+)
+
+const (
+	saveQueueKey = "save-queue"
+
+	// 서버의 save 충돌 에러 코드
+	saveConflictCode = 306
+
+	// apiCacheIndexKey 에 저장해둔 응답의 키와 크기를 오래된 순서로 적어둔다
+	apiCacheIndexKey = "api-cache"
+	// 저장해둘 응답 전체 크기와 응답 하나의 크기 (localStorage 는 보통 5MB 까지 쓸 수 있다)
+	apiCacheMaxBytes      = 2 << 20
+	apiCacheMaxEntryBytes = 512 << 10
+)
+
+// 오프라인에서 편집하는 데 필요한 자막, 영상 정보만 저장해둔다 (계정, 검토 같은 응답은 저장하지 않는다)
+var apiCacheCalls = map[string]bool{
+	"subtitle": true,
+	"video":    true,
+}
+
+type apiCacheEntry struct {
+	Key  string `json:"key"`
+	Size int    `json:"size"`
+}
+
+type offline struct {
+	app.Compo
+
+	offline bool
+	queued  int
+
+	unsubscribes []func()
+}
+
+// queuedSave 는 오프라인일 때 쌓아두었다가 다시 연결되면 보내는 저장
+type queuedSave struct {
+	Platform string    `json:"platform"`
+	ID       string    `json:"id"`
+	Lang     string    `json:"lang"`
+	Base     string    `json:"base"`
+	Subtitle string    `json:"subtitle"`
+	QueuedAt time.Time `json:"queuedAt"`
+}
+
+func (s queuedSave) sameSubtitle(other queuedSave) bool {
+	return s.Platform == other.Platform && s.ID == other.ID && s.Lang == other.Lang
+}
+
+func APICacheKey(data url.Values) string {
+	return "api:" + data.Encode()
+}
+
+// CachedPostForm 은 성공한 응답을 저장해두고, 서버에 연결할 수 없으면 저장된 응답을 돌려준다
+func CachedPostForm(data url.Values) ([]byte, int, error) {
+	resp, err := http.PostForm(ApiServer, data)
+	if err != nil {
+		var cached string
+		if app.LocalStorage.Get(APICacheKey(data), &cached) != nil || len(cached) == 0 {
+			return nil, 0, err
+		}
+
+		fmt.Println("오프라인: 저장된 응답 사용 (" + data.Get("call") + ")")
+		return []byte(cached), 200, nil
+	}
+
+	body, _ := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+
+	if resp.StatusCode == 200 && apiCacheCalls[data.Get("call")] {
+		StoreAPICache(APICacheKey(data), body)
+	}
+
+	return body, resp.StatusCode, nil
+}
+
+func loadAPICacheIndex() []apiCacheEntry {
+	var index []apiCacheEntry
+	if err := app.LocalStorage.Get(apiCacheIndexKey, &index); err != nil {
+		return nil
+	}
+
+	return index
+}
+
+// StoreAPICache 는 응답을 저장하고, 전체 크기가 apiCacheMaxBytes 를 넘으면 오래된 응답부터 지운다
+func StoreAPICache(key string, body []byte) {
+	index := loadAPICacheIndex()
+
+	// 같은 키는 지우고 맨 뒤에 다시 넣는다
+	kept := index[:0]
+	for _, entry := range index {
+		if entry.Key != key {
+			kept = append(kept, entry)
+		}
+	}
+	index = kept
+
+	if len(body) > apiCacheMaxEntryBytes {
+		app.LocalStorage.Del(key)
+	} else {
+		index = append(index, apiCacheEntry{Key: key, Size: len(body)})
+	}
+
+	total := 0
+	for _, entry := range index {
+		total += entry.Size
+	}
+
+	for len(index) != 0 && total > apiCacheMaxBytes {
+		app.LocalStorage.Del(index[0].Key)
+		total -= index[0].Size
+		index = index[1:]
+	}
+
+	if len(body) <= apiCacheMaxEntryBytes {
+		if err := app.LocalStorage.Set(key, string(body)); err != nil {
+			fmt.Println(err)
+			index = index[:len(index)-1]
+		}
+	}
+
+	if err := app.LocalStorage.Set(apiCacheIndexKey, index); err != nil {
+		fmt.Println(err)
+	}
+}
+
+func LoadSaveQueue() []queuedSave {
+	var queue []queuedSave
+	if err := app.LocalStorage.Get(saveQueueKey, &queue); err != nil {
+		return nil
+	}
+
+	return queue
+}
+
+func StoreSaveQueue(queue []queuedSave) {
+	if len(queue) == 0 {
+		app.LocalStorage.Del(saveQueueKey)
+		return
+	}
+
+	if err := app.LocalStorage.Set(saveQueueKey, queue); err != nil {
+		fmt.Println(err)
+	}
+}
+
+// QueueSave 는 같은 자막 (platform/id/lang) 의 저장을 하나만 남긴다. 새 자막이 이전 것을 포함하므로 내용은 새 것으로,
+// 기준 버전은 처음 쌓은 것으로 둬서 다시 연결됐을 때 내 저장끼리 충돌하지 않게 한다
+func (p *player) QueueSave(save queuedSave) {
+	var queue []queuedSave
+	for _, queued := range LoadSaveQueue() {
+		if queued.sameSubtitle(save) {
+			save.Base = queued.Base
+			continue
+		}
+
+		queue = append(queue, queued)
+	}
+
+	queue = append(queue, save)
+	StoreSaveQueue(queue)
+
+	p.offline.queued = len(queue)
+	fmt.Printf("저장 대기열에 추가 (%d개)\n", p.offline.queued)
+}
+
+// ReplaySaves 는 쌓인 저장을 순서대로 보낸다. 그 사이 서버 자막이 바뀌었으면 임시 저장본으로 돌려서 비교 후 복구할 수 있게 한다
+func (p *player) ReplaySaves() {
+	queue := LoadSaveQueue()
+
+	for len(queue) != 0 {
+		save := queue[0]
+
+		if p.offline.offline || p.NeedLogin() {
+			break
+		}
+
+		data := url.Values{}
+		data.Add("call", "save")
+		data.Add("platform", save.Platform)
+		data.Add("id", save.ID)
+		data.Add("lang", save.Lang)
+		data.Add("base", save.Base)
+		data.Add("subtitle", save.Subtitle)
+
+		resp, err := http.PostForm(ApiServer, data)
+		if err != nil {
+			fmt.Println("저장 대기열 전송 실패")
+			fmt.Println(err)
+			break
+		}
+
+		body, _ := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+
+		var resultJSON ResultJSON
+		_ = json.Unmarshal(body, &resultJSON)
+
+		switch {
+		case resp.StatusCode == 200 && resultJSON.Code == 0:
+			if len(resultJSON.Quarantined) != 0 {
+				fmt.Printf("대기 중이던 저장 격리 (%s, %s)\n", save.ID, resultJSON.Quarantined)
+			} else if len(resultJSON.Pending) != 0 {
+				fmt.Printf("대기 중이던 저장 검토 요청 (%s, %s)\n", save.ID, resultJSON.Pending)
+			} else {
+				fmt.Printf("대기 중이던 저장 완료 (%s, %s)\n", save.ID, resultJSON.Version)
+			}
+
+			if save.Platform == p.platform && save.ID == p.youtubeID && save.Lang == "ko" {
+				p.autosave.baseVersion = resultJSON.Version
+			}
+
+			// 예전에 쌓인 같은 자막의 저장이 남아 있으면 방금 올린 버전을 기준으로 보낸다
+			if len(resultJSON.Version) != 0 {
+				for i := range queue[1:] {
+					if queue[1+i].sameSubtitle(save) {
+						queue[1+i].Base = resultJSON.Version
+					}
+				}
+			}
+		case resultJSON.Code == saveConflictCode:
+			fmt.Printf("저장 충돌 (%s): %s\n", save.ID, resultJSON.Msg)
+
+			if err := app.LocalStorage.Set(DraftKey(save.Platform, save.ID, save.Lang), draft{
+				Platform:    save.Platform,
+				ID:          save.ID,
+				Lang:        save.Lang,
+				BaseVersion: save.Base,
+				Subtitle:    save.Subtitle,
+				SavedAt:     save.QueuedAt,
+			}); err != nil {
+				fmt.Println(err)
+			}
+
+			if save.Platform == p.platform && save.ID == p.youtubeID && save.Lang == "ko" {
+				p.ReloadSub()
+				p.CheckDraft()
+			} else {
+				app.Window().Call("alert", fmt.Sprintf("다른 사람이 먼저 저장해서 %s 자막을 저장하지 못했습니다.\n"+
+					"편집기에서 다시 열면 비교 후 복구할 수 있습니다.", save.ID))
+			}
+		case resultJSON.Code == apiAuthCode || resultJSON.Code == apiForbiddenCode:
+			fmt.Println("저장 대기열을 보낼 권한이 없습니다: " + resultJSON.Msg)
+			p.LoadAccount()
+			p.NeedLogin()
+			p.offline.queued = len(queue)
+			return
+		case resultJSON.Code == apiRateLimitCode:
+			fmt.Printf("요청 제한으로 저장 대기 (%d초)\n", resultJSON.RetryAfter)
+			p.offline.queued = len(queue)
+			return
+		case resp.StatusCode >= 500:
+			fmt.Printf("서버 오류로 저장 대기 (%d)\n", resp.StatusCode)
+			p.offline.queued = len(queue)
+			return
+		default:
+			fmt.Printf("대기 중이던 저장 실패 (%s): %s\n", save.ID, resultJSON.Msg)
+		}
+
+		queue = queue[1:]
+		StoreSaveQueue(queue)
+	}
+
+	p.offline.queued = len(queue)
+}
+
+// ReloadSub 는 서버의 최신 자막을 다시 불러온다
+func (p *player) ReloadSub() {
+	body, version, statusCode := IsSubExist(p.platform, p.youtubeID, "ko")
+	if statusCode != 200 {
+		return
+	}
+
+	subs, err := ParseSrtSub(body)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	p.autosave.baseVersion = version
+	p.subtitle.youtubeSrtSub = subs
+	p.subtitle.content = p.LoadSubList()
+}
+
+func (p *player) WatchNetwork() {
+	p.offline.offline = !app.Window().Get("navigator").Get("onLine").Bool()
+	p.offline.queued = len(LoadSaveQueue())
+
+	p.offline.unsubscribes = append(p.offline.unsubscribes,
+		app.Window().AddEventListener("online", func(ctx app.Context, e app.Event) {
+			fmt.Println("온라인")
+			p.offline.offline = false
+			p.ReplaySaves()
+			p.Update()
+		}),
+		app.Window().AddEventListener("offline", func(ctx app.Context, e app.Event) {
+			fmt.Println("오프라인")
+			p.offline.offline = true
+			p.Update()
+		}),
+	)
+}
+
+func (p *player) UnwatchNetwork() {
+	for _, unsubscribe := range p.offline.unsubscribes {
+		unsubscribe()
+	}
+
+	p.offline.unsubscribes = nil
+}
+
+func (p *player) RenderOffline() app.UI {
+	var status string
+
+	switch {
+	case p.offline.offline && p.offline.queued != 0:
+		status = fmt.Sprintf("오프라인 - 저장 대기 %d개", p.offline.queued)
+	case p.offline.offline:
+		status = "오프라인"
+	case p.offline.queued != 0:
+		status = fmt.Sprintf("저장 대기 %d개", p.offline.queued)
+	}
+
+	return app.If(len(status) != 0,
+		app.Span().Body(
+			app.Text(status),
+		).
+			Class("offline-status"),
+	)
+}
@@ -0,0 +1,185 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/maxence-charriere/go-app/v7/pkg/app"
+)
+
+const qualityKey = "quality"
+
+type stream struct {
+	URL      string `json:"url"`
+	Itag     int    `json:"itag"`
+	MimeType string `json:"mimeType"`
+	Quality  string `json:"quality"`
+	Bitrate  int    `json:"bitrate"`
+	Width    int    `json:"width"`
+	Height   int    `json:"height"`
+	HasAudio bool   `json:"hasAudio"`
+	HasVideo bool   `json:"hasVideo"`
+}
+
+type quality struct {
+	app.Compo
+
+	streams  []stream
+	selected int
+
+	// 영상만 있는 (소리가 따로인) 화질, <video> 하나로 재생할 수 없어 고를 수 없다고 보여주기만 한다
+	videoOnly []stream
+
+	resumeAt      float64
+	resumePlaying bool
+}
+
+func (s stream) Label() string {
+	label := s.Quality
+	if len(label) == 0 {
+		label = "기본"
+	}
+
+	if mimeType := strings.SplitN(s.MimeType, ";", 2)[0]; len(mimeType) != 0 {
+		label += " (" + strings.TrimPrefix(mimeType, "video/") + ")"
+	}
+
+	return label
+}
+
+// SetStreams 는 <video> 하나로 재생할 수 있는 스트림만 고르고, 전에 고른 화질과 가장 가까운 것을 선택한다
+// 유튜브는 360p 정도만 영상과 소리를 함께 주므로 나머지 화질은 videoOnly 에 모아 지원하지 않는다고 알린다
+func (p *player) SetStreams(streams []stream) {
+	p.quality.streams = nil
+	p.quality.videoOnly = nil
+
+	heights := make(map[int]bool)
+	for _, s := range streams {
+		if s.HasAudio && s.HasVideo {
+			p.quality.streams = append(p.quality.streams, s)
+			heights[s.Height] = true
+		}
+	}
+
+	for _, s := range streams {
+		if s.HasVideo && !s.HasAudio && s.Height != 0 && !heights[s.Height] {
+			p.quality.videoOnly = append(p.quality.videoOnly, s)
+			heights[s.Height] = true
+		}
+	}
+
+	p.quality.selected = 0
+
+	var height int
+	if err := app.LocalStorage.Get(qualityKey, &height); err != nil || height == 0 {
+		return
+	}
+
+	best := -1
+	for i, s := range p.quality.streams {
+		diff := s.Height - height
+		if diff < 0 {
+			diff = -diff
+		}
+
+		if best < 0 || diff < best {
+			best = diff
+			p.quality.selected = i
+		}
+	}
+}
+
+// SelectStream 은 영상 주소만 바꾸고, 새 영상이 불러와지면 보던 위치와 재생 상태를 되돌린다
+func (p *player) SelectStream(i int) {
+	if i < 0 || i >= len(p.quality.streams) || i == p.quality.selected {
+		return
+	}
+
+	s := p.quality.streams[i]
+	fmt.Println("화질 변경: " + s.Label())
+
+	if p.video != nil {
+		p.quality.resumeAt = p.video.Get("currentTime").Float()
+		p.quality.resumePlaying = !p.video.Get("paused").Bool()
+	}
+
+	p.quality.selected = i
+	p.youtubeURL = p.StreamURL()
+
+	if err := app.LocalStorage.Set(qualityKey, s.Height); err != nil {
+		fmt.Println(err)
+	}
+}
+
+// StreamURL 은 선택한 화질을 미디어 프록시로 받는 주소
+func (p *player) StreamURL() string {
+	data := url.Values{}
+	data.Add("platform", p.platform)
+	data.Add("id", p.youtubeID)
+
+	if p.quality.selected < len(p.quality.streams) {
+		if itag := p.quality.streams[p.quality.selected].Itag; itag != 0 {
+			data.Add("itag", strconv.Itoa(itag))
+		}
+	}
+
+	return MediaServer + "?" + data.Encode()
+}
+
+// ResumeStream 은 화질을 바꾼 뒤 영상이 불러와졌을 때 호출된다
+func (p *player) ResumeStream() {
+	if p.video == nil || p.quality.resumeAt == 0 {
+		return
+	}
+
+	p.video.Set("currentTime", p.quality.resumeAt)
+	p.quality.resumeAt = 0
+
+	if p.quality.resumePlaying {
+		p.Play()
+	} else {
+		p.Pause()
+	}
+}
+
+func (p *player) RenderQuality() app.UI {
+	title := "화질"
+	if len(p.quality.videoOnly) != 0 {
+		title = "화질 (영상과 소리가 함께 있는 화질만 재생할 수 있습니다)"
+	}
+
+	return app.If(len(p.quality.streams) > 1 || (len(p.quality.streams) != 0 && len(p.quality.videoOnly) != 0),
+		app.Select().Body(
+			app.Range(p.quality.streams).Slice(func(i int) app.UI {
+				return app.Option().
+					Value(strconv.Itoa(i)).
+					Selected(i == p.quality.selected).
+					Text(p.quality.streams[i].Label())
+			}),
+			app.If(len(p.quality.videoOnly) != 0,
+				app.OptGroup().Body(
+					app.Range(p.quality.videoOnly).Slice(func(i int) app.UI {
+						return app.Option().
+							Disabled(true).
+							Text(p.quality.videoOnly[i].Label())
+					}),
+				).
+					Disabled(true).
+					Label("소리가 따로인 화질 (지원하지 않음)"),
+			),
+		).
+			Class("play-quality").
+			Title(title).
+			OnChange(func(ctx app.Context, e app.Event) {
+				i, err := strconv.Atoi(ctx.JSSrc.JSValue().Get("value").String())
+				if err != nil {
+					return
+				}
+
+				p.SelectStream(i)
+				p.Update()
+			}),
+	)
+}
@@ -0,0 +1,323 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/maxence-charriere/go-app/v7/pkg/app"
+)
+
+const (
+	roleReviewer = "reviewer"
+	roleAdmin    = "admin"
+)
+
+var reviewStatusText = map[string]string{
+	"pending":     "검토 대기",
+	"approved":    "게시됨",
+	"rejected":    "반려",
+	"changes":     "수정 요청",
+	"superseded":  "새로 올림",
+	"quarantined": "격리",
+	"discarded":   "폐기",
+}
+
+type reviewComment struct {
+	Author    string    `json:"author"`
+	Status    string    `json:"status"`
+	Text      string    `json:"text"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+type proposal struct {
+	ID        string          `json:"id"`
+	Lang      string          `json:"lang"`
+	Author    string          `json:"author"`
+	Base      string          `json:"base"`
+	Status    string          `json:"status"`
+	Reasons   []string        `json:"reasons"`
+	Comments  []reviewComment `json:"comments"`
+	Version   string          `json:"version"`
+	CreatedAt time.Time       `json:"createdAt"`
+}
+
+type reviewJSON struct {
+	Proposals []proposal `json:"proposals"`
+	Proposal  *proposal  `json:"proposal"`
+	Diff      []struct {
+		Op   string `json:"op"`
+		Text string `json:"text"`
+	} `json:"diff"`
+	Msg  string `json:"msg"`
+	Code int    `json:"code"`
+}
+
+type review struct {
+	app.Compo
+
+	proposals []proposal
+	current   *proposal
+	diff      []diffLine
+	comment   string
+}
+
+// CanReview 는 검토할 수 있는 역할인지 확인한다, 서버에서 다시 확인하므로 화면에만 쓴다
+func (p *player) CanReview() bool {
+	return roleRanks[p.user.role] >= roleRanks[roleReviewer]
+}
+
+// CanRelease 는 격리된 저장을 게시하거나 버릴 수 있는지 확인한다
+func (p *player) CanRelease() bool {
+	return roleRanks[p.user.role] >= roleRanks[roleAdmin]
+}
+
+func (p *player) postReview(action string, extra url.Values) (*reviewJSON, error) {
+	data := url.Values{}
+	data.Add("call", "review")
+	data.Add("action", action)
+	data.Add("platform", p.platform)
+	data.Add("id", p.youtubeID)
+	data.Add("lang", "ko")
+	for key, values := range extra {
+		data[key] = values
+	}
+
+	resp, err := http.PostForm(ApiServer, data)
+	if err != nil {
+		return nil, err
+	}
+
+	body, _ := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+
+	var result reviewJSON
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, err
+	}
+
+	if result.Code != 0 {
+		return &result, fmt.Errorf("%s", result.Msg)
+	}
+
+	return &result, nil
+}
+
+// LoadReviews 는 이 영상의 한국어 자막 검토 목록을 가져온다
+func (p *player) LoadReviews() {
+	if len(p.youtubeID) == 0 || !p.CanEdit() {
+		p.review.proposals = nil
+		return
+	}
+
+	result, err := p.postReview("list", nil)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	p.review.proposals = result.Proposals
+}
+
+// OpenProposal 은 지금 게시된 자막과 비교한 결과를 연다
+func (p *player) OpenProposal(id string) {
+	result, err := p.postReview("diff", url.Values{"proposal": {id}})
+	if err != nil {
+		app.Window().Call("alert", err.Error())
+		return
+	}
+
+	p.review.current = result.Proposal
+	p.review.comment = ""
+	p.review.diff = nil
+	for _, line := range result.Diff {
+		p.review.diff = append(p.review.diff, diffLine{Op: line.Op, Text: line.Text})
+	}
+}
+
+func (p *player) CloseProposal() {
+	p.review.current = nil
+	p.review.diff = nil
+	p.review.comment = ""
+}
+
+// ReviewProposal 은 action 이 approve, reject, changes, release, discard 중 하나, 게시하면 게시된 자막을 다시 불러온다
+func (p *player) ReviewProposal(action string) {
+	if p.review.current == nil {
+		return
+	}
+
+	_, err := p.postReview(action, url.Values{
+		"proposal": {p.review.current.ID},
+		"comment":  {p.review.comment},
+	})
+	if err != nil {
+		app.Window().Call("alert", err.Error())
+		return
+	}
+
+	if (action == "approve" || action == "release") && (p.IsEmptySub() || app.Window().Call("confirm", "게시된 자막을 불러올까요? 편집 중인 내용은 사라집니다").Bool()) {
+		p.ReloadSub()
+	}
+
+	p.CloseProposal()
+	p.LoadReviews()
+}
+
+func proposalAuthor(proposal proposal) string {
+	if len(proposal.Author) == 0 {
+		return "익명"
+	}
+
+	return proposal.Author
+}
+
+func (p *player) RenderReview() app.UI {
+	return app.Div().Body(
+		app.If(len(p.review.proposals) != 0,
+			app.Div().Body(
+				app.Range(p.review.proposals).Slice(func(i int) app.UI {
+					proposal := p.review.proposals[i]
+
+					return app.Div().Body(
+						app.Span().Body(
+							app.Text(reviewStatusText[proposal.Status]),
+						).
+							Class("review-status review-"+proposal.Status),
+						app.Text(fmt.Sprintf("%s · %s", proposalAuthor(proposal), proposal.CreatedAt.Local().Format("01-02 15:04"))),
+					).
+						Class("review-item").
+						OnClick(func(ctx app.Context, e app.Event) {
+							p.OpenProposal(proposal.ID)
+							p.Update()
+						})
+				}),
+			).
+				Class("review-list"),
+		),
+		p.RenderProposal(),
+	)
+}
+
+func (p *player) RenderProposal() app.UI {
+	current := p.review.current
+	if current == nil {
+		return app.If(false)
+	}
+
+	pending := current.Status == "pending"
+	quarantined := current.Status == "quarantined"
+
+	return app.Div().Body(
+		app.Div().Body(
+			app.P().Body(
+				app.Text(fmt.Sprintf("%s 님의 검토 요청 (%s, 기준 버전: %s)", proposalAuthor(*current), reviewStatusText[current.Status], current.Base)),
+			),
+			app.Div().Body(
+				app.Range(p.review.diff).Slice(func(i int) app.UI {
+					class := "draft-diff-add"
+					if p.review.diff[i].Op == "-" {
+						class = "draft-diff-del"
+					}
+
+					return app.Div().Body(
+						app.Text(p.review.diff[i].Op + " " + p.review.diff[i].Text),
+					).
+						Class(class)
+				}),
+			).
+				Class("draft-diff"),
+			app.Range(current.Reasons).Slice(func(i int) app.UI {
+				return app.P().Body(
+					app.Text(current.Reasons[i]),
+				).
+					Class("draft-warning")
+			}),
+			app.Range(current.Comments).Slice(func(i int) app.UI {
+				comment := current.Comments[i]
+
+				return app.P().Body(
+					app.Text(fmt.Sprintf("%s (%s): %s", comment.Author, reviewStatusText[comment.Status], comment.Text)),
+				).
+					Class("review-comment")
+			}),
+			app.If(pending && p.CanReview(),
+				app.Textarea().
+					Class("review-input").
+					Placeholder("반려하거나 수정을 요청할 때는 이유를 적어주세요").
+					Text(p.review.comment).
+					OnInput(func(ctx app.Context, e app.Event) {
+						p.review.comment = ctx.JSSrc.JSValue().Get("value").String()
+					}),
+				app.Button().Body(
+					app.Text("승인"),
+				).
+					Class("btn btn-blue").
+					Type("button").
+					OnClick(func(ctx app.Context, e app.Event) {
+						p.ReviewProposal("approve")
+						p.Update()
+					}),
+				app.Button().Body(
+					app.Text("수정 요청"),
+				).
+					Class("btn btn-gray").
+					Type("button").
+					OnClick(func(ctx app.Context, e app.Event) {
+						p.ReviewProposal("changes")
+						p.Update()
+					}),
+				app.Button().Body(
+					app.Text("반려"),
+				).
+					Class("btn btn-gray").
+					Type("button").
+					OnClick(func(ctx app.Context, e app.Event) {
+						p.ReviewProposal("reject")
+						p.Update()
+					}),
+			),
+			app.If(quarantined && p.CanRelease(),
+				app.Textarea().
+					Class("review-input").
+					Placeholder("메모 (선택)").
+					Text(p.review.comment).
+					OnInput(func(ctx app.Context, e app.Event) {
+						p.review.comment = ctx.JSSrc.JSValue().Get("value").String()
+					}),
+				app.Button().Body(
+					app.Text("게시"),
+				).
+					Class("btn btn-blue").
+					Type("button").
+					OnClick(func(ctx app.Context, e app.Event) {
+						p.ReviewProposal("release")
+						p.Update()
+					}),
+				app.Button().Body(
+					app.Text("폐기"),
+				).
+					Class("btn btn-gray").
+					Type("button").
+					OnClick(func(ctx app.Context, e app.Event) {
+						p.ReviewProposal("discard")
+						p.Update()
+					}),
+			),
+			app.Button().Body(
+				app.Text("닫기"),
+			).
+				Class("btn btn-gray").
+				Type("button").
+				OnClick(func(ctx app.Context, e app.Event) {
+					p.CloseProposal()
+					p.Update()
+				}),
+		).
+			Class("draft-dialog"),
+	).
+		Class("draft-overlay")
+}
@@ -14,7 +14,8 @@ import (
 )
 
 const (
-	ApiServer = "https://editor.jamak.icu/api"
+	ApiServer   = "https://editor.jamak.icu/api"
+	MediaServer = "https://editor.jamak.icu/media/proxy"
 )
 
 var (
@@ -22,11 +23,22 @@ var (
 )
 
 type ResultJSON struct {
-	Code     int    `json:"code"`
-	Subtitle string `json:"subtitle"`
-	URL      string `json:"url"`
-	Version  string `json:"version"`
-	Msg      string `json:"msg"`
+	Code           int      `json:"code"`
+	Subtitle       string   `json:"subtitle"`
+	URL            string   `json:"url"`
+	Version        string   `json:"version"`
+	Msg            string   `json:"msg"`
+	Rate           int      `json:"rate"`
+	Duration       float64  `json:"duration"`
+	SamplesPerPeak int      `json:"samplesPerPeak"`
+	Min            []int8   `json:"min"`
+	Max            []int8   `json:"max"`
+	Snapped        int      `json:"snapped"`
+	Streams        []stream `json:"streams"`
+	Draft          string   `json:"draft"`
+	Pending        string   `json:"pending"`
+	Quarantined    string   `json:"quarantined"`
+	RetryAfter     int      `json:"retryAfter"`
 }
 
 type player struct {
@@ -35,12 +47,23 @@ type player struct {
 	subtitle
 	editor
 	control
+	timeline
+	autosave
+	offline
+	finder
+	quality
+	memory
+	lint
 	user
+	review
+	comments
 
 	video app.Value
 
 	videoLoaded bool
 
+	platform string
+
 	youtubeID     string
 	youtubeURL    string
 	youtubeStart  float64
@@ -86,7 +109,13 @@ type control struct {
 type user struct {
 	app.Compo
 
-	ip string
+	name   string
+	role   string
+	signup bool
+
+	open         bool
+	formName     string
+	formPassword string
 }
 
 type srtSub struct {
@@ -96,30 +125,83 @@ type srtSub struct {
 	EndAt   time.Duration
 }
 
-func IsSubExist(platform, id, lang string) (string, int) {
+func IsSubExist(platform, id, lang string) (string, string, int) {
 	data := url.Values{}
 	data.Add("call", "subtitle")
 	data.Add("platform", platform)
 	data.Add("id", id)
 	data.Add("lang", lang)
 
-	resp, err := http.PostForm(ApiServer, data)
-	if err != nil || resp.StatusCode != 200 {
+	body, statusCode, err := CachedPostForm(data)
+	if err != nil || statusCode != 200 {
 		fmt.Println(err)
-		return "", 0
+		return "", "", 0
 	}
 
-	body, _ := ioutil.ReadAll(resp.Body)
-	resp.Body.Close()
-
 	var resultJSON ResultJSON
 	err = json.Unmarshal(body, &resultJSON)
 	if err != nil {
 		fmt.Println(err)
-		return "", 0
+		return "", "", 0
 	}
 
-	return resultJSON.Subtitle, resp.StatusCode
+	return resultJSON.Subtitle, resultJSON.Version, statusCode
+}
+
+func ParseSrtSub(body string) ([]*srtSub, error) {
+	srt, err := astisub.ReadFromSRT(strings.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+
+	var subs []*srtSub
+	for _, item := range srt.Items {
+		subs = append(subs, &srtSub{
+			Index:   item.Index,
+			Text:    item.String(),
+			StartAt: item.StartAt,
+			EndAt:   item.EndAt,
+		})
+	}
+
+	return subs, nil
+}
+
+func FormatSrtSub(subs []*srtSub) string {
+	var youtubeSrtRaw string
+
+	for _, item := range subs {
+		startAt := item.StartAt.Milliseconds()
+		endAt := item.EndAt.Milliseconds()
+
+		// 시작시간
+		startAth := startAt / 3600000
+		startAt = startAt - (3600000 * startAth)
+		startAtm := startAt / 60000
+		startAt = startAt - (60000 * startAtm)
+		startAts := startAt / 1000
+		startAt = startAt - (1000 * startAts)
+
+		// 종료시간
+		endAth := endAt / 3600000
+		endAt = endAt - (3600000 * endAth)
+		endAtm := endAt / 60000
+		endAt = endAt - (60000 * endAtm)
+		endAts := endAt / 1000
+		endAt = endAt - (1000 * endAts)
+
+		youtubeSrtRaw += fmt.Sprintf("%d\n"+
+			"%s --> %s\n"+
+			"%s\n"+
+			"\n",
+			item.Index,
+			fmt.Sprintf("%02d:%02d:%02d,%03d", startAth, startAtm, startAts, startAt),
+			fmt.Sprintf("%02d:%02d:%02d,%03d", endAth, endAtm, endAts, endAt),
+			item.Text,
+		)
+	}
+
+	return youtubeSrtRaw
 }
 
 func (p *player) DelSub(i int) {
@@ -142,7 +224,14 @@ func (p *player) Pause() {
 }
 
 func (p *player) LoadSubList() app.RangeLoop {
+	p.MatchComments()
+
 	return app.Range(p.subtitle.youtubeSrtSub).Slice(func(i int) app.UI {
+		containerClass := "editor-container"
+		if p.IsFindCurrent(i) {
+			containerClass += " find-current"
+		}
+
 		startAt := p.subtitle.youtubeSrtSub[i].StartAt.Milliseconds()
 		endAt := p.subtitle.youtubeSrtSub[i].EndAt.Milliseconds()
 
@@ -177,6 +266,7 @@ func (p *player) LoadSubList() app.RangeLoop {
 							timeDuration := ((float64(setTime.Hour()*3600) + float64(setTime.Minute()*60) + float64(setTime.Second())) + (float64(setTime.Nanosecond()) / 1000000000)) * 1000000000
 
 							p.subtitle.youtubeSrtSub[i].StartAt = time.Duration(timeDuration)
+							p.ScheduleDraft()
 							p.Update()
 
 							fmt.Println("시간 설정: " + p.subtitle.youtubeSrtSub[i].StartAt.String())
@@ -193,6 +283,7 @@ func (p *player) LoadSubList() app.RangeLoop {
 							timeDuration := ((float64(setTime.Hour()*3600) + float64(setTime.Minute()*60) + float64(setTime.Second())) + (float64(setTime.Nanosecond()) / 1000000000)) * 1000000000
 
 							p.subtitle.youtubeSrtSub[i].EndAt = time.Duration(timeDuration)
+							p.ScheduleDraft()
 							p.Update()
 
 							fmt.Println("시간 설정: " + p.subtitle.youtubeSrtSub[i].EndAt.String())
@@ -200,23 +291,32 @@ func (p *player) LoadSubList() app.RangeLoop {
 				).
 					Class("sub-time"),
 				app.Div().Body( // Textarea
-					app.Textarea().
-						Name("sub").
-						Class("form-control").
-						Text(p.subtitle.youtubeSrtSub[i].Text).
-						Placeholder("자막을 입력해주세요").
-						OnClick(func(ctx app.Context, e app.Event) {
-							fmt.Printf("[%s~%s] 자막 클릭: %s\n", p.subtitle.youtubeSrtSub[i].StartAt.String(), p.subtitle.youtubeSrtSub[i].EndAt.String(), p.subtitle.youtubeSrtSub[i].Text)
-							subTextClicked = true
-							p.subtitle.youtubeSubtitle = p.subtitle.youtubeSrtSub[i].Text
-							p.youtubeStart = p.subtitle.youtubeSrtSub[i].StartAt.Seconds()
-							p.Update()
-						}).
-						OnInput(func(ctx app.Context, e app.Event) {
-							p.Pause()
-							p.subtitle.youtubeSrtSub[i].Text = ctx.JSSrc.JSValue().Get("value").String()
-							p.Update()
-						}),
+					app.Div().Body(
+						p.RenderHighlight(i),
+						app.Textarea().
+							Name("sub").
+							Class("form-control").
+							Text(p.subtitle.youtubeSrtSub[i].Text).
+							Placeholder("자막을 입력해주세요").
+							OnClick(func(ctx app.Context, e app.Event) {
+								fmt.Printf("[%s~%s] 자막 클릭: %s\n", p.subtitle.youtubeSrtSub[i].StartAt.String(), p.subtitle.youtubeSrtSub[i].EndAt.String(), p.subtitle.youtubeSrtSub[i].Text)
+								subTextClicked = true
+								p.subtitle.youtubeSubtitle = p.subtitle.youtubeSrtSub[i].Text
+								p.youtubeStart = p.subtitle.youtubeSrtSub[i].StartAt.Seconds()
+								p.Suggest(i)
+								p.Update()
+							}).
+							OnInput(func(ctx app.Context, e app.Event) {
+								p.Pause()
+								p.subtitle.youtubeSrtSub[i].Text = ctx.JSSrc.JSValue().Get("value").String()
+								p.ScheduleDraft()
+								p.Find()
+								p.Update()
+							}),
+					).
+						Class(p.LintClass(i)).
+						Title(p.LintMessage(i)),
+					p.RenderSuggestions(i),
 				).
 					Class("sub-text"),
 				app.Div().Body(
@@ -226,7 +326,9 @@ func (p *player) LoadSubList() app.RangeLoop {
 						OnClick(func(ctx app.Context, e app.Event) {
 							fmt.Printf("%d번 자막 삭제\n", i+1)
 							p.DelSub(i)
+							p.Find()
 							p.subtitle.content = p.LoadSubList()
+							p.ScheduleDraft()
 							p.Update()
 						}),
 					app.A().
@@ -240,38 +342,58 @@ func (p *player) LoadSubList() app.RangeLoop {
 								StartAt: time.Duration(p.video.Get("currentTime").Float() * 1000000000),
 								EndAt:   time.Duration(p.video.Get("currentTime").Float() * 1000000000),
 							})
+							p.Find()
 							p.subtitle.content = p.LoadSubList()
+							p.ScheduleDraft()
 							p.Update()
 						}),
+					p.CommentMarker(i),
 				).
 					Class("sub-buttons"),
 			).
 				Class("sub-card"),
 		).
-			Class("editor-container")
+			ID(fmt.Sprintf("sub-%d", i)).
+			Class(containerClass)
 	})
 }
 
 func (p *player) OnMount(app.Context) {
 	fmt.Println("구성요소 mount")
+	p.WatchNetwork()
 }
 
 func (p *player) OnDismount() {
 	fmt.Println("구성요소 dismount")
+	p.UnwatchNetwork()
 }
 
 func (p *player) OnNav(_ app.Context, u *url.URL) {
 	p.youtubeID = u.Query().Get("id")
+	p.platform = u.Query().Get("platform")
+
+	if len(p.platform) == 0 {
+		p.platform = "youtube"
+	}
+
+	fmt.Println("ID: " + p.platform + "/" + p.youtubeID)
+
+	if len(p.youtubeID) == 0 {
+		app.Navigate("/dashboard")
+		return
+	}
 
-	fmt.Println("ID: " + p.youtubeID)
+	p.LoadAccount()
+	p.LoadReviews()
 
 	if len(p.youtubeID) != 0 {
-		fmt.Println("유튜브 영상 정보 가져오는 중...")
+		fmt.Println("영상 정보 가져오는 중...")
 		data := url.Values{}
-		data.Add("call", "youtube")
+		data.Add("call", "video")
+		data.Add("platform", p.platform)
 		data.Add("id", p.youtubeID)
 
-		resp, err := http.PostForm(ApiServer, data)
+		ytBody, _, err := CachedPostForm(data)
 		if err != nil {
 			fmt.Println("API 불러오기 실패 (resp)")
 			fmt.Println(err)
@@ -279,9 +401,6 @@ func (p *player) OnNav(_ app.Context, u *url.URL) {
 			return
 		}
 
-		ytBody, _ := ioutil.ReadAll(resp.Body)
-		resp.Body.Close()
-
 		var resultJSON ResultJSON
 		err = json.Unmarshal(ytBody, &resultJSON)
 		if err != nil {
@@ -290,33 +409,29 @@ func (p *player) OnNav(_ app.Context, u *url.URL) {
 			return
 		}
 
-		ytURL := resultJSON.URL
-
-		if len(ytURL) == 0 {
+		if len(resultJSON.URL) == 0 {
 			fmt.Println("실패")
 
 			return
 		}
 
-		body, statusCode := IsSubExist("youtube", p.youtubeID, "ko")
+		// 주소가 서버 IP 에 묶여 있을 수 있어서 영상은 항상 미디어 프록시로 받는다
+		p.SetStreams(resultJSON.Streams)
+		ytURL := p.StreamURL()
+
+		body, version, statusCode := IsSubExist(p.platform, p.youtubeID, "ko")
+		p.autosave.baseVersion = version
 
 		if statusCode == 200 {
 			fmt.Println("자막 발견")
-			srt, err := astisub.ReadFromSRT(strings.NewReader(body))
+			subs, err := ParseSrtSub(body)
 			if err != nil {
 				fmt.Println(err)
 
 				return
 			}
 
-			for _, item := range srt.Items {
-				p.subtitle.youtubeSrtSub = append(p.subtitle.youtubeSrtSub, &srtSub{
-					Index:   item.Index,
-					Text:    item.String(),
-					StartAt: item.StartAt,
-					EndAt:   item.EndAt,
-				})
-			}
+			p.subtitle.youtubeSrtSub = append(p.subtitle.youtubeSrtSub, subs...)
 		} else {
 			p.subtitle.youtubeSrtSub = append(p.subtitle.youtubeSrtSub, &srtSub{
 				Index:   0,
@@ -326,6 +441,16 @@ func (p *player) OnNav(_ app.Context, u *url.URL) {
 			})
 		}
 
+		p.CheckDraft()
+		p.LoadMemorySource()
+		p.Lint()
+		p.LoadComments()
+		p.Zoom(1)
+
+		if !p.offline.offline {
+			p.ReplaySaves()
+		}
+
 		p.control.playPause = "play-pause"
 		p.youtubeURL = ytURL
 		p.content = p.LoadSubList()
@@ -382,9 +507,19 @@ func (p *player) Render() app.UI {
 							p.subtitle.hidden = true
 						}
 
+						p.DrawTimeline(currentTime)
 						p.Update()
 					}).
 					OnLoadedData(func(ctx app.Context, e app.Event) {
+						p.video = ctx.Src.JSValue()
+						p.ResumeStream()
+
+						// 화질을 바꾸면 다시 불리므로 크기 조정은 한 번만 시작한다
+						if p.videoLoaded {
+							return
+						}
+						p.videoLoaded = true
+
 						go func() {
 							var prevWindowW, prevWindowH int
 
@@ -401,6 +536,8 @@ func (p *player) Render() app.UI {
 									p.subtitle.youtubeSubtitleMarginLeft = int(float64(p.youtubeWidth/2) * 0.3)
 									p.subtitle.youtubeSubtitleWidth = int(float64(p.youtubeWidth) * 0.7)
 									p.editor.height = int(float64(windowH) * 0.85)
+									p.timeline.width = p.youtubeWidth
+									p.timeline.height = int(float64(windowH) * 0.08)
 									p.Update()
 
 									fmt.Printf("가로: %d -> %d\n", windowW, p.youtubeWidth)
@@ -466,100 +603,175 @@ func (p *player) Render() app.UI {
 						Class("play-time-total"),
 				).
 					Class("play-time"),
+				p.RenderQuality(),
 			).
 				Class("play-control"),
+			p.RenderTimeline(),
 		).
 			Class("display-left"),
 		app.Div().Body( // 오른쪽
+			p.RenderFinder(),
 			app.Div().Body( // 자막 에디터
 				p.subtitle.content,
 			).
 				Class("display-subtitle-editor").
 				Style("height", fmt.Sprintf("%dpx", p.editor.height)),
 			app.Div().Body( // 저장
-				app.Script().Src("https://jsgetip.appspot.com/"),
-				app.Button().Body(
-					app.Text("Save"),
-				).
-					Class("btn btn-blue").
-					Type("button").
-					OnClick(func(ctx app.Context, e app.Event) {
-						fmt.Println("저장")
+				p.RenderAccount(),
+				p.RenderOffline(),
+				// 읽기만 할 수 있으면 편집 도구와 저장 버튼은 숨긴다
+				app.If(p.CanEdit(),
+					app.Button().Body(
+						app.Text("구간 생성"),
+					).
+						Class("btn btn-gray").
+						Type("button").
+						Title("음성 구간마다 빈 자막 만들기").
+						OnClick(func(ctx app.Context, e app.Event) {
+							p.AutoTiming("create")
+							p.Update()
+						}),
+					app.Button().Body(
+						app.Text("구간 맞춤"),
+					).
+						Class("btn btn-gray").
+						Type("button").
+						Title("자막 시간을 가까운 음성 경계에 맞추기").
+						OnClick(func(ctx app.Context, e app.Event) {
+							p.AutoTiming("snap")
+							p.Update()
+						}),
+					app.Button().Body(
+						app.Text("자동 번역"),
+					).
+						Class("btn btn-gray").
+						Type("button").
+						Title("원문 자막을 기계 번역해서 채우기").
+						OnClick(func(ctx app.Context, e app.Event) {
+							p.MachineTranslate()
+							p.Update()
+						}),
+					app.Button().Body(
+						app.Text("Save"),
+					).
+						Class("btn btn-blue").
+						Type("button").
+						OnClick(func(ctx app.Context, e app.Event) {
+							fmt.Println("저장")
 
-						var youtubeSrtRaw string
+							youtubeSrtRaw := FormatSrtSub(p.subtitle.youtubeSrtSub)
 
-						for _, item := range p.subtitle.youtubeSrtSub {
-							startAt := item.StartAt.Milliseconds()
-							endAt := item.EndAt.Milliseconds()
-
-							// 시작시간
-							startAth := startAt / 3600000
-							startAt = startAt - (3600000 * startAth)
-							startAtm := startAt / 60000
-							startAt = startAt - (60000 * startAtm)
-							startAts := startAt / 1000
-							startAt = startAt - (1000 * startAts)
-
-							// 종료시간
-							endAth := endAt / 3600000
-							endAt = endAt - (3600000 * endAth)
-							endAtm := endAt / 60000
-							endAt = endAt - (60000 * endAtm)
-							endAts := endAt / 1000
-							endAt = endAt - (1000 * endAts)
-
-							youtubeSrtRaw += fmt.Sprintf("%d\n"+
-								"%s --> %s\n"+
-								"%s\n"+
-								"\n",
-								item.Index,
-								fmt.Sprintf("%02d:%02d:%02d,%03d", startAth, startAtm, startAts, startAt),
-								fmt.Sprintf("%02d:%02d:%02d,%03d", endAth, endAtm, endAts, endAt),
-								item.Text,
-							)
-						}
+							if p.NeedLogin() {
+								p.Update()
 
-						p.user.ip = app.Window().Call("ip").String()
-						data := url.Values{}
-						data.Add("call", "save")
-						data.Add("ip", p.user.ip)
-						data.Add("platform", "youtube")
-						data.Add("id", p.youtubeID)
-						data.Add("lang", "ko")
-						data.Add("subtitle", youtubeSrtRaw)
+								app.Window().Call("alert", p.DeniedMessage())
 
-						resp, err := http.PostForm(ApiServer, data)
-						if err != nil || resp.StatusCode != 200 {
-							app.Window().Call("alert", "저장 실패")
+								return
+							}
 
-							return
-						}
+							if p.offline.offline {
+								p.QueueSave(queuedSave{
+									Platform: p.platform,
+									ID:       p.youtubeID,
+									Lang:     "ko",
+									Base:     p.autosave.baseVersion,
+									Subtitle: youtubeSrtRaw,
+									QueuedAt: time.Now(),
+								})
+								p.Update()
 
-						body, _ := ioutil.ReadAll(resp.Body)
-						resp.Body.Close()
+								app.Window().Call("alert", "오프라인 상태라서 연결되면 저장합니다")
 
-						var resultJSON ResultJSON
-						err = json.Unmarshal(body, &resultJSON)
-						if err != nil || resultJSON.Code != 0 {
-							app.Window().Call("alert", "저장 실패")
+								return
+							}
 
-							return
-						}
+							data := url.Values{}
+							data.Add("call", "save")
+							data.Add("platform", p.platform)
+							data.Add("id", p.youtubeID)
+							data.Add("lang", "ko")
+							data.Add("subtitle", youtubeSrtRaw)
 
-						app.Window().Call("alert", fmt.Sprintf("저장 완료\n"+
-							"버전: %s",
-							resultJSON.Version,
-						))
-					}),
+							resp, err := http.PostForm(ApiServer, data)
+							if err != nil {
+								p.QueueSave(queuedSave{
+									Platform: p.platform,
+									ID:       p.youtubeID,
+									Lang:     "ko",
+									Base:     p.autosave.baseVersion,
+									Subtitle: youtubeSrtRaw,
+									QueuedAt: time.Now(),
+								})
+								p.Update()
+
+								app.Window().Call("alert", "서버에 연결할 수 없어서 연결되면 저장합니다")
+
+								return
+							}
+
+							body, _ := ioutil.ReadAll(resp.Body)
+							resp.Body.Close()
+
+							var resultJSON ResultJSON
+							err = json.Unmarshal(body, &resultJSON)
+							if resultJSON.Code == apiAuthCode || resultJSON.Code == apiForbiddenCode {
+								p.LoadAccount()
+								p.NeedLogin()
+								p.Update()
+
+								app.Window().Call("alert", p.DeniedMessage())
+
+								return
+							}
+
+							if resultJSON.Code == apiRateLimitCode {
+								app.Window().Call("alert", fmt.Sprintf("저장 요청이 너무 많습니다\n%d초 뒤에 다시 저장해주세요", resultJSON.RetryAfter))
+
+								return
+							}
+
+							if err != nil || resp.StatusCode != 200 || resultJSON.Code != 0 {
+								app.Window().Call("alert", "저장 실패")
+
+								return
+							}
+
+							p.DelDraft()
+							p.autosave.baseVersion = resultJSON.Version
+
+							// 격리된 저장은 관리자가 확인해야 게시된다
+							if len(resultJSON.Quarantined) != 0 {
+								p.LoadReviews()
+								app.Window().Call("alert", "게시된 자막과 많이 달라서 관리자가 확인한 뒤 게시됩니다")
+								return
+							}
+
+							// 검토 대기로 올라간 저장은 검토자가 승인해야 게시된다
+							if len(resultJSON.Pending) != 0 {
+								p.LoadReviews()
+								app.Window().Call("alert", "검토 요청 완료\n검토 후 게시됩니다")
+								return
+							}
+
+							app.Window().Call("alert", fmt.Sprintf("저장 완료\n"+
+								"버전: %s",
+								resultJSON.Version,
+							))
+						}),
+				),
 			).
 				Class("editor-button"),
 		).
 			Class("display-right"),
+		p.RenderDraft(),
+		p.RenderReview(),
+		p.RenderComments(),
 	).
 		Class("display-main")
 }
 
 func main() {
 	app.Route("/", &player{})
+	app.Route("/dashboard", &dashboard{})
 	app.Run()
 }
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+
+	"github.com/maxence-charriere/go-app/v7/pkg/app"
+)
+
+// MachineTranslate 는 원문 자막을 기계 번역해서 빈 자막을 채운다, 서버에는 초안으로만 남는다
+func (p *player) MachineTranslate() {
+	if !p.IsEmptySub() && !app.Window().Call("confirm", "지금 자막을 지우고 원문을 번역해서 새로 만들까요?").Bool() {
+		return
+	}
+
+	fmt.Println("기계 번역 중...")
+
+	data := url.Values{}
+	data.Add("call", "translate")
+	data.Add("platform", p.platform)
+	data.Add("id", p.youtubeID)
+	data.Add("source", SourceLang)
+	data.Add("lang", TargetLang)
+
+	resp, err := http.PostForm(ApiServer, data)
+	if err != nil || resp.StatusCode != 200 {
+		app.Window().Call("alert", "번역할 원문 자막이 없습니다")
+
+		return
+	}
+
+	body, _ := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+
+	var resultJSON ResultJSON
+	err = json.Unmarshal(body, &resultJSON)
+	if err != nil || resultJSON.Code != 0 {
+		app.Window().Call("alert", "번역하지 못했습니다")
+
+		return
+	}
+
+	subs, err := ParseSrtSub(resultJSON.Subtitle)
+	if err != nil || len(subs) == 0 {
+		fmt.Println(err)
+
+		return
+	}
+
+	p.PushUndo()
+	p.subtitle.youtubeSrtSub = subs
+	p.subtitle.content = p.LoadSubList()
+	p.ScheduleDraft()
+
+	fmt.Printf("기계 번역 완료: %d개\n", len(subs))
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+
+	"github.com/maxence-charriere/go-app/v7/pkg/app"
+)
+
+// AutoTiming 은 음성 구간으로 빈 자막을 만들거나(create) 지금 자막 시간을 음성 경계에 맞춘다(snap)
+func (p *player) AutoTiming(mode string) {
+	fmt.Printf("자동 타이밍: %s\n", mode)
+
+	if mode == "create" && !p.IsEmptySub() && !app.Window().Call("confirm", "지금 자막을 지우고 음성 구간으로 새로 만들까요?").Bool() {
+		return
+	}
+
+	data := url.Values{}
+	data.Add("call", "vad")
+	data.Add("platform", p.platform)
+	data.Add("id", p.youtubeID)
+	data.Add("mode", mode)
+
+	if mode == "snap" {
+		data.Add("subtitle", FormatSrtSub(p.subtitle.youtubeSrtSub))
+	}
+
+	resp, err := http.PostForm(ApiServer, data)
+	if err != nil || resp.StatusCode != 200 {
+		app.Window().Call("alert", "음성 구간을 찾지 못했습니다")
+
+		return
+	}
+
+	body, _ := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+
+	var resultJSON ResultJSON
+	err = json.Unmarshal(body, &resultJSON)
+	if err != nil || resultJSON.Code != 0 {
+		app.Window().Call("alert", "음성 구간을 찾지 못했습니다")
+
+		return
+	}
+
+	subs, err := ParseSrtSub(resultJSON.Subtitle)
+	if err != nil || len(subs) == 0 {
+		fmt.Println(err)
+
+		return
+	}
+
+	p.subtitle.youtubeSrtSub = subs
+	p.subtitle.content = p.LoadSubList()
+	p.ScheduleDraft()
+
+	if mode == "snap" {
+		fmt.Printf("%d개 자막 시간 조정\n", resultJSON.Snapped)
+	}
+}
+
+// IsEmptySub 는 처음 만들어지는 "Text here" 한 줄짜리 자막인지 확인한다
+func (p *player) IsEmptySub() bool {
+	for _, item := range p.subtitle.youtubeSrtSub {
+		if item.Text != "" && item.Text != "Text here" {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,190 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/maxence-charriere/go-app/v7/pkg/app"
+)
+
+// 확대 단계별 화면에 보이는 구간 (초), 서버의 WaveformLevels 와 순서가 같다
+var timelineSpans = []float64{5, 10, 30, 120}
+
+type timeline struct {
+	app.Compo
+
+	zoom   int
+	width  int
+	height int
+
+	peaks map[int]*ResultJSON
+}
+
+func LoadWaveform(platform, id string, level int) (*ResultJSON, int) {
+	data := url.Values{}
+	data.Add("call", "waveform")
+	data.Add("platform", platform)
+	data.Add("id", id)
+	data.Add("level", strconv.Itoa(level))
+
+	resp, err := http.PostForm(ApiServer, data)
+	if err != nil || resp.StatusCode != 200 {
+		fmt.Println(err)
+		return nil, 0
+	}
+
+	body, _ := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+
+	var resultJSON ResultJSON
+	err = json.Unmarshal(body, &resultJSON)
+	if err != nil {
+		fmt.Println(err)
+		return nil, 0
+	}
+
+	return &resultJSON, resp.StatusCode
+}
+
+func (p *player) Zoom(zoom int) {
+	if zoom < 0 || zoom >= len(timelineSpans) {
+		return
+	}
+
+	p.timeline.zoom = zoom
+
+	if p.timeline.peaks == nil {
+		p.timeline.peaks = make(map[int]*ResultJSON)
+	}
+
+	if _, ok := p.timeline.peaks[zoom]; !ok {
+		fmt.Printf("파형 불러오는 중... (%d단계)\n", zoom)
+		peaks, statusCode := LoadWaveform(p.platform, p.youtubeID, zoom)
+		if statusCode != 200 {
+			peaks = nil
+		}
+
+		p.timeline.peaks[zoom] = peaks
+	}
+}
+
+// TimelineStart 는 현재 시간이 가운데 오도록 한 타임라인 왼쪽 끝 시간
+func (p *player) TimelineStart(currentTime float64) float64 {
+	start := currentTime - timelineSpans[p.timeline.zoom]/2
+	if start < 0 {
+		start = 0
+	}
+
+	return start
+}
+
+func (p *player) DrawTimeline(currentTime float64) {
+	canvas := app.Window().GetElementByID("timeline")
+	if canvas.IsNull() || canvas.IsUndefined() || p.timeline.width == 0 {
+		return
+	}
+
+	width := float64(p.timeline.width)
+	height := float64(p.timeline.height)
+	span := timelineSpans[p.timeline.zoom]
+	start := p.TimelineStart(currentTime)
+
+	ctx := canvas.Call("getContext", "2d")
+	ctx.Call("clearRect", 0, 0, width, height)
+
+	// 파형
+	if peaks := p.timeline.peaks[p.timeline.zoom]; peaks != nil && peaks.Rate != 0 {
+		peaksPerSecond := float64(peaks.Rate) / float64(peaks.SamplesPerPeak)
+		middle := height / 2
+
+		ctx.Set("fillStyle", "#575759")
+
+		for x := 0; x < p.timeline.width; x++ {
+			from := int((start + span*float64(x)/width) * peaksPerSecond)
+			to := int((start + span*float64(x+1)/width) * peaksPerSecond)
+			if to <= from {
+				to = from + 1
+			}
+
+			if from >= len(peaks.Min) {
+				break
+			}
+			if to > len(peaks.Min) {
+				to = len(peaks.Min)
+			}
+
+			min, max := peaks.Min[from], peaks.Max[from]
+			for i := from; i < to; i++ {
+				if peaks.Min[i] < min {
+					min = peaks.Min[i]
+				}
+				if peaks.Max[i] > max {
+					max = peaks.Max[i]
+				}
+			}
+
+			top := middle - float64(max)/127*middle
+			bottom := middle - float64(min)/127*middle
+			ctx.Call("fillRect", x, top, 1, bottom-top+1)
+		}
+	}
+
+	// 자막 구간
+	ctx.Set("fillStyle", "rgba(61, 132, 235, 0.35)")
+	for _, item := range p.subtitle.youtubeSrtSub {
+		if item.EndAt.Seconds() < start || item.StartAt.Seconds() > start+span {
+			continue
+		}
+
+		left := (item.StartAt.Seconds() - start) / span * width
+		right := (item.EndAt.Seconds() - start) / span * width
+		ctx.Call("fillRect", left, 0, right-left, height)
+	}
+
+	// 재생 위치
+	ctx.Set("fillStyle", "#ff453a")
+	ctx.Call("fillRect", (currentTime-start)/span*width, 0, 1, height)
+}
+
+func (p *player) RenderTimeline() app.UI {
+	return app.Div().Body(
+		app.Canvas().
+			ID("timeline").
+			Class("timeline").
+			Width(p.timeline.width).
+			Height(p.timeline.height).
+			OnClick(func(ctx app.Context, e app.Event) {
+				if p.video == nil {
+					return
+				}
+
+				currentTime := p.video.Get("currentTime").Float()
+				seekTo := p.TimelineStart(currentTime) + e.Get("offsetX").Float()/float64(p.timeline.width)*timelineSpans[p.timeline.zoom]
+
+				fmt.Printf("타임라인 클릭: %f초\n", seekTo)
+				p.video.Set("currentTime", seekTo)
+			}),
+		app.Div().Body(
+			app.Span().
+				Class("timeline-zoom-in").
+				Title("확대").
+				OnClick(func(ctx app.Context, e app.Event) {
+					p.Zoom(p.timeline.zoom - 1)
+					p.Update()
+				}),
+			app.Span().
+				Class("timeline-zoom-out").
+				Title("축소").
+				OnClick(func(ctx app.Context, e app.Event) {
+					p.Zoom(p.timeline.zoom + 1)
+					p.Update()
+				}),
+		).
+			Class("timeline-zoom"),
+	).
+		Class("timeline-container")
+}
@@ -0,0 +1,232 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+const maxCommentLen = 1000
+
+// CommentThread 는 자막 하나에 남기는 메모와 답글, SubtitleDir/platform/id/comments/<lang>.json 에 언어별로 모아 저장한다
+// 자막 번호는 자막을 추가, 삭제하면 바뀌므로 StartAt, EndAt (밀리초) 시간 구간으로 어느 자막인지 찾는다
+// Comments 의 첫 번째가 처음 남긴 메모, 나머지는 답글
+type CommentThread struct {
+	ID         string    `json:"id"`
+	Lang       string    `json:"lang"`
+	StartAt    int64     `json:"startAt"`
+	EndAt      int64     `json:"endAt"`
+	Comments   []Comment `json:"comments"`
+	Resolved   bool      `json:"resolved"`
+	ResolvedBy string    `json:"resolvedBy,omitempty"`
+	ResolvedAt time.Time `json:"resolvedAt"`
+	CreatedAt  time.Time `json:"createdAt"`
+}
+
+type Comment struct {
+	ID        string    `json:"id"`
+	Author    string    `json:"author"`
+	Text      string    `json:"text"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+type CommentJSON struct {
+	Threads []*CommentThread `json:"threads,omitempty"`
+	Thread  *CommentThread   `json:"thread,omitempty"`
+	Code    int              `json:"code"`
+}
+
+var (
+	commentIDRegx = regexp.MustCompile(`^[0-9]{1,20}$`)
+
+	commentMu sync.Mutex
+)
+
+func ValidateCommentID(cid string) error {
+	if !commentIDRegx.MatchString(cid) {
+		return fmt.Errorf("invalid comment: %s", cid)
+	}
+
+	return nil
+}
+
+func validateCommentText(text string) (string, error) {
+	text = strings.TrimSpace(text)
+	if len(text) == 0 {
+		return "", fmt.Errorf("comment is empty")
+	}
+
+	if utf8.RuneCountInString(text) > maxCommentLen {
+		return "", fmt.Errorf("comment is longer than %d characters", maxCommentLen)
+	}
+
+	return text, nil
+}
+
+// ParseCueRange 는 폼의 start, end (밀리초) 를 읽는다
+func ParseCueRange(start, end string) (int64, int64, error) {
+	startAt, err := strconv.ParseInt(start, 10, 64)
+	if err != nil || startAt < 0 {
+		return 0, 0, fmt.Errorf("invalid start: %s", start)
+	}
+
+	endAt, err := strconv.ParseInt(end, 10, 64)
+	if err != nil || endAt < startAt {
+		return 0, 0, fmt.Errorf("invalid end: %s", end)
+	}
+
+	return startAt, endAt, nil
+}
+
+func loadComments(platform, id, lang string) ([]*CommentThread, error) {
+	threads := []*CommentThread{}
+
+	file, err := SubtitleStore.ReadFile(platform, id, "comments", lang+".json")
+	if os.IsNotExist(err) {
+		return threads, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(file, &threads); err != nil {
+		return nil, err
+	}
+
+	return threads, nil
+}
+
+func storeComments(platform, id, lang string, threads []*CommentThread) error {
+	file, err := json.Marshal(threads)
+	if err != nil {
+		return err
+	}
+
+	return SubtitleStore.WriteFile(file, platform, id, "comments", lang+".json")
+}
+
+// ListComments 는 시간 순서로 돌려준다
+func ListComments(platform, id, lang string) ([]*CommentThread, error) {
+	commentMu.Lock()
+	defer commentMu.Unlock()
+
+	threads, err := loadComments(platform, id, lang)
+	if err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(threads, func(i, j int) bool {
+		return threads[i].StartAt < threads[j].StartAt
+	})
+
+	return threads, nil
+}
+
+func newComment(author, text string) Comment {
+	now := time.Now()
+
+	return Comment{
+		ID:        strconv.FormatInt(now.UnixNano(), 10),
+		Author:    author,
+		Text:      text,
+		CreatedAt: now,
+	}
+}
+
+// AddComment 는 startAt~endAt 구간의 자막에 새 메모를 남긴다
+func AddComment(platform, id, lang string, startAt, endAt int64, author, text string) (*CommentThread, error) {
+	text, err := validateCommentText(text)
+	if err != nil {
+		return nil, err
+	}
+
+	commentMu.Lock()
+	defer commentMu.Unlock()
+
+	threads, err := loadComments(platform, id, lang)
+	if err != nil {
+		return nil, err
+	}
+
+	comment := newComment(author, text)
+	thread := &CommentThread{
+		ID:        comment.ID,
+		Lang:      lang,
+		StartAt:   startAt,
+		EndAt:     endAt,
+		Comments:  []Comment{comment},
+		CreatedAt: comment.CreatedAt,
+	}
+
+	if err := storeComments(platform, id, lang, append(threads, thread)); err != nil {
+		return nil, err
+	}
+
+	return thread, nil
+}
+
+// updateThread 는 tid 스레드를 찾아 update 를 부르고 저장한다
+func updateThread(platform, id, lang, tid string, update func(thread *CommentThread)) (*CommentThread, error) {
+	if err := ValidateCommentID(tid); err != nil {
+		return nil, err
+	}
+
+	commentMu.Lock()
+	defer commentMu.Unlock()
+
+	threads, err := loadComments(platform, id, lang)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, thread := range threads {
+		if thread.ID != tid {
+			continue
+		}
+
+		update(thread)
+
+		if err := storeComments(platform, id, lang, threads); err != nil {
+			return nil, err
+		}
+
+		return thread, nil
+	}
+
+	return nil, fmt.Errorf("comment not found: %s", tid)
+}
+
+// ReplyComment 는 답글을 단다, 해결된 스레드에 답글을 달면 다시 열린다
+func ReplyComment(platform, id, lang, tid, author, text string) (*CommentThread, error) {
+	text, err := validateCommentText(text)
+	if err != nil {
+		return nil, err
+	}
+
+	return updateThread(platform, id, lang, tid, func(thread *CommentThread) {
+		thread.Comments = append(thread.Comments, newComment(author, text))
+		thread.Resolved = false
+		thread.ResolvedBy = ""
+		thread.ResolvedAt = time.Time{}
+	})
+}
+
+func ResolveComment(platform, id, lang, tid, resolver string, resolved bool) (*CommentThread, error) {
+	return updateThread(platform, id, lang, tid, func(thread *CommentThread) {
+		thread.Resolved = resolved
+		thread.ResolvedBy = ""
+		thread.ResolvedAt = time.Time{}
+
+		if resolved {
+			thread.ResolvedBy = resolver
+			thread.ResolvedAt = time.Now()
+		}
+	})
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseCueRange(t *testing.T) {
+	tests := []struct {
+		start, end     string
+		startAt, endAt int64
+		ok             bool
+	}{
+		{"0", "0", 0, 0, true},
+		{"1000", "2500", 1000, 2500, true},
+		{"-1", "1000", 0, 0, false},
+		{"2000", "1000", 0, 0, false},
+		{"", "1000", 0, 0, false},
+		{"1000", "", 0, 0, false},
+		{"1.5", "2", 0, 0, false},
+		{"99999999999999999999", "1", 0, 0, false},
+	}
+
+	for _, test := range tests {
+		startAt, endAt, err := ParseCueRange(test.start, test.end)
+		if (err == nil) != test.ok || startAt != test.startAt || endAt != test.endAt {
+			t.Errorf("ParseCueRange(%q, %q) = %d, %d, %v", test.start, test.end, startAt, endAt, err)
+		}
+	}
+}
+
+func TestComments(t *testing.T) {
+	setupAPI(t)
+
+	if threads, err := ListComments("youtube", proxyTestID, "ko"); err != nil || threads == nil || len(threads) != 0 {
+		t.Fatalf("empty comments = %#v, %v", threads, err)
+	}
+
+	for _, text := range []string{"", "   ", strings.Repeat("가", maxCommentLen+1)} {
+		if _, err := AddComment("youtube", proxyTestID, "ko", 0, 1000, "kim", text); err == nil {
+			t.Errorf("AddComment(%d runes) succeeded", len([]rune(text)))
+		}
+	}
+
+	later, err := AddComment("youtube", proxyTestID, "ko", 5000, 6000, "kim", " 오타 ")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if later.Lang != "ko" || later.StartAt != 5000 || later.EndAt != 6000 || len(later.Comments) != 1 || later.Comments[0].Text != "오타" || later.Comments[0].Author != "kim" || later.ID != later.Comments[0].ID {
+		t.Errorf("thread = %+v", later)
+	}
+
+	earlier, err := AddComment("youtube", proxyTestID, "ko", 1000, 2000, "lee", "시간이 늦다")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 다른 언어의 메모와 섞이지 않는다
+	if _, err := AddComment("youtube", proxyTestID, "en", 1000, 2000, "lee", "typo"); err != nil {
+		t.Fatal(err)
+	}
+
+	threads, err := ListComments("youtube", proxyTestID, "ko")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var ids []string
+	for _, thread := range threads {
+		ids = append(ids, thread.ID)
+	}
+
+	if !reflect.DeepEqual(ids, []string{earlier.ID, later.ID}) {
+		t.Errorf("threads = %v, want sorted by start", ids)
+	}
+
+	// 해결, 다시 열기
+	thread, err := ResolveComment("youtube", proxyTestID, "ko", later.ID, "park", true)
+	if err != nil || !thread.Resolved || thread.ResolvedBy != "park" || thread.ResolvedAt.IsZero() {
+		t.Fatalf("resolved thread = %+v, %v", thread, err)
+	}
+
+	thread, err = ResolveComment("youtube", proxyTestID, "ko", later.ID, "park", false)
+	if err != nil || thread.Resolved || len(thread.ResolvedBy) != 0 || !thread.ResolvedAt.IsZero() {
+		t.Errorf("unresolved thread = %+v, %v", thread, err)
+	}
+
+	// 해결된 스레드에 답글을 달면 다시 열린다
+	if _, err := ResolveComment("youtube", proxyTestID, "ko", later.ID, "park", true); err != nil {
+		t.Fatal(err)
+	}
+
+	thread, err = ReplyComment("youtube", proxyTestID, "ko", later.ID, "lee", "아직 그대로예요")
+	if err != nil || thread.Resolved || len(thread.ResolvedBy) != 0 || len(thread.Comments) != 2 || thread.Comments[1].Author != "lee" {
+		t.Errorf("replied thread = %+v, %v", thread, err)
+	}
+
+	if _, err := ReplyComment("youtube", proxyTestID, "ko", later.ID, "lee", " "); err == nil {
+		t.Error("empty reply was accepted")
+	}
+
+	for _, tid := range []string{"", "../x", "123", earlier.ID + "0"} {
+		if _, err := ReplyComment("youtube", proxyTestID, "ko", tid, "lee", "답글"); err == nil {
+			t.Errorf("reply to %q succeeded", tid)
+		}
+
+		if _, err := ResolveComment("youtube", proxyTestID, "ko", tid, "lee", true); err == nil {
+			t.Errorf("resolve %q succeeded", tid)
+		}
+	}
+
+	// 다른 언어의 스레드는 찾지 못한다
+	if _, err := ResolveComment("youtube", proxyTestID, "en", later.ID, "park", true); err == nil {
+		t.Error("thread was found in another language")
+	}
+
+	// 저장한 내용은 다시 읽어도 같다
+	threads, err = ListComments("youtube", proxyTestID, "ko")
+	if err != nil || len(threads) != 2 || len(threads[1].Comments) != 2 || threads[1].Resolved || threads[0].Resolved {
+		t.Errorf("stored threads = %+v, %v", threads, err)
+	}
+}
+
+func TestCommentAPIRange(t *testing.T) {
+	setupAPI(t)
+
+	tests := []struct {
+		start, end string
+		code       int
+	}{
+		{"1000", "2000", 0},
+		{"2000", "1000", 1702},
+		{"-5", "1000", 1702},
+		{"", "", 1702},
+	}
+
+	for _, test := range tests {
+		form := url.Values{"call": {"comment"}, "action": {"add"}, "platform": {"youtube"}, "id": {proxyTestID}, "lang": {"ko"}, "start": {test.start}, "end": {test.end}, "text": {"메모"}}
+		r := httptest.NewRequest("POST", "/api", strings.NewReader(form.Encode()))
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		w := httptest.NewRecorder()
+
+		API(w, r)
+
+		var result CommentJSON
+		if err := json.Unmarshal(w.Body.Bytes(), &result); err != nil || result.Code != test.code {
+			t.Errorf("add %s-%s = %d %s, want code %d", test.start, test.end, w.Code, w.Body, test.code)
+		}
+	}
+
+	if threads, err := ListComments("youtube", proxyTestID, "ko"); err != nil || len(threads) != 1 {
+		t.Errorf("threads = %+v, %v", threads, err)
+	}
+}
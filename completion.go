@@ -0,0 +1,263 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/asticode/go-astisub"
+)
+
+// 번역 진행률을 계산할 때 따로 정하지 않으면 이 언어를 원문으로 본다
+const DefaultSourceLang = "en"
+
+// Completion 은 한 언어 자막이 영상을 얼마나 채웠는지 나타낸다
+type Completion struct {
+	Duration   time.Duration `json:"duration"`
+	Cued       time.Duration `json:"cued"`
+	Coverage   float64       `json:"coverage"`
+	Cues       int           `json:"cues"`
+	EmptyCues  int           `json:"emptyCues"`
+	LargestGap time.Duration `json:"largestGap"`
+	GapStartAt time.Duration `json:"gapStartAt"`
+	// 원문 자막 중 시간이 겹치는 번역 자막이 있는 비율, 원문이 없거나 자기 자신이면 -1
+	Translated float64   `json:"translated"`
+	UpdatedAt  time.Time `json:"updatedAt"`
+}
+
+type VideoCompletion struct {
+	SourceLang string                 `json:"sourceLang"`
+	Languages  map[string]*Completion `json:"languages"`
+}
+
+type CompletionJSON struct {
+	SourceLang string                 `json:"sourceLang"`
+	Languages  map[string]*Completion `json:"languages"`
+	Code       int                    `json:"code"`
+}
+
+var completionMu sync.Mutex
+
+func LoadCompletion(platform, id string) (*VideoCompletion, error) {
+	file, err := SubtitleStore.ReadFile(platform, id, "completion.json")
+	if err != nil {
+		return nil, err
+	}
+
+	var completion VideoCompletion
+	if err := json.Unmarshal(file, &completion); err != nil {
+		return nil, err
+	}
+
+	return &completion, nil
+}
+
+// ErrNoSubtitles 는 진행률을 계산할 자막이 하나도 없을 때
+var ErrNoSubtitles = errors.New("no subtitles")
+
+// UpdateCompletion 은 저장할 때마다 모든 언어를 다시 계산해서 completion.json 에 남긴다 (원문이 바뀌면 번역 진행률도 바뀌므로)
+// source 가 비어 있으면 전에 쓴 원문 언어를 그대로 쓴다
+func UpdateCompletion(platform, id, source string) (*VideoCompletion, error) {
+	completionMu.Lock()
+	defer completionMu.Unlock()
+
+	completion, err := ComputeVideoCompletion(platform, id, source)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := json.Marshal(completion)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := SubtitleStore.WriteFile(file, platform, id, "completion.json"); err != nil {
+		return nil, err
+	}
+
+	return completion, nil
+}
+
+// ComputeVideoCompletion 은 저장하지 않고 계산만 한다, 보는 사람이 원문 언어를 바꿔 볼 때 쓴다
+func ComputeVideoCompletion(platform, id, source string) (*VideoCompletion, error) {
+	if len(source) == 0 {
+		source = DefaultSourceLang
+		if prev, err := LoadCompletion(platform, id); err == nil && len(prev.SourceLang) != 0 {
+			source = prev.SourceLang
+		}
+	}
+
+	files, err := subtitleFiles(platform, id)
+	if err != nil {
+		return nil, err
+	}
+
+	tracks := make(map[string]*astisub.Subtitles)
+	for _, file := range files {
+		lang := strings.TrimSuffix(filepath.Base(file), ".srt")
+
+		subs, err := ReadSubtitle(platform, id, lang)
+		if err != nil {
+			fmt.Println(err)
+			continue
+		}
+
+		tracks[lang] = subs
+	}
+
+	if len(tracks) == 0 {
+		return nil, ErrNoSubtitles
+	}
+
+	duration := videoDuration(platform, id)
+	completion := &VideoCompletion{
+		SourceLang: source,
+		Languages:  make(map[string]*Completion),
+	}
+
+	for lang, subs := range tracks {
+		var sourceSubs *astisub.Subtitles
+		if lang != source {
+			sourceSubs = tracks[source]
+		}
+
+		completion.Languages[lang] = ComputeCompletion(subs, sourceSubs, duration)
+	}
+
+	return completion, nil
+}
+
+// videoDuration 은 저장된 영상 정보 -> 파형 캐시 순으로 영상 길이를 찾고, 모르면 0
+func videoDuration(platform, id string) time.Duration {
+	if metadata, err := LoadMetadata(platform, id); err == nil && metadata.Duration > 0 {
+		return metadata.Duration
+	}
+
+	if waveform, err := GetWaveform(platform, id); err == nil {
+		return time.Duration(waveform.Duration * float64(time.Second))
+	}
+
+	return 0
+}
+
+// ComputeCompletion 은 duration 이 0 이면 마지막 자막이 끝나는 시간을 영상 길이로 본다
+func ComputeCompletion(subs, source *astisub.Subtitles, duration time.Duration) *Completion {
+	completion := &Completion{
+		Cues:       len(subs.Items),
+		Translated: -1,
+		UpdatedAt:  time.Now(),
+	}
+
+	var spans []SpeechSegment
+	for _, item := range subs.Items {
+		if len(CueText(item)) == 0 {
+			completion.EmptyCues++
+			continue
+		}
+
+		if item.EndAt > item.StartAt {
+			spans = append(spans, SpeechSegment{StartAt: item.StartAt, EndAt: item.EndAt})
+		}
+	}
+
+	spans = mergeSpans(spans)
+
+	if duration == 0 && len(spans) != 0 {
+		duration = spans[len(spans)-1].EndAt
+	}
+	completion.Duration = duration
+
+	// 모든 구간을 [0, duration] 으로 자르고, 영상이 끝난 뒤에 시작하는 구간은 뺀다
+	clipped := spans[:0]
+	for _, span := range spans {
+		if span.StartAt < 0 {
+			span.StartAt = 0
+		}
+
+		if span.EndAt > duration {
+			span.EndAt = duration
+		}
+
+		if span.EndAt > span.StartAt {
+			clipped = append(clipped, span)
+		}
+	}
+	spans = clipped
+
+	var prevEnd time.Duration
+	for _, span := range spans {
+		completion.Cued += span.EndAt - span.StartAt
+
+		if gap := span.StartAt - prevEnd; gap > completion.LargestGap {
+			completion.LargestGap = gap
+			completion.GapStartAt = prevEnd
+		}
+		prevEnd = span.EndAt
+	}
+
+	if gap := duration - prevEnd; gap > completion.LargestGap {
+		completion.LargestGap = gap
+		completion.GapStartAt = prevEnd
+	}
+
+	if duration > 0 {
+		completion.Coverage = float64(completion.Cued) / float64(duration)
+	}
+
+	if source != nil {
+		completion.Translated = translatedRatio(source, spans)
+	}
+
+	return completion
+}
+
+// mergeSpans 는 겹치는 자막을 합쳐서 시간순으로 돌려준다
+func mergeSpans(spans []SpeechSegment) []SpeechSegment {
+	sort.Slice(spans, func(i, j int) bool {
+		return spans[i].StartAt < spans[j].StartAt
+	})
+
+	var merged []SpeechSegment
+	for _, span := range spans {
+		if last := len(merged) - 1; last >= 0 && span.StartAt <= merged[last].EndAt {
+			if span.EndAt > merged[last].EndAt {
+				merged[last].EndAt = span.EndAt
+			}
+			continue
+		}
+
+		merged = append(merged, span)
+	}
+
+	return merged
+}
+
+// translatedRatio 는 내용이 있는 원문 자막 중 번역 자막과 시간이 겹치는 비율
+func translatedRatio(source *astisub.Subtitles, spans []SpeechSegment) float64 {
+	var total, translated int
+
+	for _, item := range source.Items {
+		if len(CueText(item)) == 0 {
+			continue
+		}
+		total++
+
+		i := sort.Search(len(spans), func(i int) bool {
+			return spans[i].EndAt > item.StartAt
+		})
+		if i < len(spans) && spans[i].StartAt < item.EndAt {
+			translated++
+		}
+	}
+
+	if total == 0 {
+		return -1
+	}
+
+	return float64(translated) / float64(total)
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/asticode/go-astisub"
+)
+
+func completionAPI(t *testing.T, platform, id, source string) (*httptest.ResponseRecorder, CompletionJSON) {
+	t.Helper()
+
+	form := url.Values{"call": {"completion"}, "platform": {platform}, "id": {id}, "source": {source}}
+	r := httptest.NewRequest("POST", "/api", strings.NewReader(form.Encode()))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w := httptest.NewRecorder()
+
+	API(w, r)
+
+	var result CompletionJSON
+	if err := json.Unmarshal(w.Body.Bytes(), &result); err != nil {
+		t.Fatalf("response is not JSON: %q", w.Body)
+	}
+
+	return w, result
+}
+
+const completionEnSRT = "1\n00:00:01,000 --> 00:00:02,000\nHello\n\n2\n00:00:03,000 --> 00:00:04,000\nNice to meet you\n"
+
+// 보는 사람의 요청은 계산만 하고 completion.json 은 저장할 때만 쓴다
+func TestCompletionAPIReadOnly(t *testing.T) {
+	setupAPI(t)
+
+	for lang, srt := range map[string]string{"en": completionEnSRT, "ko": fuzzSRT} {
+		if err := SubtitleStore.WriteFile([]byte(srt), "youtube", proxyTestID, lang+".srt"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	w, result := completionAPI(t, "youtube", proxyTestID, "ko")
+	if w.Code != http.StatusOK || result.SourceLang != "ko" || len(result.Languages) != 2 {
+		t.Fatalf("completion = %d %s", w.Code, w.Body)
+	}
+
+	if result.Languages["ko"].Translated != -1 || result.Languages["en"].Translated != 1 {
+		t.Errorf("translated = ko %v, en %v", result.Languages["ko"].Translated, result.Languages["en"].Translated)
+	}
+
+	if _, err := LoadCompletion("youtube", proxyTestID); err == nil {
+		t.Fatal("completion.json was written by a read")
+	}
+
+	// 저장하면서 남긴 원문 언어는 다른 원문으로 본 뒤에도 그대로다
+	if _, err := UpdateCompletion("youtube", proxyTestID, "en"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, result := completionAPI(t, "youtube", proxyTestID, "ko"); result.SourceLang != "ko" {
+		t.Errorf("sourceLang = %q, want ko", result.SourceLang)
+	}
+
+	stored, err := LoadCompletion("youtube", proxyTestID)
+	if err != nil || stored.SourceLang != "en" {
+		t.Errorf("stored sourceLang = %v, %v, want en", stored, err)
+	}
+
+	if _, result := completionAPI(t, "youtube", proxyTestID, ""); result.SourceLang != "en" {
+		t.Errorf("sourceLang without source = %q, want en", result.SourceLang)
+	}
+}
+
+func TestCompletionAPINoSubtitles(t *testing.T) {
+	setupAPI(t)
+
+	w, result := completionAPI(t, "youtube", proxyTestID, "ko")
+	if w.Code != http.StatusNotFound || result.Code != 804 {
+		t.Errorf("completion without subtitles = %d %s, want 404 code 804", w.Code, w.Body)
+	}
+
+	path, err := SubtitleStore.Path("youtube", proxyTestID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("video directory was created: %v", err)
+	}
+
+	if _, err := UpdateCompletion("youtube", proxyTestID, ""); err != ErrNoSubtitles {
+		t.Errorf("UpdateCompletion without subtitles = %v", err)
+	}
+}
+
+func TestComputeCompletion(t *testing.T) {
+	cue := func(start, end time.Duration, text string) *astisub.Item {
+		return &astisub.Item{StartAt: start, EndAt: end, Lines: []astisub.Line{{Items: []astisub.LineItem{{Text: text}}}}}
+	}
+
+	subs := astisub.NewSubtitles()
+	subs.Items = []*astisub.Item{
+		cue(1*time.Second, 3*time.Second, "하나"),
+		cue(2*time.Second, 4*time.Second, "겹침"),
+		cue(5*time.Second, 6*time.Second, ""),
+		// 영상이 끝난 뒤의 자막은 잘라낸다
+		cue(9*time.Second, 12*time.Second, "끝"),
+		cue(15*time.Second, 16*time.Second, "밖"),
+	}
+
+	source := astisub.NewSubtitles()
+	source.Items = []*astisub.Item{
+		cue(1500*time.Millisecond, 2*time.Second, "one"),
+		cue(6*time.Second, 7*time.Second, "two"),
+	}
+
+	completion := ComputeCompletion(subs, source, 10*time.Second)
+
+	if completion.Cues != 5 || completion.EmptyCues != 1 {
+		t.Errorf("cues = %d, empty = %d", completion.Cues, completion.EmptyCues)
+	}
+
+	if completion.Cued != 4*time.Second || completion.Coverage != 0.4 {
+		t.Errorf("cued = %s, coverage = %v, want 4s and 0.4", completion.Cued, completion.Coverage)
+	}
+
+	if completion.LargestGap != 5*time.Second || completion.GapStartAt != 4*time.Second {
+		t.Errorf("largest gap = %s at %s, want 5s at 4s", completion.LargestGap, completion.GapStartAt)
+	}
+
+	if completion.Translated != 0.5 {
+		t.Errorf("translated = %v, want 0.5", completion.Translated)
+	}
+
+	// 길이를 모르면 마지막 자막이 끝나는 시간까지로 본다
+	if completion := ComputeCompletion(subs, nil, 0); completion.Duration != 16*time.Second || completion.Translated != -1 {
+		t.Errorf("duration = %s, translated = %v", completion.Duration, completion.Translated)
+	}
+}
@@ -0,0 +1,209 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/asticode/go-astisub"
+)
+
+// GlossaryTerm 은 원문 용어 하나를 어떻게 번역해야 하는지 정한다
+type GlossaryTerm struct {
+	Source    string   `json:"source"`
+	Target    string   `json:"target"`
+	Forbidden []string `json:"forbidden"`
+}
+
+type Glossary struct {
+	Project string         `json:"project"`
+	Terms   []GlossaryTerm `json:"terms"`
+}
+
+type GlossaryJSON struct {
+	Project string         `json:"project"`
+	Terms   []GlossaryTerm `json:"terms"`
+	Code    int            `json:"code"`
+}
+
+// LintIssue 는 Index 번째(0부터) 자막에서 찾은 용어 문제
+// Rule 이 forbidden 이면 Found 에 쓰면 안 되는 표현이, missing 이면 원문에 있는 용어가 들어있다
+type LintIssue struct {
+	Index   int    `json:"index"`
+	Rule    string `json:"rule"`
+	Term    string `json:"term"`
+	Target  string `json:"target"`
+	Found   string `json:"found"`
+	Message string `json:"message"`
+}
+
+type LintJSON struct {
+	Project string      `json:"project"`
+	Issues  []LintIssue `json:"issues"`
+	Code    int         `json:"code"`
+}
+
+var glossaryMu sync.Mutex
+
+func ValidateProject(project string) error {
+	if !mediaIDRegx.MatchString(project) {
+		return fmt.Errorf("invalid project: %s", project)
+	}
+
+	return nil
+}
+
+// LoadGlossary 는 아직 용어집이 없는 프로젝트면 빈 용어집을 돌려준다
+func LoadGlossary(project string) (*Glossary, error) {
+	glossary := &Glossary{
+		Project: project,
+		Terms:   []GlossaryTerm{},
+	}
+
+	file, err := GlossaryStore.ReadFile(project + ".json")
+	if os.IsNotExist(err) {
+		return glossary, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(file, glossary); err != nil {
+		return nil, err
+	}
+
+	return glossary, nil
+}
+
+func storeGlossary(glossary *Glossary) error {
+	sort.Slice(glossary.Terms, func(i, j int) bool {
+		return strings.ToLower(glossary.Terms[i].Source) < strings.ToLower(glossary.Terms[j].Source)
+	})
+
+	file, err := json.Marshal(glossary)
+	if err != nil {
+		return err
+	}
+
+	return GlossaryStore.WriteFile(file, glossary.Project+".json")
+}
+
+// SetGlossaryTerm 은 원문 용어가 같은(대소문자 무시) 항목이 있으면 바꾸고 없으면 추가한다
+func SetGlossaryTerm(project string, term GlossaryTerm) (*Glossary, error) {
+	term.Source = strings.TrimSpace(term.Source)
+	term.Target = strings.TrimSpace(term.Target)
+
+	if len(term.Source) == 0 || len(term.Target) == 0 {
+		return nil, fmt.Errorf("source and target are required")
+	}
+
+	var forbidden []string
+	for _, variant := range term.Forbidden {
+		if variant = strings.TrimSpace(variant); len(variant) != 0 && !strings.EqualFold(variant, term.Target) {
+			forbidden = append(forbidden, variant)
+		}
+	}
+	term.Forbidden = forbidden
+
+	glossaryMu.Lock()
+	defer glossaryMu.Unlock()
+
+	glossary, err := LoadGlossary(project)
+	if err != nil {
+		return nil, err
+	}
+
+	replaced := false
+	for i := range glossary.Terms {
+		if strings.EqualFold(glossary.Terms[i].Source, term.Source) {
+			glossary.Terms[i] = term
+			replaced = true
+			break
+		}
+	}
+
+	if !replaced {
+		glossary.Terms = append(glossary.Terms, term)
+	}
+
+	if err := storeGlossary(glossary); err != nil {
+		return nil, err
+	}
+
+	return glossary, nil
+}
+
+func DeleteGlossaryTerm(project, source string) (*Glossary, error) {
+	glossaryMu.Lock()
+	defer glossaryMu.Unlock()
+
+	glossary, err := LoadGlossary(project)
+	if err != nil {
+		return nil, err
+	}
+
+	terms := glossary.Terms[:0]
+	for _, term := range glossary.Terms {
+		if !strings.EqualFold(term.Source, source) {
+			terms = append(terms, term)
+		}
+	}
+	glossary.Terms = terms
+
+	if err := storeGlossary(glossary); err != nil {
+		return nil, err
+	}
+
+	return glossary, nil
+}
+
+// LintSubtitles 는 번역 자막마다 쓰면 안 되는 표현이 있는지,
+// 겹치는 원문 자막에 용어가 있으면 정해진 번역을 썼는지 확인한다 (source 가 nil 이면 앞의 것만)
+func LintSubtitles(glossary *Glossary, subs, source *astisub.Subtitles) []LintIssue {
+	issues := []LintIssue{}
+
+	for i, item := range subs.Items {
+		text := strings.ToLower(CueText(item))
+		if len(text) == 0 {
+			continue
+		}
+
+		var sourceText string
+		if source != nil {
+			if s := BestOverlap(source.Items, item.StartAt, item.EndAt); s != nil {
+				sourceText = strings.ToLower(CueText(s))
+			}
+		}
+
+		for _, term := range glossary.Terms {
+			for _, variant := range term.Forbidden {
+				if strings.Contains(text, strings.ToLower(variant)) {
+					issues = append(issues, LintIssue{
+						Index:   i,
+						Rule:    "forbidden",
+						Term:    term.Source,
+						Target:  term.Target,
+						Found:   variant,
+						Message: fmt.Sprintf("'%s' 대신 '%s' 을(를) 써주세요", variant, term.Target),
+					})
+				}
+			}
+
+			if len(sourceText) != 0 && strings.Contains(sourceText, strings.ToLower(term.Source)) && !strings.Contains(text, strings.ToLower(term.Target)) {
+				issues = append(issues, LintIssue{
+					Index:   i,
+					Rule:    "missing",
+					Term:    term.Source,
+					Target:  term.Target,
+					Found:   term.Source,
+					Message: fmt.Sprintf("원문의 '%s' 은(는) '%s' (으)로 번역해주세요", term.Source, term.Target),
+				})
+			}
+		}
+	}
+
+	return issues
+}
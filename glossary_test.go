@@ -0,0 +1,144 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestSetGlossaryTerm(t *testing.T) {
+	setupAPI(t)
+
+	glossary, err := LoadGlossary("series")
+	if err != nil || glossary.Project != "series" || glossary.Terms == nil || len(glossary.Terms) != 0 {
+		t.Fatalf("empty glossary = %+v, %v", glossary, err)
+	}
+
+	invalid := []GlossaryTerm{
+		{Source: "", Target: "용사"},
+		{Source: "Hero", Target: ""},
+		{Source: "   ", Target: "용사"},
+		{Source: "Hero", Target: " \t"},
+	}
+
+	for _, term := range invalid {
+		if _, err := SetGlossaryTerm("series", term); err == nil {
+			t.Errorf("SetGlossaryTerm(%+v) succeeded", term)
+		}
+	}
+
+	if _, err := SetGlossaryTerm("series", GlossaryTerm{Source: " Hero ", Target: " 용사 ", Forbidden: []string{"영웅", " ", "용사", " 히어로 "}}); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := SetGlossaryTerm("series", GlossaryTerm{Source: "Demon King", Target: "마왕"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// 원문이 같으면 (대소문자 무시) 바꾼다
+	if _, err := SetGlossaryTerm("series", GlossaryTerm{Source: "demon king", Target: "마왕님"}); err != nil {
+		t.Fatal(err)
+	}
+
+	glossary, err = LoadGlossary("series")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []GlossaryTerm{
+		{Source: "demon king", Target: "마왕님"},
+		{Source: "Hero", Target: "용사", Forbidden: []string{"영웅", "히어로"}},
+	}
+
+	if !reflect.DeepEqual(glossary.Terms, want) {
+		t.Errorf("terms = %+v, want %+v", glossary.Terms, want)
+	}
+
+	// 다른 프로젝트의 용어집과 섞이지 않는다
+	if other, err := LoadGlossary("other"); err != nil || len(other.Terms) != 0 {
+		t.Errorf("other project = %+v, %v", other, err)
+	}
+
+	if glossary, err = DeleteGlossaryTerm("series", "HERO"); err != nil {
+		t.Fatal(err)
+	}
+
+	if glossary, err = LoadGlossary("series"); err != nil || len(glossary.Terms) != 1 || glossary.Terms[0].Target != "마왕님" {
+		t.Errorf("terms after delete = %+v, %v", glossary.Terms, err)
+	}
+}
+
+func TestValidateProject(t *testing.T) {
+	for _, project := range []string{"series", "jNQXAC9IVRw", "a-b_c"} {
+		if err := ValidateProject(project); err != nil {
+			t.Errorf("ValidateProject(%q) = %v", project, err)
+		}
+	}
+
+	for _, project := range []string{"", "..", "a/b", "a b", "../etc"} {
+		if err := ValidateProject(project); err == nil {
+			t.Errorf("ValidateProject(%q) succeeded", project)
+		}
+	}
+}
+
+func TestLintSubtitles(t *testing.T) {
+	s := time.Second
+
+	glossary := &Glossary{Terms: []GlossaryTerm{
+		{Source: "Hero", Target: "용사", Forbidden: []string{"영웅"}},
+		{Source: "Demon King", Target: "마왕", Forbidden: []string{"Devil"}},
+	}}
+
+	source := memorySubs(
+		memoryCue(1*s, 2*s, "The HERO arrives"),
+		memoryCue(3*s, 4*s, "the demon king laughs"),
+		memoryCue(5*s, 6*s, "Nothing here"),
+		memoryCue(7*s, 8*s, "hero"),
+	)
+
+	subs := memorySubs(
+		// 용사를 썼으므로 문제없다
+		memoryCue(1*s, 2*s, "용사가 왔다"),
+		// 마왕 대신 다른 말을 썼다
+		memoryCue(3*s, 4*s, "DEVIL 이 웃는다"),
+		// 원문에 용어가 없어도 쓰면 안 되는 표현은 찾는다
+		memoryCue(5*s, 6*s, "영웅은 없다"),
+		memoryCue(7*s, 8*s, ""),
+	)
+
+	type issue struct {
+		index      int
+		rule, term string
+		found      string
+	}
+
+	var got []issue
+	for _, i := range LintSubtitles(glossary, subs, source) {
+		got = append(got, issue{i.Index, i.Rule, i.Term, i.Found})
+	}
+
+	want := []issue{
+		{1, "forbidden", "Demon King", "Devil"},
+		{1, "missing", "Demon King", "Demon King"},
+		{2, "forbidden", "Hero", "영웅"},
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("issues = %+v, want %+v", got, want)
+	}
+
+	// 원문이 없으면 쓰면 안 되는 표현만 본다
+	got = nil
+	for _, i := range LintSubtitles(glossary, subs, nil) {
+		got = append(got, issue{i.Index, i.Rule, i.Term, i.Found})
+	}
+
+	if !reflect.DeepEqual(got, []issue{want[0], want[2]}) {
+		t.Errorf("issues without source = %+v", got)
+	}
+
+	if issues := LintSubtitles(&Glossary{}, subs, source); issues == nil || len(issues) != 0 {
+		t.Errorf("issues with an empty glossary = %#v", issues)
+	}
+}
@@ -1,9 +1,24 @@
 module app
 
-go 1.15
+go 1.21
 
 require (
+	github.com/asticode/go-astisub v0.8.0
 	github.com/go-sql-driver/mysql v1.5.0
+	github.com/kkdai/youtube/v2 v2.10.1
 	github.com/maxence-charriere/go-app/v7 v7.0.5
 	github.com/rs/cors v1.7.0
+	golang.org/x/crypto v0.21.0
+	golang.org/x/text v0.14.0
+)
+
+require (
+	github.com/asticode/go-astikit v0.8.0 // indirect
+	github.com/asticode/go-astits v1.4.0 // indirect
+	github.com/bitly/go-simplejson v0.5.1 // indirect
+	github.com/dlclark/regexp2 v1.11.4 // indirect
+	github.com/dop251/goja v0.0.0-20241024094426-79f3a7efcdbd // indirect
+	github.com/go-sourcemap/sourcemap v2.1.3+incompatible // indirect
+	github.com/google/pprof v0.0.0-20240409012703-83162a5b38cd // indirect
+	golang.org/x/net v0.22.0 // indirect
 )
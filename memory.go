@@ -0,0 +1,364 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/asticode/go-astisub"
+)
+
+const (
+	// 이보다 덜 비슷하면 추천하지 않는다
+	MemoryMinScore = 0.5
+	memoryMaxLimit = 20
+
+	// 추천을 찾을 때 보는 원문 길이 (글자), 자막 한 줄은 보통 이보다 훨씬 짧다
+	MemoryMaxQueryRunes = 300
+
+	// 원문과 번역 자막이 둘 중 짧은 쪽 길이의 이 비율 이상 겹쳐야 짝짓는다
+	MemoryMinOverlap = 0.5
+)
+
+// TranslationMemory 는 같은 영상의 언어별 자막을 시간으로 맞춰 (원문, 번역) 쌍을 만들고 비슷한 원문을 찾는다
+type TranslationMemory struct {
+	mu     sync.RWMutex
+	videos map[string]*memoryVideo
+	ready  bool
+}
+
+type memoryVideo struct {
+	pairs    []memoryPair
+	postings map[string][]int
+}
+
+type memoryPair struct {
+	platform   string
+	id         string
+	sourceLang string
+	targetLang string
+	startAt    time.Duration
+	source     string
+	target     string
+	lower      []rune
+}
+
+type MemoryMatch struct {
+	Platform string        `json:"platform"`
+	ID       string        `json:"id"`
+	StartAt  time.Duration `json:"startAt"`
+	Source   string        `json:"source"`
+	Target   string        `json:"target"`
+	Score    float64       `json:"score"`
+}
+
+type MemoryJSON struct {
+	Matches []MemoryMatch `json:"matches"`
+	Code    int           `json:"code"`
+}
+
+var Memory = NewTranslationMemory()
+
+func NewTranslationMemory() *TranslationMemory {
+	return &TranslationMemory{
+		videos: make(map[string]*memoryVideo),
+	}
+}
+
+// Update 는 영상 하나의 모든 언어 자막을 다시 읽어서 쌍을 새로 만든다
+func (m *TranslationMemory) Update(platform, id string) error {
+	files, err := subtitleFiles(platform, id)
+	if err != nil {
+		return err
+	}
+
+	tracks := make(map[string]*astisub.Subtitles)
+	for _, file := range files {
+		lang := strings.TrimSuffix(filepath.Base(file), ".srt")
+
+		subs, err := ReadSubtitle(platform, id, lang)
+		if err != nil {
+			fmt.Println(err)
+			continue
+		}
+
+		tracks[lang] = subs
+	}
+
+	video := &memoryVideo{
+		postings: make(map[string][]int),
+	}
+
+	for sourceLang, source := range tracks {
+		for targetLang, target := range tracks {
+			if sourceLang == targetLang {
+				continue
+			}
+
+			for _, pair := range AlignCues(source, target) {
+				pair.platform = platform
+				pair.id = id
+				pair.sourceLang = sourceLang
+				pair.targetLang = targetLang
+
+				for _, token := range uniqueTokens(pair.source) {
+					video.postings[token] = append(video.postings[token], len(video.pairs))
+				}
+				video.pairs = append(video.pairs, pair)
+			}
+		}
+	}
+
+	m.mu.Lock()
+	m.videos[platform+"/"+id] = video
+	m.mu.Unlock()
+
+	return nil
+}
+
+// Build 는 자막이 두 언어 이상 있는 영상을 모두 읽는다
+func (m *TranslationMemory) Build() {
+	started := time.Now()
+
+	var count int
+
+	dirs, _ := filepath.Glob(SubtitleStore.Root + "/*/*")
+	for _, dir := range dirs {
+		if files, _ := filepath.Glob(dir + "/*.srt"); len(files) < 2 {
+			continue
+		}
+
+		rel, _ := filepath.Rel(SubtitleStore.Root, dir)
+		parts := strings.Split(rel, string(filepath.Separator))
+
+		if err := m.Update(parts[0], parts[1]); err != nil {
+			fmt.Println(err)
+			continue
+		}
+		count++
+	}
+
+	m.mu.Lock()
+	m.ready = true
+	m.mu.Unlock()
+
+	fmt.Printf("번역 메모리 완료: %d개 영상 (%s)\n", count, time.Since(started))
+}
+
+func (m *TranslationMemory) Ready() bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.ready
+}
+
+// AlignCues 는 번역 자막마다 가장 많이 겹치는 원문 자막을 찾아, 원문 자막 하나에 들어간 번역 자막들을 시간순으로 합쳐 짝짓는다
+// 겹치는 시간이 둘 중 짧은 자막의 MemoryMinOverlap 보다 적으면 우연히 닿은 것으로 보고 짝짓지 않는다
+func AlignCues(source, target *astisub.Subtitles) []memoryPair {
+	assigned := make([][]*astisub.Item, len(source.Items))
+
+	for _, t := range target.Items {
+		if len(CueText(t)) == 0 {
+			continue
+		}
+
+		best, bestOverlap := -1, time.Duration(0)
+		for i, s := range source.Items {
+			if overlap := cueOverlap(s, t); overlap > bestOverlap && len(CueText(s)) != 0 {
+				best, bestOverlap = i, overlap
+			}
+		}
+
+		if best < 0 {
+			continue
+		}
+
+		shorter := t.EndAt - t.StartAt
+		if d := source.Items[best].EndAt - source.Items[best].StartAt; d < shorter {
+			shorter = d
+		}
+
+		if float64(bestOverlap) < MemoryMinOverlap*float64(shorter) {
+			continue
+		}
+
+		assigned[best] = append(assigned[best], t)
+	}
+
+	var pairs []memoryPair
+	for i, s := range source.Items {
+		if len(assigned[i]) == 0 {
+			continue
+		}
+
+		targets := assigned[i]
+		sort.SliceStable(targets, func(a, b int) bool {
+			return targets[a].StartAt < targets[b].StartAt
+		})
+
+		texts := make([]string, len(targets))
+		for j, t := range targets {
+			texts[j] = CueText(t)
+		}
+
+		sourceText := CueText(s)
+		pairs = append(pairs, memoryPair{
+			startAt: s.StartAt,
+			source:  sourceText,
+			target:  strings.Join(texts, " "),
+			lower:   []rune(normalizeText(sourceText)),
+		})
+	}
+
+	return pairs
+}
+
+// cueOverlap 은 두 자막이 겹치는 시간, 겹치지 않으면 0
+func cueOverlap(a, b *astisub.Item) time.Duration {
+	start, end := a.StartAt, a.EndAt
+	if b.StartAt > start {
+		start = b.StartAt
+	}
+	if b.EndAt < end {
+		end = b.EndAt
+	}
+
+	if end < start {
+		return 0
+	}
+
+	return end - start
+}
+
+func uniqueTokens(text string) []string {
+	seen := make(map[string]bool)
+
+	var tokens []string
+	for _, token := range Tokenize(text) {
+		if !seen[token] {
+			seen[token] = true
+			tokens = append(tokens, token)
+		}
+	}
+
+	return tokens
+}
+
+// Suggest 는 토큰이 하나라도 겹치는 원문 중에서 편집 거리로 비슷한 순서대로 돌려준다, 같은 번역은 한 번만 나온다
+// 편집 거리는 길이의 곱만큼 걸리므로 원문은 MemoryMaxQueryRunes 글자까지만 본다
+func (m *TranslationMemory) Suggest(source, sourceLang, targetLang string, limit int) []MemoryMatch {
+	query := []rune(normalizeText(strings.TrimSpace(source)))
+	if len(query) > MemoryMaxQueryRunes {
+		query = query[:MemoryMaxQueryRunes]
+		source = string(query)
+	}
+	tokens := uniqueTokens(source)
+
+	matches := []MemoryMatch{}
+	if len(tokens) == 0 {
+		return matches
+	}
+
+	m.mu.RLock()
+	for _, video := range m.videos {
+		checked := make(map[int]bool)
+
+		for _, token := range tokens {
+			for _, i := range video.postings[token] {
+				if checked[i] {
+					continue
+				}
+				checked[i] = true
+
+				pair := &video.pairs[i]
+				if pair.sourceLang != sourceLang || pair.targetLang != targetLang {
+					continue
+				}
+
+				score := Similarity(query, pair.lower)
+				if score < MemoryMinScore {
+					continue
+				}
+
+				matches = append(matches, MemoryMatch{
+					Platform: pair.platform,
+					ID:       pair.id,
+					StartAt:  pair.startAt,
+					Source:   pair.source,
+					Target:   pair.target,
+					Score:    score,
+				})
+			}
+		}
+	}
+	m.mu.RUnlock()
+
+	sort.SliceStable(matches, func(i, j int) bool {
+		return matches[i].Score > matches[j].Score
+	})
+
+	seen := make(map[string]bool)
+	unique := matches[:0]
+	for _, match := range matches {
+		if seen[match.Target] {
+			continue
+		}
+		seen[match.Target] = true
+
+		unique = append(unique, match)
+		if len(unique) == limit {
+			break
+		}
+	}
+
+	return unique
+}
+
+// Similarity 는 1 - (편집 거리 / 긴 쪽 길이)
+func Similarity(a, b []rune) float64 {
+	if len(a) == 0 && len(b) == 0 {
+		return 1
+	}
+
+	longest := len(a)
+	if len(b) > longest {
+		longest = len(b)
+	}
+
+	return 1 - float64(levenshtein(a, b))/float64(longest)
+}
+
+func levenshtein(a, b []rune) int {
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+
+			curr[j] = curr[j-1] + 1
+			if prev[j]+1 < curr[j] {
+				curr[j] = prev[j] + 1
+			}
+			if prev[j-1]+cost < curr[j] {
+				curr[j] = prev[j-1] + cost
+			}
+		}
+
+		prev, curr = curr, prev
+	}
+
+	return prev[len(b)]
+}
@@ -0,0 +1,201 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/asticode/go-astisub"
+)
+
+func memoryCue(start, end time.Duration, text string) *astisub.Item {
+	return &astisub.Item{StartAt: start, EndAt: end, Lines: []astisub.Line{{Items: []astisub.LineItem{{Text: text}}}}}
+}
+
+func memorySubs(items ...*astisub.Item) *astisub.Subtitles {
+	subs := astisub.NewSubtitles()
+	subs.Items = items
+
+	return subs
+}
+
+func TestAlignCues(t *testing.T) {
+	s := time.Second
+	ms := time.Millisecond
+
+	tests := []struct {
+		name           string
+		source, target *astisub.Subtitles
+		want           [][2]string
+	}{
+		{
+			"same timing",
+			memorySubs(memoryCue(1*s, 2*s, "Hello"), memoryCue(3*s, 4*s, "Bye")),
+			memorySubs(memoryCue(1*s, 2*s, "안녕"), memoryCue(3*s, 4*s, "잘 가")),
+			[][2]string{{"Hello", "안녕"}, {"Bye", "잘 가"}},
+		},
+		{
+			"split translation is merged in time order",
+			memorySubs(memoryCue(1*s, 5*s, "Nice to meet you, my name is Kim")),
+			memorySubs(memoryCue(3*s, 5*s, "김입니다"), memoryCue(1*s, 3*s, "반갑습니다")),
+			[][2]string{{"Nice to meet you, my name is Kim", "반갑습니다 김입니다"}},
+		},
+		{
+			// 짧은 쪽 (번역 1초) 의 절반보다 적게 겹친다
+			"touching cues are not paired",
+			memorySubs(memoryCue(1*s, 2*s, "Hello")),
+			memorySubs(memoryCue(1900*ms, 2900*ms, "다음")),
+			nil,
+		},
+		{
+			"overlap at the threshold",
+			memorySubs(memoryCue(1*s, 2*s, "Hello")),
+			memorySubs(memoryCue(1500*ms, 2500*ms, "안녕")),
+			[][2]string{{"Hello", "안녕"}},
+		},
+		{
+			// 원문 두 개에 걸친 번역은 더 많이 겹치는 쪽에만 들어간다
+			"translation spanning two cues",
+			memorySubs(memoryCue(1*s, 2*s, "Good"), memoryCue(2*s, 4*s, "morning")),
+			memorySubs(memoryCue(1500*ms, 4*s, "좋은 아침")),
+			[][2]string{{"morning", "좋은 아침"}},
+		},
+		{
+			"empty cues are skipped",
+			memorySubs(memoryCue(1*s, 2*s, ""), memoryCue(3*s, 4*s, "Bye")),
+			memorySubs(memoryCue(1*s, 2*s, "안녕"), memoryCue(3*s, 4*s, "")),
+			nil,
+		},
+	}
+
+	for _, test := range tests {
+		var got [][2]string
+		for _, pair := range AlignCues(test.source, test.target) {
+			got = append(got, [2]string{pair.source, pair.target})
+		}
+
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: AlignCues = %q, want %q", test.name, got, test.want)
+		}
+	}
+}
+
+func TestSimilarity(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want float64
+	}{
+		{"", "", 1},
+		{"abc", "abc", 1},
+		{"abc", "", 0},
+		{"kitten", "sitting", 1 - 3.0/7},
+		{"안녕하세요", "안녕하셔요", 0.8},
+	}
+
+	for _, test := range tests {
+		if got := Similarity([]rune(test.a), []rune(test.b)); got != test.want {
+			t.Errorf("Similarity(%q, %q) = %v, want %v", test.a, test.b, got, test.want)
+		}
+	}
+}
+
+// setupMemory 는 빈 번역 메모리를 Memory 로 쓰고, 영상마다 언어별 자막을 저장해서 색인한다
+func setupMemory(t *testing.T, videos map[string]map[string]string) {
+	t.Helper()
+	setupAPI(t)
+
+	previous := Memory
+	Memory = NewTranslationMemory()
+	t.Cleanup(func() { Memory = previous })
+
+	for id, tracks := range videos {
+		for lang, srt := range tracks {
+			if err := SubtitleStore.WriteFile([]byte(srt), "youtube", id, lang+".srt"); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	Memory.Build()
+}
+
+func TestSuggest(t *testing.T) {
+	setupMemory(t, map[string]map[string]string{
+		"aaaaaaaaaaa": {
+			"en": "1\n00:00:01,000 --> 00:00:02,000\nThank you very much\n\n2\n00:00:03,000 --> 00:00:04,000\nSee you next time\n",
+			"ko": "1\n00:00:01,000 --> 00:00:02,000\n정말 감사합니다\n\n2\n00:00:03,000 --> 00:00:04,000\n다음에 또 만나요\n",
+		},
+		"bbbbbbbbbbb": {
+			"en": "1\n00:00:01,000 --> 00:00:02,000\nThank you so much\n\n2\n00:00:05,000 --> 00:00:06,000\nThank you very much\n",
+			"ko": "1\n00:00:01,000 --> 00:00:02,000\n너무 고마워요\n\n2\n00:00:05,000 --> 00:00:06,000\n정말 감사합니다\n",
+		},
+		// 한 언어만 있는 영상은 짝이 없다
+		"ccccccccccc": {
+			"en": "1\n00:00:01,000 --> 00:00:02,000\nThank you very much\n",
+		},
+	})
+
+	if !Memory.Ready() {
+		t.Fatal("memory is not ready after Build")
+	}
+
+	matches := Memory.Suggest("thank you very much!", "en", "ko", 10)
+
+	// 같은 번역은 한 번만, 점수가 높은 순서로
+	var targets []string
+	for _, match := range matches {
+		targets = append(targets, match.Target)
+	}
+
+	if !reflect.DeepEqual(targets, []string{"정말 감사합니다", "너무 고마워요"}) {
+		t.Fatalf("targets = %q", targets)
+	}
+
+	if matches[0].Score != 1-1.0/20 || matches[0].Source != "Thank you very much" {
+		t.Errorf("best match = %+v", matches[0])
+	}
+
+	if matches[1].Score < MemoryMinScore || matches[1].Score >= matches[0].Score {
+		t.Errorf("second match = %+v", matches[1])
+	}
+
+	if matches := Memory.Suggest("thank you very much", "en", "ko", 1); len(matches) != 1 {
+		t.Errorf("limit 1 = %d matches", len(matches))
+	}
+
+	// 반대 방향은 따로 찾는다
+	if matches := Memory.Suggest("정말 감사합니다", "ko", "en", 10); len(matches) != 1 || matches[0].Target != "Thank you very much" {
+		t.Errorf("ko -> en = %+v", matches)
+	}
+
+	if matches := Memory.Suggest("thank you very much", "en", "ja", 10); len(matches) != 0 {
+		t.Errorf("en -> ja = %+v", matches)
+	}
+
+	// 토큰은 겹쳐도 MemoryMinScore 보다 덜 비슷하면 뺀다
+	if matches := Memory.Suggest("thank you for watching this video today", "en", "ko", 10); len(matches) != 0 {
+		t.Errorf("dissimilar matches = %+v", matches)
+	}
+
+	if matches := Memory.Suggest("  !!! ", "en", "ko", 10); matches == nil || len(matches) != 0 {
+		t.Errorf("punctuation only = %#v", matches)
+	}
+}
+
+// 긴 원문은 MemoryMaxQueryRunes 글자까지만 보고 찾는다
+func TestSuggestQueryCap(t *testing.T) {
+	long := strings.Repeat("a", MemoryMaxQueryRunes)
+
+	setupMemory(t, map[string]map[string]string{
+		"aaaaaaaaaaa": {
+			"en": "1\n00:00:01,000 --> 00:00:02,000\n" + long + "\n",
+			"ko": "1\n00:00:01,000 --> 00:00:02,000\n가\n",
+		},
+	})
+
+	matches := Memory.Suggest(strings.Repeat("a", MemoryMaxQueryRunes*10), "en", "ko", 10)
+	if len(matches) != 1 || matches[0].Score != 1 {
+		t.Errorf("matches for a capped query = %+v", matches)
+	}
+}
@@ -0,0 +1,329 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+type VideoInfo struct {
+	Platform   string           `json:"platform"`
+	ID         string           `json:"id"`
+	Metadata   *VideoMetadata   `json:"metadata"`
+	Languages  []string         `json:"languages"`
+	EditedAt   time.Time        `json:"editedAt"`
+	Revisions  int              `json:"revisions"`
+	LastEditor string           `json:"lastEditor"`
+	Completion *VideoCompletion `json:"completion"`
+}
+
+type VideosJSON struct {
+	Videos []VideoInfo `json:"videos"`
+	Total  int         `json:"total"`
+	Code   int         `json:"code"`
+}
+
+// HistorySummary 는 저장할 때마다 history.json 에 남기는 저장 횟수와 마지막으로 저장한 사람, 목록을 만들 때 기록 DB 에 묻지 않으려고 둔다
+type HistorySummary struct {
+	Revisions  int    `json:"revisions"`
+	LastEditor string `json:"lastEditor"`
+}
+
+var (
+	metadataMu       sync.Mutex
+	historySummaryMu sync.Mutex
+)
+
+func LoadMetadata(platform, id string) (*VideoMetadata, error) {
+	file, err := SubtitleStore.ReadFile(platform, id, "metadata.json")
+	if err != nil {
+		return nil, err
+	}
+
+	var metadata VideoMetadata
+	if err := json.Unmarshal(file, &metadata); err != nil {
+		return nil, err
+	}
+
+	return &metadata, nil
+}
+
+// GetMetadata 는 저장해 둔 영상 정보를 먼저 쓰고, 없으면 처음 가져온 정보를 자막 옆에 저장한다
+func GetMetadata(platform, id string) (*VideoMetadata, error) {
+	if metadata, err := LoadMetadata(platform, id); err == nil {
+		return metadata, nil
+	}
+
+	fetched, err := Streams.Metadata(platform, id)
+	if err != nil {
+		return nil, err
+	}
+
+	metadataMu.Lock()
+	defer metadataMu.Unlock()
+
+	// 기다리는 사이 다른 요청이 먼저 저장했으면 그걸 쓴다
+	if metadata, err := LoadMetadata(platform, id); err == nil {
+		return metadata, nil
+	}
+
+	metadata := *fetched
+	metadata.FirstSeen = time.Now()
+
+	if err := StoreMetadata(platform, id, &metadata); err != nil {
+		return nil, err
+	}
+
+	return &metadata, nil
+}
+
+func StoreMetadata(platform, id string, metadata *VideoMetadata) error {
+	file, err := json.Marshal(metadata)
+	if err != nil {
+		return err
+	}
+
+	return SubtitleStore.WriteFile(file, platform, id, "metadata.json")
+}
+
+func LoadHistorySummary(platform, id string) (*HistorySummary, error) {
+	file, err := SubtitleStore.ReadFile(platform, id, "history.json")
+	if err != nil {
+		return nil, err
+	}
+
+	var summary HistorySummary
+	if err := json.Unmarshal(file, &summary); err != nil {
+		return nil, err
+	}
+
+	return &summary, nil
+}
+
+// UpdateHistorySummary 는 기록 DB 에서 다시 세어 history.json 에 저장한다, 기록을 읽지 못했으면 (0번) 저장하지 않는다
+func UpdateHistorySummary(platform, id string) (*HistorySummary, error) {
+	historySummaryMu.Lock()
+	defer historySummaryMu.Unlock()
+
+	summary := &HistorySummary{}
+	summary.Revisions, summary.LastEditor = GetHistory(id)
+
+	if summary.Revisions == 0 {
+		return summary, fmt.Errorf("no history: %s/%s", platform, id)
+	}
+
+	file, err := json.Marshal(summary)
+	if err != nil {
+		return nil, err
+	}
+
+	return summary, SubtitleStore.WriteFile(file, platform, id, "history.json")
+}
+
+// SetProject 는 영상을 용어집을 같이 쓰는 프로젝트(시리즈)에 묶는다
+func SetProject(platform, id, project string) error {
+	metadata, err := GetMetadata(platform, id)
+	if err != nil {
+		return err
+	}
+
+	metadataMu.Lock()
+	defer metadataMu.Unlock()
+
+	metadata.Project = project
+
+	return StoreMetadata(platform, id, metadata)
+}
+
+// ListVideos 는 자막이 하나라도 있는 영상을 마지막으로 수정한 순서대로 돌려준다
+func ListVideos(platform string) ([]VideoInfo, error) {
+	var platforms []string
+	if len(platform) != 0 {
+		platforms = []string{platform}
+	} else {
+		for name := range Sources {
+			platforms = append(platforms, name)
+		}
+	}
+
+	videos := []VideoInfo{}
+	for _, platform := range platforms {
+		dir, err := SubtitleStore.Path(platform)
+		if err != nil {
+			return nil, err
+		}
+
+		dirs, err := ioutil.ReadDir(dir)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		for _, dir := range dirs {
+			if !dir.IsDir() {
+				continue
+			}
+
+			info, err := videoInfo(platform, dir.Name())
+			if err != nil {
+				fmt.Println(err)
+				continue
+			}
+
+			if len(info.Languages) != 0 {
+				videos = append(videos, *info)
+			}
+		}
+	}
+
+	sort.SliceStable(videos, func(i, j int) bool {
+		return videos[i].EditedAt.After(videos[j].EditedAt)
+	})
+
+	return videos, nil
+}
+
+func videoInfo(platform, id string) (*VideoInfo, error) {
+	dir, err := SubtitleStore.Path(platform, id)
+	if err != nil {
+		return nil, err
+	}
+
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	info := &VideoInfo{
+		Platform:  platform,
+		ID:        id,
+		Languages: []string{},
+	}
+
+	for _, file := range files {
+		if file.IsDir() || filepath.Ext(file.Name()) != ".srt" {
+			continue
+		}
+
+		info.Languages = append(info.Languages, strings.TrimSuffix(file.Name(), ".srt"))
+
+		if file.ModTime().After(info.EditedAt) {
+			info.EditedAt = file.ModTime()
+		}
+	}
+
+	if len(info.Languages) == 0 {
+		return info, nil
+	}
+
+	if metadata, err := LoadMetadata(platform, id); err == nil {
+		info.Metadata = metadata
+	}
+
+	if completion, err := LoadCompletion(platform, id); err == nil {
+		info.Completion = completion
+	}
+
+	// history.json 이 생기기 전에 저장한 영상은 한 번만 DB 에서 세어 남긴다
+	summary, err := LoadHistorySummary(platform, id)
+	if err != nil {
+		if summary, err = UpdateHistorySummary(platform, id); err != nil {
+			fmt.Println(err)
+		}
+	}
+
+	if summary != nil {
+		info.Revisions, info.LastEditor = summary.Revisions, summary.LastEditor
+	}
+
+	return info, nil
+}
+
+// SearchVideos 는 ID, 제목, 채널에 query 가 들어간 영상만 남긴다 (대소문자 무시)
+func SearchVideos(videos []VideoInfo, query string) []VideoInfo {
+	query = strings.ToLower(strings.TrimSpace(query))
+	if len(query) == 0 {
+		return videos
+	}
+
+	found := []VideoInfo{}
+	for _, video := range videos {
+		fields := []string{video.ID}
+		if video.Metadata != nil {
+			fields = append(fields, video.Metadata.Title, video.Metadata.Channel)
+		}
+
+		for _, field := range fields {
+			if strings.Contains(strings.ToLower(field), query) {
+				found = append(found, video)
+				break
+			}
+		}
+	}
+
+	return found
+}
+
+// SortVideos 는 edited(기본), title, revisions, languages 순으로 정렬한다
+func SortVideos(videos []VideoInfo, sortBy string) error {
+	var less func(a, b VideoInfo) bool
+
+	switch sortBy {
+	case "", "edited":
+		less = func(a, b VideoInfo) bool {
+			return a.EditedAt.After(b.EditedAt)
+		}
+	case "title":
+		less = func(a, b VideoInfo) bool {
+			return strings.ToLower(videoTitle(a)) < strings.ToLower(videoTitle(b))
+		}
+	case "revisions":
+		less = func(a, b VideoInfo) bool {
+			return a.Revisions > b.Revisions
+		}
+	case "languages":
+		less = func(a, b VideoInfo) bool {
+			return len(a.Languages) > len(b.Languages)
+		}
+	default:
+		return fmt.Errorf("unknown sort: %s", sortBy)
+	}
+
+	sort.SliceStable(videos, func(i, j int) bool {
+		return less(videos[i], videos[j])
+	})
+
+	return nil
+}
+
+func videoTitle(video VideoInfo) string {
+	if video.Metadata != nil && len(video.Metadata.Title) != 0 {
+		return video.Metadata.Title
+	}
+
+	return video.ID
+}
+
+// MaskIP 는 목록에 공개되는 IP 의 마지막 자리 (IPv6 는 48비트 뒤) 를 가린다
+func MaskIP(ip string) string {
+	// IPv6 는 앞의 48비트 (보통 한 기관에 주는 범위) 만 남긴다
+	if parsed := net.ParseIP(ip); parsed != nil && parsed.To4() == nil {
+		return parsed.Mask(net.CIDRMask(48, 128)).String() + "*"
+	}
+
+	i := strings.LastIndex(ip, ".")
+	if i < 0 {
+		return ip
+	}
+
+	return ip[:i] + ".*"
+}
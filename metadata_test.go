@@ -0,0 +1,215 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// writeVideo 는 영상 폴더에 자막 파일과 (있으면) 영상 정보, 저장 기록 요약을 만든다
+func writeVideo(t *testing.T, platform, id string, editedAt time.Time, langs []string, metadata *VideoMetadata, summary *HistorySummary) {
+	t.Helper()
+
+	for _, lang := range langs {
+		if err := SubtitleStore.WriteFile([]byte(fuzzSRT), platform, id, lang+".srt"); err != nil {
+			t.Fatal(err)
+		}
+
+		path, err := SubtitlePath(platform, id, lang)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if err := os.Chtimes(path, editedAt, editedAt); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if metadata != nil {
+		if err := StoreMetadata(platform, id, metadata); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if summary != nil {
+		file, err := json.Marshal(summary)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if err := SubtitleStore.WriteFile(file, platform, id, "history.json"); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func videoIDs(videos []VideoInfo) []string {
+	ids := []string{}
+	for _, video := range videos {
+		ids = append(ids, video.ID)
+	}
+
+	return ids
+}
+
+func TestListVideos(t *testing.T) {
+	setupAPI(t)
+
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	writeVideo(t, "youtube", "aaaaaaaaaaa", base, []string{"ko"}, &VideoMetadata{Title: "Zoo"}, &HistorySummary{Revisions: 3, LastEditor: "kim"})
+	writeVideo(t, "youtube", "bbbbbbbbbbb", base.Add(2*time.Hour), []string{"ko", "en"}, nil, &HistorySummary{Revisions: 1, LastEditor: "1.2.3.*"})
+	writeVideo(t, "local", "clip", base.Add(time.Hour), []string{"ja"}, nil, &HistorySummary{Revisions: 7})
+
+	// 자막이 없는 폴더는 목록에 넣지 않는다
+	if _, err := SubtitleStore.MkdirAll("youtube", "ccccccccccc", "version"); err != nil {
+		t.Fatal(err)
+	}
+
+	historyStub.reset()
+
+	videos, err := ListVideos("")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if ids := videoIDs(videos); !reflect.DeepEqual(ids, []string{"bbbbbbbbbbb", "clip", "aaaaaaaaaaa"}) {
+		t.Fatalf("videos = %v, want newest first", ids)
+	}
+
+	first := videos[0]
+	if first.Platform != "youtube" || !first.EditedAt.Equal(base.Add(2*time.Hour)) || len(first.Languages) != 2 || first.Revisions != 1 || first.LastEditor != "1.2.3.*" {
+		t.Errorf("video = %+v", first)
+	}
+
+	if last := videos[2]; last.Metadata == nil || last.Metadata.Title != "Zoo" || last.Revisions != 3 || last.LastEditor != "kim" {
+		t.Errorf("video = %+v", last)
+	}
+
+	// 저장 횟수는 history.json 에서 읽으므로 목록을 만드는 동안 기록 DB 에 묻지 않는다
+	if queries := historyStub.reset(); len(queries) != 0 {
+		t.Errorf("history queries while listing: %v", queries)
+	}
+
+	videos, err = ListVideos("local")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if ids := videoIDs(videos); !reflect.DeepEqual(ids, []string{"clip"}) {
+		t.Errorf("local videos = %v", ids)
+	}
+}
+
+// history.json 이 없는 예전 영상은 기록 DB 에서 센다, 기록을 읽지 못했으면 남기지 않는다
+func TestListVideosWithoutSummary(t *testing.T) {
+	setupAPI(t)
+
+	writeVideo(t, "youtube", "aaaaaaaaaaa", time.Now(), []string{"ko"}, nil, nil)
+
+	videos, err := ListVideos("youtube")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(videos) != 1 || videos[0].Revisions != 0 {
+		t.Fatalf("videos = %+v", videos)
+	}
+
+	if queries := historyStub.reset(); len(queries) == 0 {
+		t.Error("history was not queried for a video without history.json")
+	}
+
+	if _, err := LoadHistorySummary("youtube", "aaaaaaaaaaa"); err == nil {
+		t.Error("empty history was stored")
+	}
+}
+
+func TestSearchVideos(t *testing.T) {
+	videos := []VideoInfo{
+		{ID: "jNQXAC9IVRw", Metadata: &VideoMetadata{Title: "Me at the zoo", Channel: "jawed"}},
+		{ID: "aaaaaaaaaaa", Metadata: &VideoMetadata{Title: "강아지 영상", Channel: "동물원"}},
+		{ID: "clip"},
+	}
+
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"", []string{"jNQXAC9IVRw", "aaaaaaaaaaa", "clip"}},
+		{"   ", []string{"jNQXAC9IVRw", "aaaaaaaaaaa", "clip"}},
+		{"ZOO", []string{"jNQXAC9IVRw"}},
+		{"jnqx", []string{"jNQXAC9IVRw"}},
+		{"Jawed", []string{"jNQXAC9IVRw"}},
+		{"동물", []string{"aaaaaaaaaaa"}},
+		{"clip", []string{"clip"}},
+		{"missing", []string{}},
+	}
+
+	for _, test := range tests {
+		if got := videoIDs(SearchVideos(videos, test.query)); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("SearchVideos(%q) = %v, want %v", test.query, got, test.want)
+		}
+	}
+}
+
+func TestSortVideos(t *testing.T) {
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	videos := func() []VideoInfo {
+		return []VideoInfo{
+			{ID: "b", EditedAt: base, Revisions: 5, Languages: []string{"ko"}, Metadata: &VideoMetadata{Title: "banana"}},
+			{ID: "a", EditedAt: base.Add(time.Hour), Revisions: 1, Languages: []string{"ko", "en", "ja"}},
+			{ID: "c", EditedAt: base.Add(-time.Hour), Revisions: 5, Languages: []string{"ko", "en"}, Metadata: &VideoMetadata{Title: "Apple"}},
+		}
+	}
+
+	tests := []struct {
+		sortBy string
+		want   []string
+	}{
+		{"", []string{"a", "b", "c"}},
+		{"edited", []string{"a", "b", "c"}},
+		// 제목이 없으면 ID 로, 대소문자는 가리지 않는다
+		{"title", []string{"a", "c", "b"}},
+		// 같으면 원래 순서를 지킨다
+		{"revisions", []string{"b", "c", "a"}},
+		{"languages", []string{"a", "c", "b"}},
+	}
+
+	for _, test := range tests {
+		list := videos()
+		if err := SortVideos(list, test.sortBy); err != nil {
+			t.Errorf("SortVideos(%q): %v", test.sortBy, err)
+			continue
+		}
+
+		if got := videoIDs(list); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("SortVideos(%q) = %v, want %v", test.sortBy, got, test.want)
+		}
+	}
+
+	if err := SortVideos(videos(), "random"); err == nil {
+		t.Error("unknown sort was accepted")
+	}
+}
+
+func TestMaskIP(t *testing.T) {
+	tests := []struct {
+		ip, want string
+	}{
+		{"203.0.113.7", "203.0.113.*"},
+		{"127.0.0.1", "127.0.0.*"},
+		{"2001:db8:1234:5678::1", "2001:db8:1234::*"},
+		{"2001:db8::1", "2001:db8::*"},
+		{"::ffff:203.0.113.7", "::ffff:203.0.113.*"},
+		{"", ""},
+		{"garbage", "garbage"},
+	}
+
+	for _, test := range tests {
+		if got := MaskIP(test.ip); got != test.want {
+			t.Errorf("MaskIP(%q) = %q, want %q", test.ip, got, test.want)
+		}
+	}
+}
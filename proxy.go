@@ -0,0 +1,438 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var ProxyConfigPath = filepath.Join(DataDir, "proxy.json")
+
+const (
+	// 클라이언트 하나가 받을 수 있는 최대 속도 (동시에 여러 요청을 보내도 합쳐서 계산)
+	ProxyBytesPerSecond = 2 << 20
+
+	proxyBufferSize = 32 << 10
+)
+
+// 프록시가 받아올 수 있는 호스트, 점으로 시작하면 하위 도메인까지 허용한다
+// proxy.json 의 allowHosts 를 더하고, http 플랫폼 영상은 MediaDir/http/<id>.url 에 적힌 호스트를 허용한다
+var ProxyAllowHosts = []string{
+	".googlevideo.com",
+}
+
+// 앞에 둔 리버스 프록시 주소, 여기서 온 요청만 X-Forwarded-For, X-Real-IP 를 믿는다
+var defaultTrustedProxies = []string{
+	"127.0.0.0/8",
+	"::1",
+}
+
+// ProxyConfig 는 {"trustedProxies": ["127.0.0.1", "10.0.0.0/8"], "allowHosts": [".example.com"]} 형식
+// trustedProxies 가 없으면 로컬 주소만 믿고, allowHosts 는 ProxyAllowHosts 에 더한다
+type ProxyConfig struct {
+	TrustedProxies []string `json:"trustedProxies"`
+	AllowHosts     []string `json:"allowHosts"`
+}
+
+func LoadProxyConfig() ProxyConfig {
+	config := ProxyConfig{}
+
+	file, err := ioutil.ReadFile(ProxyConfigPath)
+	if err == nil {
+		if err := json.Unmarshal(file, &config); err != nil {
+			fmt.Println(err)
+		}
+	}
+
+	if len(config.TrustedProxies) == 0 {
+		config.TrustedProxies = defaultTrustedProxies
+	}
+
+	return config
+}
+
+// Trusted 는 trustedProxies 에 주소나 CIDR 로 적혀 있으면 true
+func (c ProxyConfig) Trusted(addr string) bool {
+	ip := net.ParseIP(addr)
+	if ip == nil {
+		return false
+	}
+
+	for _, trusted := range c.TrustedProxies {
+		if _, network, err := net.ParseCIDR(trusted); err == nil {
+			if network.Contains(ip) {
+				return true
+			}
+
+			continue
+		}
+
+		if proxy := net.ParseIP(trusted); proxy != nil && proxy.Equal(ip) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// 업스트림 응답에서 그대로 넘겨주는 헤더
+var proxyHeaders = []string{
+	"Accept-Ranges",
+	"Content-Length",
+	"Content-Range",
+	"Content-Type",
+	"ETag",
+	"Last-Modified",
+}
+
+// MediaProxy 는 영상을 같은 출처에서 받을 수 있게 업스트림이나 로컬 파일을 Range 요청 그대로 전달한다
+type MediaProxy struct {
+	Streams        *StreamCache
+	AllowHosts     []string
+	BytesPerSecond int
+	Client         *http.Client
+	Local          *Storage
+
+	mu       sync.Mutex
+	limiters map[string]*byteLimiter
+}
+
+func NewMediaProxy(streams *StreamCache) *MediaProxy {
+	m := &MediaProxy{
+		Streams:        streams,
+		AllowHosts:     ProxyAllowHosts,
+		BytesPerSecond: ProxyBytesPerSecond,
+		Local:          &Storage{Root: filepath.Join(MediaStore.Root, "local")},
+		limiters:       make(map[string]*byteLimiter),
+	}
+
+	m.Client = &http.Client{}
+
+	return m
+}
+
+// Allowed 는 AllowHosts 와 proxy.json 의 allowHosts 를 확인한다, extra 는 요청 하나에만 더 허용하는 호스트
+func (m *MediaProxy) Allowed(u *url.URL, extra ...string) bool {
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return false
+	}
+
+	host := strings.ToLower(u.Hostname())
+	for _, hosts := range [][]string{m.AllowHosts, LoadProxyConfig().AllowHosts, extra} {
+		for _, allow := range hosts {
+			allow = strings.ToLower(allow)
+
+			if strings.HasPrefix(allow, ".") && strings.HasSuffix(host, allow) {
+				return true
+			}
+
+			if host == allow {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// ClientAddr 는 믿을 수 있는 프록시를 거쳐 왔을 때만 전달된 주소를 쓴다
+// X-Forwarded-For 는 클라이언트가 앞쪽을 마음대로 채울 수 있으므로 오른쪽부터 프록시가 아닌 첫 주소를 쓰고,
+// X-Forwarded-For 가 없으면 프록시가 넣은 X-Real-IP 를 쓴다
+func ClientAddr(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+
+	config := LoadProxyConfig()
+	if !config.Trusted(host) {
+		return host
+	}
+
+	if forwarded := r.Header.Values("X-Forwarded-For"); len(forwarded) != 0 {
+		addrs := strings.Split(strings.Join(forwarded, ","), ",")
+
+		for i := len(addrs) - 1; i >= 0; i-- {
+			addr := strings.TrimSpace(addrs[i])
+			if net.ParseIP(addr) == nil {
+				// 알 수 없는 값 앞은 믿을 수 없으므로 마지막으로 확인한 프록시 주소를 쓴다
+				return host
+			}
+
+			if !config.Trusted(addr) {
+				return addr
+			}
+
+			host = addr
+		}
+
+		return host
+	}
+
+	if real := strings.TrimSpace(r.Header.Get("X-Real-IP")); net.ParseIP(real) != nil {
+		return real
+	}
+
+	return host
+}
+
+func (m *MediaProxy) limiter(client string) *byteLimiter {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	limiter := m.limiters[client]
+	if limiter == nil {
+		limiter = newByteLimiter(m.BytesPerSecond)
+		m.limiters[client] = limiter
+	}
+
+	limiter.users++
+
+	return limiter
+}
+
+func (m *MediaProxy) release(client string, limiter *byteLimiter) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	limiter.users--
+	if limiter.users == 0 {
+		delete(m.limiters, client)
+	}
+}
+
+// ServeHTTP 는 /media/proxy?platform=&id=&itag= 요청을 처리한다
+func (m *MediaProxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" && r.Method != "HEAD" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	platform := r.URL.Query().Get("platform")
+	id := r.URL.Query().Get("id")
+	itag, _ := strconv.Atoi(r.URL.Query().Get("itag"))
+
+	if _, err := GetSource(platform, id); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// 영상을 보는 요청은 로그인하지 않아도 되므로 인증 오류는 무시하고 이름만 쓴다
+	client := ClientAddr(r)
+	user, _ := Authenticate(r)
+
+	limits := LoadRateLimitConfig()
+	if limits.DeniedIP(client) || limits.DeniedUser(user) {
+		http.Error(w, "Access denied", http.StatusForbidden)
+		return
+	}
+
+	// 캐시에 없는 영상은 밖으로 스트림 주소를 받으러 가므로 api 의 video 호출과 같은 버킷에서 센다
+	if platform != "local" && !m.Streams.Cached(platform, id, itag) {
+		if ok, wait := APILimiter.Allow(RateLimitClient(r, user), "video", limits.Limit("video")); !ok {
+			w.Header().Set("Retry-After", strconv.Itoa(RetryAfterSeconds(wait)))
+			http.Error(w, "Too many requests", http.StatusTooManyRequests)
+			return
+		}
+	}
+
+	limiter := m.limiter(client)
+	defer m.release(client, limiter)
+
+	tw := &throttledWriter{ResponseWriter: w, limiter: limiter}
+
+	if platform == "local" {
+		m.serveLocal(tw, r, id)
+		return
+	}
+
+	streams, err := m.Streams.Resolve(platform, id, itag)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+
+	resp, err := m.fetch(r, platform, streams[0].URL)
+	if err == nil && resp.StatusCode == http.StatusForbidden {
+		// 주소가 만료됐거나 다른 IP 에 묶였으면 한 번만 다시 받아온다
+		resp.Body.Close()
+		m.Streams.Invalidate(platform, id)
+
+		if streams, err = m.Streams.Resolve(platform, id, itag); err == nil {
+			resp, err = m.fetch(r, platform, streams[0].URL)
+		}
+	}
+
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	defer resp.Body.Close()
+
+	for _, header := range proxyHeaders {
+		if value := resp.Header.Get(header); len(value) != 0 {
+			w.Header().Set(header, value)
+		}
+	}
+
+	w.WriteHeader(resp.StatusCode)
+
+	if r.Method == "HEAD" {
+		return
+	}
+
+	if _, err := io.CopyBuffer(tw, resp.Body, make([]byte, proxyBufferSize)); err != nil {
+		fmt.Println(err)
+	}
+}
+
+// fetch 는 허용한 호스트로만 요청하고, 리다이렉트도 허용한 호스트로만 따라간다
+func (m *MediaProxy) fetch(r *http.Request, platform, streamURL string) (*http.Response, error) {
+	u, err := url.Parse(streamURL)
+	if err != nil {
+		return nil, err
+	}
+
+	// http 플랫폼 영상은 관리자가 MediaDir/http/<id>.url 에 적은 주소이므로 그 호스트는 허용한다
+	var extra []string
+	if platform == "http" {
+		extra = append(extra, u.Hostname())
+	}
+
+	if !m.Allowed(u, extra...) {
+		return nil, fmt.Errorf("upstream %s is not allowed", u.Host)
+	}
+
+	client := *m.Client
+	client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		if len(via) >= 10 {
+			return fmt.Errorf("stopped after %d redirects", len(via))
+		}
+
+		if !m.Allowed(req.URL, extra...) {
+			return fmt.Errorf("redirect to %s is not allowed", req.URL.Host)
+		}
+
+		return nil
+	}
+
+	req, err := http.NewRequestWithContext(r.Context(), r.Method, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, header := range []string{"Range", "If-Range"} {
+		if value := r.Header.Get(header); len(value) != 0 {
+			req.Header.Set(header, value)
+		}
+	}
+
+	return client.Do(req)
+}
+
+func (m *MediaProxy) serveLocal(w http.ResponseWriter, r *http.Request, id string) {
+	name, err := localSource{}.file(id)
+	if err != nil {
+		http.Error(w, "Not found", 404)
+		return
+	}
+
+	path, err := m.Local.Path(name)
+	if err != nil {
+		http.Error(w, "Not found", 404)
+		return
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		http.Error(w, "Not found", 404)
+		return
+	}
+	defer file.Close()
+
+	stat, err := file.Stat()
+	if err != nil {
+		http.Error(w, "Not found", 404)
+		return
+	}
+
+	w.Header().Set("Content-Type", mimeTypeByExt(filepath.Ext(name)))
+	http.ServeContent(w, r, name, stat.ModTime(), file)
+}
+
+// byteLimiter 는 초당 rate 바이트까지 쓸 수 있는 토큰 버킷
+type byteLimiter struct {
+	mu     sync.Mutex
+	rate   float64
+	tokens float64
+	last   time.Time
+	users  int
+}
+
+func newByteLimiter(rate int) *byteLimiter {
+	return &byteLimiter{
+		rate:   float64(rate),
+		tokens: float64(rate),
+		last:   time.Now(),
+	}
+}
+
+// wait 는 n 바이트를 쓸 수 있을 때까지 기다린다
+func (l *byteLimiter) wait(n int) {
+	if l.rate <= 0 {
+		return
+	}
+
+	l.mu.Lock()
+	now := time.Now()
+	l.tokens += now.Sub(l.last).Seconds() * l.rate
+	if l.tokens > l.rate {
+		l.tokens = l.rate
+	}
+	l.last = now
+	l.tokens -= float64(n)
+	debt := -l.tokens
+	l.mu.Unlock()
+
+	if debt > 0 {
+		time.Sleep(time.Duration(debt / l.rate * float64(time.Second)))
+	}
+}
+
+type throttledWriter struct {
+	http.ResponseWriter
+	limiter *byteLimiter
+}
+
+func (w *throttledWriter) Write(b []byte) (int, error) {
+	var written int
+
+	for len(b) != 0 {
+		chunk := b
+		if len(chunk) > proxyBufferSize {
+			chunk = chunk[:proxyBufferSize]
+		}
+
+		w.limiter.wait(len(chunk))
+
+		n, err := w.ResponseWriter.Write(chunk)
+		written += n
+		if err != nil {
+			return written, err
+		}
+
+		b = b[len(chunk):]
+	}
+
+	return written, nil
+}
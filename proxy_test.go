@@ -0,0 +1,295 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+const proxyTestID = "jNQXAC9IVRw"
+
+// newTestUpstream 은 Range 요청을 처리하는 업스트림, 요청 수를 센다
+func newTestUpstream(t *testing.T, body []byte) (*httptest.Server, *int32) {
+	t.Helper()
+
+	var hits int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		w.Header().Set("Content-Type", "video/mp4")
+		http.ServeContent(w, r, "video.mp4", time.Time{}, bytes.NewReader(body))
+	}))
+	t.Cleanup(server.Close)
+
+	return server, &hits
+}
+
+// newTestProxy 는 youtube 스트림 주소를 upstream 으로 미리 넣어둔 프록시
+func newTestProxy(t *testing.T, upstream string) *MediaProxy {
+	t.Helper()
+
+	streams := NewStreamCache()
+	streams.entries["youtube/"+proxyTestID] = &streamEntry{
+		streams: []Stream{{URL: upstream + "/videoplayback?itag=18", Itag: 18, HasAudio: true, HasVideo: true}},
+		expires: []time.Time{time.Now().Add(time.Hour)},
+	}
+
+	proxy := NewMediaProxy(streams)
+	proxy.AllowHosts = []string{"127.0.0.1"}
+
+	return proxy
+}
+
+func proxyGet(proxy http.Handler, query string, header http.Header) *httptest.ResponseRecorder {
+	r := httptest.NewRequest("GET", "/media/proxy?"+query, nil)
+	for key, values := range header {
+		r.Header[key] = values
+	}
+
+	w := httptest.NewRecorder()
+	proxy.ServeHTTP(w, r)
+
+	return w
+}
+
+func testBody(n int) []byte {
+	body := make([]byte, n)
+	for i := range body {
+		body[i] = byte(i % 251)
+	}
+
+	return body
+}
+
+func TestMediaProxyRange(t *testing.T) {
+	body := testBody(1000)
+	upstream, _ := newTestUpstream(t, body)
+	proxy := newTestProxy(t, upstream.URL)
+
+	w := proxyGet(proxy, "platform=youtube&id="+proxyTestID, http.Header{"Range": {"bytes=100-199"}})
+
+	if w.Code != http.StatusPartialContent {
+		t.Fatalf("status = %d, want 206: %s", w.Code, w.Body)
+	}
+
+	if got := w.Header().Get("Content-Range"); got != "bytes 100-199/1000" {
+		t.Errorf("Content-Range = %q", got)
+	}
+
+	if got := w.Header().Get("Content-Type"); got != "video/mp4" {
+		t.Errorf("Content-Type = %q", got)
+	}
+
+	if !bytes.Equal(w.Body.Bytes(), body[100:200]) {
+		t.Errorf("body = %d bytes, want bytes 100-199", w.Body.Len())
+	}
+}
+
+func TestMediaProxyRefusedHost(t *testing.T) {
+	upstream, hits := newTestUpstream(t, testBody(10))
+	proxy := newTestProxy(t, upstream.URL)
+	proxy.AllowHosts = []string{".googlevideo.com"}
+
+	w := proxyGet(proxy, "platform=youtube&id="+proxyTestID, nil)
+
+	if w.Code != http.StatusBadGateway {
+		t.Errorf("status = %d, want 502", w.Code)
+	}
+
+	if n := atomic.LoadInt32(hits); n != 0 {
+		t.Errorf("upstream was requested %d times", n)
+	}
+}
+
+func TestMediaProxyRefusedRedirect(t *testing.T) {
+	target, hits := newTestUpstream(t, testBody(10))
+
+	// 127.0.0.1 만 허용하고 리다이렉트는 같은 서버를 localhost 로 보낸다
+	u, _ := url.Parse(target.URL)
+	redirect := httptest.NewServer(http.RedirectHandler("http://localhost:"+u.Port()+"/video.mp4", http.StatusFound))
+	t.Cleanup(redirect.Close)
+
+	proxy := newTestProxy(t, redirect.URL)
+
+	w := proxyGet(proxy, "platform=youtube&id="+proxyTestID, nil)
+
+	if w.Code != http.StatusBadGateway {
+		t.Errorf("status = %d, want 502", w.Code)
+	}
+
+	if n := atomic.LoadInt32(hits); n != 0 {
+		t.Errorf("redirect target was requested %d times", n)
+	}
+}
+
+// http 플랫폼은 MediaDir/http/<id>.url 에 적힌 호스트를 AllowHosts 에 없어도 받아온다
+func TestMediaProxyHTTPPlatform(t *testing.T) {
+	body := testBody(100)
+	upstream, _ := newTestUpstream(t, body)
+
+	root := t.TempDir()
+	defer func(previous string) { MediaStore.Root = previous }(MediaStore.Root)
+	MediaStore.Root = root
+
+	if err := os.MkdirAll(filepath.Join(root, "http"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.WriteFile(filepath.Join(root, "http", "clip.url"), []byte(upstream.URL+"/clip.mp4\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	proxy := NewMediaProxy(NewStreamCache())
+
+	w := proxyGet(proxy, "platform=http&id=clip", nil)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200: %s", w.Code, w.Body)
+	}
+
+	if !bytes.Equal(w.Body.Bytes(), body) {
+		t.Errorf("body = %d bytes, want %d", w.Body.Len(), len(body))
+	}
+}
+
+func TestMediaProxyThrottle(t *testing.T) {
+	const rate = 20000
+
+	body := testBody(rate * 3 / 2)
+	upstream, _ := newTestUpstream(t, body)
+	proxy := newTestProxy(t, upstream.URL)
+	proxy.BytesPerSecond = rate
+
+	server := httptest.NewServer(proxy)
+	t.Cleanup(server.Close)
+
+	// 처음 1초 분량은 바로 보내고 나머지 0.5초 분량은 기다렸다 보낸다
+	start := time.Now()
+
+	resp, err := http.Get(server.URL + "/media/proxy?platform=youtube&id=" + proxyTestID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	received, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	elapsed := time.Since(start)
+
+	if !bytes.Equal(received, body) {
+		t.Fatalf("body = %d bytes, want %d", len(received), len(body))
+	}
+
+	if elapsed < 400*time.Millisecond {
+		t.Errorf("elapsed = %s, want throttled to about 500ms", elapsed)
+	}
+}
+
+func TestClientAddr(t *testing.T) {
+	tests := []struct {
+		remote  string
+		header  http.Header
+		want    string
+		comment string
+	}{
+		{"203.0.113.7:5000", nil, "203.0.113.7", "direct"},
+		{"203.0.113.7:5000", http.Header{"X-Forwarded-For": {"198.51.100.1"}}, "203.0.113.7", "untrusted proxy"},
+		{"127.0.0.1:5000", http.Header{"X-Forwarded-For": {"198.51.100.1"}}, "198.51.100.1", "trusted proxy"},
+		{"127.0.0.1:5000", http.Header{"X-Forwarded-For": {"1.1.1.1, 198.51.100.1"}}, "198.51.100.1", "spoofed first entry"},
+		{"127.0.0.1:5000", http.Header{"X-Forwarded-For": {"1.1.1.1", "198.51.100.1, 127.0.0.1"}}, "198.51.100.1", "chained proxies"},
+		{"127.0.0.1:5000", http.Header{"X-Forwarded-For": {"garbage, 198.51.100.1"}}, "198.51.100.1", "garbage before client"},
+		{"127.0.0.1:5000", http.Header{"X-Forwarded-For": {"198.51.100.1, garbage"}}, "127.0.0.1", "garbage after client"},
+		{"127.0.0.1:5000", http.Header{"X-Real-Ip": {"2001:db8::1"}}, "2001:db8::1", "real ip"},
+		{"[::1]:5000", http.Header{"X-Forwarded-For": {"2001:db8::2"}}, "2001:db8::2", "ipv6 proxy"},
+		{"127.0.0.1:5000", nil, "127.0.0.1", "local"},
+	}
+
+	for _, test := range tests {
+		r := httptest.NewRequest("GET", "/", nil)
+		r.RemoteAddr = test.remote
+		r.Header = test.header
+		if r.Header == nil {
+			r.Header = http.Header{}
+		}
+
+		if got := ClientAddr(r); got != test.want {
+			t.Errorf("%s: ClientAddr = %q, want %q", test.comment, got, test.want)
+		}
+	}
+}
+
+// 캐시에 없는 영상은 video 버킷에서 세고, 캐시에 있는 영상을 이어 받는 요청은 세지 않는다
+func TestMediaProxyRateLimit(t *testing.T) {
+	setupAPI(t)
+
+	clock := time.Now()
+	APILimiter = NewRateLimiter()
+	APILimiter.now = func() time.Time { return clock }
+
+	upstream, _ := newTestUpstream(t, testBody(10))
+	proxy := newTestProxy(t, upstream.URL)
+
+	burst := int(LoadRateLimitConfig().Limit("video").Burst)
+	for i := 0; i < burst; i++ {
+		// stubSource 는 스트림을 돌려주지 않으므로 502
+		if w := proxyGet(proxy, "platform=youtube&id=aaaaaaaaaaa", nil); w.Code != http.StatusBadGateway {
+			t.Fatalf("request %d: status = %d, want 502", i+1, w.Code)
+		}
+	}
+
+	w := proxyGet(proxy, "platform=youtube&id=bbbbbbbbbbb", nil)
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("status = %d, want 429", w.Code)
+	}
+
+	if w.Header().Get("Retry-After") == "" {
+		t.Error("429 without Retry-After")
+	}
+
+	if w := proxyGet(proxy, "platform=youtube&id="+proxyTestID, nil); w.Code != http.StatusOK {
+		t.Errorf("cached stream: status = %d, want 200", w.Code)
+	}
+
+	// 다른 주소는 따로 센다
+	r := httptest.NewRequest("GET", "/media/proxy?platform=youtube&id=bbbbbbbbbbb", nil)
+	r.RemoteAddr = "198.51.100.1:5000"
+	other := httptest.NewRecorder()
+	proxy.ServeHTTP(other, r)
+
+	if other.Code == http.StatusTooManyRequests {
+		t.Error("another client was limited")
+	}
+}
+
+func TestMediaProxyDenied(t *testing.T) {
+	setupAPI(t)
+
+	upstream, hits := newTestUpstream(t, testBody(10))
+	proxy := newTestProxy(t, upstream.URL)
+
+	path := filepath.Join(t.TempDir(), "ratelimit.json")
+	defer func(previous string) { RateLimitConfigPath = previous }(RateLimitConfigPath)
+	RateLimitConfigPath = path
+
+	if err := os.WriteFile(path, []byte(`{"denyIPs": ["192.0.2.0/24"]}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// httptest.NewRequest 는 192.0.2.1 에서 온다
+	if w := proxyGet(proxy, "platform=youtube&id="+proxyTestID, nil); w.Code != http.StatusForbidden {
+		t.Errorf("status = %d, want 403", w.Code)
+	}
+
+	if n := atomic.LoadInt32(hits); n != 0 {
+		t.Errorf("upstream was requested %d times", n)
+	}
+}
@@ -0,0 +1,246 @@
+package main
+
+import (
+	"encoding/json"
+	"expvar"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"net"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+var RateLimitConfigPath = filepath.Join(DataDir, "ratelimit.json")
+
+const (
+	// 요청 전체 크기, 폼을 읽기 전에 막는다
+	defaultMaxBodyBytes = 8 << 20
+	// subtitle 값 하나의 크기 (save, lint, translate 로 올리는 자막)
+	defaultMaxSubtitleBytes = 2 << 20
+
+	// 이 간격으로 다시 가득 찬 버킷을 지운다
+	rateLimitSweepInterval = time.Minute
+)
+
+var rateLimitStats = expvar.NewMap("rate_limit")
+
+// RateLimit 은 토큰 버킷 하나의 크기, Burst 만큼 한 번에 보낼 수 있고 1분에 PerMinute 개씩 다시 찬다
+type RateLimit struct {
+	PerMinute float64 `json:"perMinute"`
+	Burst     float64 `json:"burst"`
+}
+
+// RateLimitConfig 는 {"limits": {"save": {"perMinute": 10, "burst": 5}}, "denyIPs": ["10.0.0.0/8"], "denyUsers": ["name"]} 형식
+// limits 의 "*" 는 따로 적지 않은 호출에 쓴다, 파일에 적은 호출만 기본값을 덮어쓴다
+type RateLimitConfig struct {
+	Limits           map[string]RateLimit `json:"limits"`
+	DenyIPs          []string             `json:"denyIPs"`
+	DenyUsers        []string             `json:"denyUsers"`
+	MaxBodyBytes     int64                `json:"maxBodyBytes"`
+	MaxSubtitleBytes int                  `json:"maxSubtitleBytes"`
+}
+
+// 밖으로 요청을 보내는 호출 (youtube, video) 과 저장은 낮게 잡는다
+var defaultRateLimits = map[string]RateLimit{
+	"*":         {PerMinute: 120, Burst: 60},
+	"youtube":   {PerMinute: 10, Burst: 5},
+	"video":     {PerMinute: 10, Burst: 5},
+	"save":      {PerMinute: 10, Burst: 5},
+	"translate": {PerMinute: 20, Burst: 10},
+	"vad":       {PerMinute: 10, Burst: 5},
+	"account":   {PerMinute: 20, Burst: 10},
+}
+
+func LoadRateLimitConfig() RateLimitConfig {
+	config := RateLimitConfig{
+		Limits:           make(map[string]RateLimit),
+		MaxBodyBytes:     defaultMaxBodyBytes,
+		MaxSubtitleBytes: defaultMaxSubtitleBytes,
+	}
+
+	file, err := ioutil.ReadFile(RateLimitConfigPath)
+	if err == nil {
+		if err := json.Unmarshal(file, &config); err != nil {
+			fmt.Println(err)
+		}
+	}
+
+	for call, limit := range defaultRateLimits {
+		if _, ok := config.Limits[call]; !ok {
+			config.Limits[call] = limit
+		}
+	}
+
+	if config.MaxBodyBytes <= 0 {
+		config.MaxBodyBytes = defaultMaxBodyBytes
+	}
+
+	if config.MaxSubtitleBytes <= 0 {
+		config.MaxSubtitleBytes = defaultMaxSubtitleBytes
+	}
+
+	return config
+}
+
+// Class 는 호출이 쓰는 제한의 이름, 설정에 없는 호출은 모두 "*" 로 묶는다
+// 버킷도 이 이름으로 나누므로 call 을 아무렇게나 바꿔 보내도 새 버킷을 얻지 못한다
+func (c RateLimitConfig) Class(call string) string {
+	if _, ok := c.Limits[call]; ok {
+		return call
+	}
+
+	return "*"
+}
+
+// Limit 은 호출에 맞는 제한, PerMinute 가 0 이하이면 제한하지 않는다
+func (c RateLimitConfig) Limit(call string) RateLimit {
+	return c.Limits[c.Class(call)]
+}
+
+// DeniedIP 는 denyIPs 에 주소나 CIDR 로 적혀 있으면 true
+func (c RateLimitConfig) DeniedIP(addr string) bool {
+	ip := net.ParseIP(addr)
+	if ip == nil {
+		return false
+	}
+
+	for _, deny := range c.DenyIPs {
+		if _, network, err := net.ParseCIDR(deny); err == nil {
+			if network.Contains(ip) {
+				return true
+			}
+
+			continue
+		}
+
+		if denied := net.ParseIP(deny); denied != nil && denied.Equal(ip) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// DeniedUser 는 대소문자를 가리지 않는다 (가입할 때 이름을 소문자로 확인하는 것과 같다)
+func (c RateLimitConfig) DeniedUser(user *User) bool {
+	if user == nil {
+		return false
+	}
+
+	for _, deny := range c.DenyUsers {
+		if strings.EqualFold(deny, user.Name) {
+			return true
+		}
+	}
+
+	return false
+}
+
+type tokenBucket struct {
+	limit  RateLimit
+	tokens float64
+	last   time.Time
+}
+
+// RateLimiter 는 클라이언트와 호출마다 토큰 버킷을 둔다, now 를 바꾸면 시간을 흘려보내며 확인할 수 있다
+type RateLimiter struct {
+	mu        sync.Mutex
+	buckets   map[string]*tokenBucket
+	lastSweep time.Time
+
+	now func() time.Time
+}
+
+var APILimiter = NewRateLimiter()
+
+func NewRateLimiter() *RateLimiter {
+	return &RateLimiter{
+		buckets: make(map[string]*tokenBucket),
+		now:     time.Now,
+	}
+}
+
+// refill 은 지난 시간만큼 토큰을 채운다, Burst 보다 많이 쌓이지 않는다
+func (b *tokenBucket) refill(now time.Time) {
+	if elapsed := now.Sub(b.last); elapsed > 0 {
+		b.tokens = math.Min(b.limit.Burst, b.tokens+elapsed.Minutes()*b.limit.PerMinute)
+	}
+
+	b.last = now
+}
+
+// Allow 는 토큰 하나를 쓴다, 남은 토큰이 없으면 false 와 다음 토큰이 찰 때까지의 시간을 돌려준다
+func (l *RateLimiter) Allow(client, call string, limit RateLimit) (bool, time.Duration) {
+	if limit.PerMinute <= 0 {
+		return true, 0
+	}
+
+	if limit.Burst < 1 {
+		limit.Burst = 1
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	l.sweep(now)
+
+	key := client + " " + call
+	bucket := l.buckets[key]
+	if bucket == nil {
+		bucket = &tokenBucket{tokens: limit.Burst, last: now}
+		l.buckets[key] = bucket
+	}
+
+	// 설정을 바꾸면 다음 요청부터 새 제한으로 채운다
+	bucket.refill(now)
+	bucket.limit = limit
+	bucket.tokens = math.Min(bucket.tokens, limit.Burst)
+
+	if bucket.tokens >= 1 {
+		bucket.tokens--
+		return true, 0
+	}
+
+	wait := time.Duration((1 - bucket.tokens) / limit.PerMinute * float64(time.Minute))
+	rateLimitStats.Add(call, 1)
+
+	return false, wait
+}
+
+// sweep 은 다시 가득 찬 버킷을 지운다, 지운 버킷은 다음 요청에 가득 찬 채로 다시 만들므로 결과는 같다
+func (l *RateLimiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < rateLimitSweepInterval {
+		return
+	}
+	l.lastSweep = now
+
+	for key, bucket := range l.buckets {
+		if bucket.tokens+now.Sub(bucket.last).Minutes()*bucket.limit.PerMinute >= bucket.limit.Burst {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+// RateLimitClient 는 로그인했으면 이름, 아니면 서버가 본 주소로 버킷을 나눈다
+func RateLimitClient(r *http.Request, user *User) string {
+	if user != nil {
+		return "user:" + user.Name
+	}
+
+	return "ip:" + ClientAddr(r)
+}
+
+// RetryAfterSeconds 는 Retry-After 헤더에 넣을 초, 1초보다 짧으면 1초
+func RetryAfterSeconds(wait time.Duration) int {
+	seconds := int(math.Ceil(wait.Seconds()))
+	if seconds < 1 {
+		return 1
+	}
+
+	return seconds
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRateLimiter(t *testing.T) {
+	clock := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	limiter := NewRateLimiter()
+	limiter.now = func() time.Time { return clock }
+
+	limit := RateLimit{PerMinute: 6, Burst: 3}
+
+	for i := 0; i < 3; i++ {
+		if ok, _ := limiter.Allow("ip:203.0.113.7", "save", limit); !ok {
+			t.Fatalf("request %d within burst was limited", i+1)
+		}
+	}
+
+	// 1분에 6개이므로 다음 토큰은 10초 뒤에 찬다
+	ok, wait := limiter.Allow("ip:203.0.113.7", "save", limit)
+	if ok {
+		t.Fatal("request after burst was allowed")
+	}
+
+	if wait != 10*time.Second || RetryAfterSeconds(wait) != 10 {
+		t.Errorf("wait = %s (Retry-After %d), want 10s", wait, RetryAfterSeconds(wait))
+	}
+
+	// 다른 클라이언트와 다른 호출은 따로 센다
+	if ok, _ := limiter.Allow("ip:198.51.100.1", "save", limit); !ok {
+		t.Error("another client was limited")
+	}
+
+	if ok, _ := limiter.Allow("ip:203.0.113.7", "lint", limit); !ok {
+		t.Error("another call was limited")
+	}
+
+	clock = clock.Add(4 * time.Second)
+
+	if ok, wait := limiter.Allow("ip:203.0.113.7", "save", limit); ok || RetryAfterSeconds(wait) != 6 {
+		t.Errorf("after 4s: allowed = %v, wait = %s, want 6s", ok, wait)
+	}
+
+	clock = clock.Add(6 * time.Second)
+
+	if ok, _ := limiter.Allow("ip:203.0.113.7", "save", limit); !ok {
+		t.Error("request after refill was limited")
+	}
+
+	if ok, _ := limiter.Allow("ip:203.0.113.7", "save", limit); ok {
+		t.Error("refill gave more than one token")
+	}
+
+	// 오래 쉬어도 Burst 보다 많이 쌓이지 않는다
+	clock = clock.Add(time.Hour)
+
+	for i := 0; i < 3; i++ {
+		if ok, _ := limiter.Allow("ip:203.0.113.7", "save", limit); !ok {
+			t.Fatalf("request %d after an hour was limited", i+1)
+		}
+	}
+
+	if ok, _ := limiter.Allow("ip:203.0.113.7", "save", limit); ok {
+		t.Error("tokens grew past burst")
+	}
+
+	if ok, _ := limiter.Allow("ip:203.0.113.7", "save", RateLimit{}); !ok {
+		t.Error("zero limit should not limit")
+	}
+}
+
+func TestRetryAfterSeconds(t *testing.T) {
+	tests := []struct {
+		wait time.Duration
+		want int
+	}{
+		{0, 1},
+		{time.Millisecond, 1},
+		{time.Second, 1},
+		{1500 * time.Millisecond, 2},
+		{time.Minute, 60},
+	}
+
+	for _, test := range tests {
+		if got := RetryAfterSeconds(test.wait); got != test.want {
+			t.Errorf("RetryAfterSeconds(%s) = %d, want %d", test.wait, got, test.want)
+		}
+	}
+}
+
+func TestRateLimitClass(t *testing.T) {
+	config := RateLimitConfig{Limits: map[string]RateLimit{
+		"*":    {PerMinute: 120, Burst: 60},
+		"save": {PerMinute: 10, Burst: 5},
+	}}
+
+	tests := []struct {
+		call, want string
+	}{
+		{"save", "save"},
+		{"*", "*"},
+		{"lint", "*"},
+		{"", "*"},
+		{"save ", "*"},
+		{"SAVE", "*"},
+	}
+
+	for _, test := range tests {
+		if got := config.Class(test.call); got != test.want {
+			t.Errorf("Class(%q) = %q, want %q", test.call, got, test.want)
+		}
+
+		if got := config.Limit(test.call); got != config.Limits[test.want] {
+			t.Errorf("Limit(%q) = %+v, want %+v", test.call, got, config.Limits[test.want])
+		}
+	}
+}
+
+// 설정에 없는 호출은 이름을 바꿔 보내도 "*" 버킷 하나에서 센다
+func TestAPIRateLimitUnknownCalls(t *testing.T) {
+	setupAPI(t)
+
+	clock := time.Now()
+	APILimiter = NewRateLimiter()
+	APILimiter.now = func() time.Time { return clock }
+
+	path := filepath.Join(t.TempDir(), "ratelimit.json")
+	defer func(previous string) { RateLimitConfigPath = previous }(RateLimitConfigPath)
+	RateLimitConfigPath = path
+
+	if err := os.WriteFile(path, []byte(`{"limits": {"*": {"perMinute": 6, "burst": 3}}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	call := func(name string) int {
+		form := url.Values{"call": {name}}
+		r := httptest.NewRequest("POST", "/api", strings.NewReader(form.Encode()))
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		w := httptest.NewRecorder()
+
+		API(w, r)
+
+		return w.Code
+	}
+
+	for i, name := range []string{"a", "b", "c"} {
+		if code := call(name); code == http.StatusTooManyRequests {
+			t.Fatalf("request %d within burst was limited", i+1)
+		}
+	}
+
+	if code := call("d"); code != http.StatusTooManyRequests {
+		t.Errorf("new call name: status = %d, want 429", code)
+	}
+
+	// 따로 적은 호출은 자기 버킷을 쓴다
+	if code := call("save"); code == http.StatusTooManyRequests {
+		t.Error("save was limited by unknown calls")
+	}
+}
@@ -0,0 +1,452 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/asticode/go-astisub"
+)
+
+// 검토 상태
+const (
+	ReviewPending    = "pending"    // 검토 대기
+	ReviewApproved   = "approved"   // 게시됨
+	ReviewRejected   = "rejected"   // 반려
+	ReviewChanges    = "changes"    // 수정 요청
+	ReviewSuperseded = "superseded" // 같은 사람이 새로 올려서 대신함
+
+	ReviewQuarantined = "quarantined" // 훼손으로 의심되어 관리자 확인 대기
+	ReviewDiscarded   = "discarded"   // 관리자가 훼손으로 보고 버림
+)
+
+// Proposal 은 검토를 기다리는 저장, SubtitleDir/platform/id/pending/<ID>.json 과 <ID>.srt 에 저장한다
+// Base 는 올릴 때 게시되어 있던 버전, Version 은 승인되어 게시된 버전
+type Proposal struct {
+	ID        string          `json:"id"`
+	Platform  string          `json:"platform"`
+	VideoID   string          `json:"videoId"`
+	Lang      string          `json:"lang"`
+	Source    string          `json:"source,omitempty"`
+	Author    string          `json:"author"`
+	IP        string          `json:"-"`
+	Base      string          `json:"base"`
+	Status    string          `json:"status"`
+	Reasons   []string        `json:"reasons,omitempty"`
+	Comments  []ReviewComment `json:"comments"`
+	Version   string          `json:"version,omitempty"`
+	CreatedAt time.Time       `json:"createdAt"`
+	UpdatedAt time.Time       `json:"updatedAt"`
+}
+
+// proposalFile 은 IP 까지 저장하는 디스크 형식 (API 로는 IP 를 내보내지 않는다)
+type proposalFile struct {
+	Proposal
+	IP string `json:"ip"`
+}
+
+type ReviewComment struct {
+	Author    string    `json:"author"`
+	Status    string    `json:"status"`
+	Text      string    `json:"text"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// DiffLine 의 Op 는 + (검토 중인 자막에만 있음) 또는 - (게시된 자막에만 있음)
+type DiffLine struct {
+	Op   string `json:"op"`
+	Text string `json:"text"`
+}
+
+type ReviewJSON struct {
+	Proposals []*Proposal `json:"proposals,omitempty"`
+	Proposal  *Proposal   `json:"proposal,omitempty"`
+	Published string      `json:"published,omitempty"`
+	Proposed  string      `json:"proposed,omitempty"`
+	Diff      []DiffLine  `json:"diff,omitempty"`
+	Code      int         `json:"code"`
+}
+
+var (
+	proposalIDRegx = regexp.MustCompile(`^[0-9]{1,20}$`)
+
+	reviewMu sync.Mutex
+)
+
+func ValidateProposalID(pid string) error {
+	if !proposalIDRegx.MatchString(pid) {
+		return fmt.Errorf("invalid proposal: %s", pid)
+	}
+
+	return nil
+}
+
+func storeProposal(p *Proposal) error {
+	p.UpdatedAt = time.Now()
+
+	file, err := json.Marshal(proposalFile{Proposal: *p, IP: p.IP})
+	if err != nil {
+		return err
+	}
+
+	return SubtitleStore.WriteFile(file, p.Platform, p.VideoID, "pending", p.ID+".json")
+}
+
+// LoadProposal 은 검토 정보와 올린 자막을 같이 읽는다
+func LoadProposal(platform, id, pid string) (*Proposal, []byte, error) {
+	if err := ValidateProposalID(pid); err != nil {
+		return nil, nil, err
+	}
+
+	file, err := SubtitleStore.ReadFile(platform, id, "pending", pid+".json")
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var stored proposalFile
+	if err := json.Unmarshal(file, &stored); err != nil {
+		return nil, nil, err
+	}
+	stored.Proposal.IP = stored.IP
+
+	srt, err := SubtitleStore.ReadFile(platform, id, "pending", pid+".srt")
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return &stored.Proposal, srt, nil
+}
+
+// ProposeSubtitle 은 저장을 게시하지 않고 검토 대기로 올린다
+// 같은 사람이 같은 언어로 올려둔 검토 대기, 수정 요청은 새 것으로 대신한다
+func ProposeSubtitle(platform, id, lang, source string, subtitle []byte, author, ip string) (*Proposal, error) {
+	return propose(platform, id, lang, source, subtitle, author, ip, ReviewPending, nil)
+}
+
+// QuarantineSubtitle 은 훼손으로 의심되는 저장을 관리자가 풀어주거나 버릴 때까지 격리한다
+// 검토 대기로 올려둔 다른 저장은 그대로 둔다
+func QuarantineSubtitle(platform, id, lang, source string, subtitle []byte, author, ip string, report *VandalismReport) (*Proposal, error) {
+	return propose(platform, id, lang, source, subtitle, author, ip, ReviewQuarantined, report.Reasons)
+}
+
+func propose(platform, id, lang, source string, subtitle []byte, author, ip, status string, reasons []string) (*Proposal, error) {
+	reviewMu.Lock()
+	defer reviewMu.Unlock()
+
+	now := time.Now()
+	p := &Proposal{
+		ID:        strconv.FormatInt(now.UnixNano(), 10),
+		Platform:  platform,
+		VideoID:   id,
+		Lang:      lang,
+		Source:    source,
+		Author:    author,
+		IP:        ip,
+		Base:      fmt.Sprintf("r%d", GetLastVersion(id)),
+		Status:    status,
+		Reasons:   reasons,
+		Comments:  []ReviewComment{},
+		CreatedAt: now,
+	}
+
+	if err := SubtitleStore.WriteFile(subtitle, platform, id, "pending", p.ID+".srt"); err != nil {
+		return nil, err
+	}
+
+	if err := storeProposal(p); err != nil {
+		return nil, err
+	}
+
+	// 익명은 누가 누군지 모르므로 대신하지 않는다
+	if len(author) == 0 || status != ReviewPending {
+		return p, nil
+	}
+
+	proposals, err := listProposals(platform, id)
+	if err != nil {
+		fmt.Println(err)
+		return p, nil
+	}
+
+	for _, old := range proposals {
+		if old.ID == p.ID || old.Author != author || old.Lang != lang {
+			continue
+		}
+
+		if old.Status == ReviewPending || old.Status == ReviewChanges {
+			old.Status = ReviewSuperseded
+			if err := storeProposal(old); err != nil {
+				fmt.Println(err)
+			}
+		}
+	}
+
+	return p, nil
+}
+
+func listProposals(platform, id string) ([]*Proposal, error) {
+	dir, err := SubtitleStore.Path(platform, id, "pending")
+	if err != nil {
+		return nil, err
+	}
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+
+	proposals := []*Proposal{}
+	for _, file := range files {
+		p, _, err := LoadProposal(platform, id, strings.TrimSuffix(filepath.Base(file), ".json"))
+		if err != nil {
+			fmt.Println(err)
+			continue
+		}
+
+		proposals = append(proposals, p)
+	}
+
+	sort.Slice(proposals, func(i, j int) bool {
+		return proposals[i].CreatedAt.After(proposals[j].CreatedAt)
+	})
+
+	return proposals, nil
+}
+
+// ListProposals 는 최근 것부터 돌려준다, lang 이나 status 가 비어 있으면 전부
+func ListProposals(platform, id, lang, status string) ([]*Proposal, error) {
+	reviewMu.Lock()
+	defer reviewMu.Unlock()
+
+	proposals, err := listProposals(platform, id)
+	if err != nil {
+		return nil, err
+	}
+
+	filtered := []*Proposal{}
+	for _, p := range proposals {
+		if (len(lang) == 0 || p.Lang == lang) && (len(status) == 0 || p.Status == status) {
+			filtered = append(filtered, p)
+		}
+	}
+
+	return filtered, nil
+}
+
+// ReviewProposal 은 검토 대기 중인 것만 처리한다, 승인하면 올린 사람 이름으로 게시한다
+func ReviewProposal(platform, id, pid, status, reviewer, comment string) (*Proposal, error) {
+	if status != ReviewApproved && status != ReviewRejected && status != ReviewChanges {
+		return nil, fmt.Errorf("invalid review: %s", status)
+	}
+
+	comment = strings.TrimSpace(comment)
+	if status != ReviewApproved && len(comment) == 0 {
+		return nil, fmt.Errorf("comment is required")
+	}
+
+	return resolveProposal(platform, id, pid, ReviewPending, status, reviewer, comment)
+}
+
+// ReleaseProposal 은 격리된 저장을 관리자가 확인하고 게시하거나 (approved) 버린다 (discarded)
+func ReleaseProposal(platform, id, pid, status, admin, comment string) (*Proposal, error) {
+	if status != ReviewApproved && status != ReviewDiscarded {
+		return nil, fmt.Errorf("invalid release: %s", status)
+	}
+
+	return resolveProposal(platform, id, pid, ReviewQuarantined, status, admin, strings.TrimSpace(comment))
+}
+
+// resolveProposal 은 from 상태인 것만 status 로 바꾼다, approved 이면 게시한다
+func resolveProposal(platform, id, pid, from, status, reviewer, comment string) (*Proposal, error) {
+	reviewMu.Lock()
+	defer reviewMu.Unlock()
+
+	p, srt, err := LoadProposal(platform, id, pid)
+	if err != nil {
+		return nil, err
+	}
+
+	if p.Status != from {
+		return nil, fmt.Errorf("proposal is %s", p.Status)
+	}
+
+	if status == ReviewApproved {
+		version, err := PublishSubtitle(platform, id, p.Lang, p.Source, srt, p.Author, p.IP)
+		if err != nil {
+			return nil, err
+		}
+
+		p.Version = fmt.Sprintf("r%d", version)
+	}
+
+	p.Status = status
+	p.Comments = append(p.Comments, ReviewComment{
+		Author:    reviewer,
+		Status:    status,
+		Text:      comment,
+		CreatedAt: time.Now(),
+	})
+
+	if err := storeProposal(p); err != nil {
+		return nil, err
+	}
+
+	return p, nil
+}
+
+// 앞뒤의 같은 자막을 빼고도 비교할 칸 (자막 수의 곱) 이 이보다 많으면 LCS 를 구하지 않고 통째로 바뀐 것으로 보여준다
+const diffMaxCells = 25000000
+
+// DiffSubtitles 는 자막 단위로 LCS 를 구해 바뀐 자막만 돌려준다 (편집기의 임시 저장본 비교와 같은 형식)
+func DiffSubtitles(published, proposed *astisub.Subtitles) []DiffLine {
+	line := func(item *astisub.Item) string {
+		return fmt.Sprintf("%s --> %s  %s", item.StartAt, item.EndAt, CueText(item))
+	}
+
+	a := make([]string, len(published.Items))
+	for i, item := range published.Items {
+		a[i] = line(item)
+	}
+
+	b := make([]string, len(proposed.Items))
+	for i, item := range proposed.Items {
+		b[i] = line(item)
+	}
+
+	return diffLines(a, b)
+}
+
+// diffLines 는 앞뒤의 같은 줄을 빼고 가운데만 Hirschberg 방식으로 비교한다 (메모리는 줄 수에 비례)
+func diffLines(a, b []string) []DiffLine {
+	start := 0
+	for start < len(a) && start < len(b) && a[start] == b[start] {
+		start++
+	}
+
+	endA, endB := len(a), len(b)
+	for endA > start && endB > start && a[endA-1] == b[endB-1] {
+		endA--
+		endB--
+	}
+
+	a, b = a[start:endA], b[start:endB]
+
+	diff := []DiffLine{}
+	if len(a)*len(b) > diffMaxCells {
+		return appendReplace(diff, a, b)
+	}
+
+	return diffMiddle(diff, a, b)
+}
+
+func appendReplace(diff []DiffLine, removed, added []string) []DiffLine {
+	for _, text := range removed {
+		diff = append(diff, DiffLine{Op: "-", Text: text})
+	}
+
+	for _, text := range added {
+		diff = append(diff, DiffLine{Op: "+", Text: text})
+	}
+
+	return diff
+}
+
+// diffMiddle 은 a 를 반으로 나누고, LCS 가 가장 길어지는 b 의 위치를 찾아 양쪽을 따로 비교한다
+func diffMiddle(diff []DiffLine, a, b []string) []DiffLine {
+	if len(a) == 0 || len(b) == 0 {
+		return appendReplace(diff, a, b)
+	}
+
+	if len(a) == 1 {
+		for k, text := range b {
+			if text == a[0] {
+				diff = appendReplace(diff, nil, b[:k])
+				return appendReplace(diff, nil, b[k+1:])
+			}
+		}
+
+		return appendReplace(diff, a, b)
+	}
+
+	mid := len(a) / 2
+	left := lcsPrefix(a[:mid], b)
+	right := lcsSuffix(a[mid:], b)
+
+	split, best := 0, -1
+	for k := 0; k <= len(b); k++ {
+		if n := left[k] + right[k]; n > best {
+			split, best = k, n
+		}
+	}
+
+	diff = diffMiddle(diff, a[:mid], b[:split])
+	return diffMiddle(diff, a[mid:], b[split:])
+}
+
+// lcsPrefix 의 k 번째 값은 a 와 b[:k] 의 LCS 길이
+func lcsPrefix(a, b []string) []int {
+	prev, cur := make([]int, len(b)+1), make([]int, len(b)+1)
+
+	for i := range a {
+		for j := range b {
+			if a[i] == b[j] {
+				cur[j+1] = prev[j] + 1
+			} else {
+				cur[j+1] = max(cur[j], prev[j+1])
+			}
+		}
+
+		prev, cur = cur, prev
+	}
+
+	return prev
+}
+
+// lcsSuffix 의 k 번째 값은 a 와 b[k:] 의 LCS 길이
+func lcsSuffix(a, b []string) []int {
+	prev, cur := make([]int, len(b)+1), make([]int, len(b)+1)
+
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				cur[j] = prev[j+1] + 1
+			} else {
+				cur[j] = max(cur[j+1], prev[j])
+			}
+		}
+
+		prev, cur = cur, prev
+	}
+
+	return prev
+}
+
+// DiffProposal 은 지금 게시된 자막과 비교한다, 게시된 자막이 없으면 빈 자막과 비교한다
+func DiffProposal(platform, id, pid string) (*Proposal, string, string, []DiffLine, error) {
+	p, srt, err := LoadProposal(platform, id, pid)
+	if err != nil {
+		return nil, "", "", nil, err
+	}
+
+	proposed, err := astisub.ReadFromSRT(bytes.NewReader(srt))
+	if err != nil {
+		return nil, "", "", nil, err
+	}
+
+	published := astisub.NewSubtitles()
+	current, err := SubtitleStore.ReadFile(platform, id, p.Lang+".srt")
+	if err == nil {
+		if published, err = astisub.ReadFromSRT(bytes.NewReader(current)); err != nil {
+			return nil, "", "", nil, err
+		}
+	}
+
+	return p, string(current), string(srt), DiffSubtitles(published, proposed), nil
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"math/rand"
+	"reflect"
+	"sort"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/asticode/go-astisub"
+)
+
+// lcsLength 는 예전처럼 전체 표를 채워 LCS 길이를 구한다
+func lcsLength(a, b []string) int {
+	table := make([][]int, len(a)+1)
+	for i := range table {
+		table[i] = make([]int, len(b)+1)
+	}
+
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				table[i][j] = table[i+1][j+1] + 1
+			} else {
+				table[i][j] = max(table[i+1][j], table[i][j+1])
+			}
+		}
+	}
+
+	return table[0][0]
+}
+
+// checkDiff 는 지운 줄이 a 에서, 더한 줄이 b 에서 왔고 남은 줄이 LCS 만큼인지 본다
+func checkDiff(t *testing.T, a, b []string, diff []DiffLine) {
+	t.Helper()
+
+	var removed, added []string
+	for _, line := range diff {
+		switch line.Op {
+		case "-":
+			removed = append(removed, line.Text)
+		case "+":
+			added = append(added, line.Text)
+		default:
+			t.Fatalf("op = %q", line.Op)
+		}
+	}
+
+	if kept, want := len(a)-len(removed), lcsLength(a, b); kept != want || len(b)-len(added) != want {
+		t.Fatalf("diff(%q, %q) keeps %d lines, want %d: %v", a, b, kept, want, diff)
+	}
+
+	// 지운 줄을 빼면 a 에, 더한 줄을 빼면 b 에 같은 줄들이 남아야 한다
+	rest := func(lines, changed []string) []string {
+		count := map[string]int{}
+		for _, line := range changed {
+			count[line]++
+		}
+
+		var rest []string
+		for _, line := range lines {
+			if count[line] > 0 {
+				count[line]--
+				continue
+			}
+
+			rest = append(rest, line)
+		}
+
+		sort.Strings(rest)
+		return rest
+	}
+
+	if left, right := rest(a, removed), rest(b, added); !reflect.DeepEqual(left, right) || len(left) != len(a)-len(removed) {
+		t.Fatalf("diff(%q, %q) = %v", a, b, diff)
+	}
+}
+
+func TestDiffLines(t *testing.T) {
+	tests := []struct {
+		a, b []string
+		want []DiffLine
+	}{
+		{nil, nil, []DiffLine{}},
+		{[]string{"a", "b"}, []string{"a", "b"}, []DiffLine{}},
+		{[]string{"a", "b", "c"}, []string{"a", "x", "c"}, []DiffLine{{"-", "b"}, {"+", "x"}}},
+		{[]string{"a", "b", "c"}, []string{"a", "c"}, []DiffLine{{"-", "b"}}},
+		{[]string{"a", "c"}, []string{"a", "b", "c"}, []DiffLine{{"+", "b"}}},
+		{nil, []string{"a"}, []DiffLine{{"+", "a"}}},
+		{[]string{"a"}, nil, []DiffLine{{"-", "a"}}},
+	}
+
+	for _, test := range tests {
+		if got := diffLines(test.a, test.b); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("diffLines(%q, %q) = %v, want %v", test.a, test.b, got, test.want)
+		}
+	}
+
+	random := rand.New(rand.NewSource(1))
+	lines := func() []string {
+		lines := make([]string, random.Intn(12))
+		for i := range lines {
+			lines[i] = strconv.Itoa(random.Intn(4))
+		}
+
+		return lines
+	}
+
+	for i := 0; i < 2000; i++ {
+		a, b := lines(), lines()
+		checkDiff(t, a, b, diffLines(a, b))
+	}
+}
+
+// 자막이 많아도 한 줄만 바뀌었으면 앞뒤를 잘라내고 바로 끝난다
+func TestDiffSubtitlesLarge(t *testing.T) {
+	published, proposed := astisub.NewSubtitles(), astisub.NewSubtitles()
+
+	for i := 0; i < 50000; i++ {
+		text := "자막 " + strconv.Itoa(i)
+		published.Items = append(published.Items, testItem(i, text))
+
+		if i == 25000 {
+			text = "바뀐 자막"
+		}
+
+		proposed.Items = append(proposed.Items, testItem(i, text))
+	}
+
+	diff := DiffSubtitles(published, proposed)
+
+	want := []DiffLine{
+		{"-", "6h56m40s --> 6h56m41s  자막 25000"},
+		{"+", "6h56m40s --> 6h56m41s  바뀐 자막"},
+	}
+
+	if !reflect.DeepEqual(diff, want) {
+		t.Errorf("diff = %v, want %v", diff, want)
+	}
+}
+
+// 둘 다 통째로 바뀐 큰 자막은 LCS 를 구하지 않고 모두 지우고 더한 것으로 보여준다
+func TestDiffLinesReplace(t *testing.T) {
+	a, b := make([]string, 6000), make([]string, 6000)
+	for i := range a {
+		a[i] = "a" + strconv.Itoa(i)
+		b[i] = "b" + strconv.Itoa(i)
+	}
+
+	diff := diffLines(a, b)
+
+	if len(diff) != len(a)+len(b) || diff[0] != (DiffLine{"-", "a0"}) || diff[len(a)] != (DiffLine{"+", "b0"}) {
+		t.Errorf("diff = %d lines, want %d removed then %d added", len(diff), len(a), len(b))
+	}
+}
+
+func testItem(i int, text string) *astisub.Item {
+	return &astisub.Item{
+		StartAt: time.Duration(i) * time.Second,
+		EndAt:   time.Duration(i+1) * time.Second,
+		Lines:   []astisub.Line{{Items: []astisub.LineItem{{Text: text}}}},
+	}
+}
@@ -0,0 +1,311 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// 역할은 아래로 갈수록 위의 권한을 모두 가진다
+const (
+	RoleViewer      = "viewer"      // 읽기만
+	RoleContributor = "contributor" // 자막 저장 (검토 대기로 올라간다), 메모, 초안과 도구 사용
+	RoleReviewer    = "reviewer"    // 바로 게시, 검토, 메모 해결, 용어집 관리
+	RoleAdmin       = "admin"       // 이전 버전 복구, 격리된 저장 처리, 사용자 관리
+)
+
+var roleRanks = map[string]int{
+	RoleViewer:      1,
+	RoleContributor: 2,
+	RoleReviewer:    3,
+	RoleAdmin:       4,
+}
+
+// RoleGrant 는 영상이나 언어 하나에만 주는 역할, 빈 칸은 전부를 뜻한다
+// 전체 역할보다 높을 때만 의미가 있다 (범위 역할로 권한을 낮추지는 않는다)
+type RoleGrant struct {
+	Platform string `json:"platform,omitempty"`
+	ID       string `json:"id,omitempty"`
+	Lang     string `json:"lang,omitempty"`
+	Role     string `json:"role"`
+}
+
+// AccountUser 는 관리자에게 보여주는 사용자 정보
+type AccountUser struct {
+	Name   string      `json:"name"`
+	Role   string      `json:"role"`
+	Grants []RoleGrant `json:"grants"`
+}
+
+// callRoles 는 API 호출마다 필요한 최소 역할, 여기에 없는 호출은 관리자만 부를 수 있다
+// 빈 문자열이면 호출 안에서 직접 확인한다
+var callRoles = map[string]string{
+	"youtube":    RoleViewer,
+	"subtitle":   RoleViewer,
+	"waveform":   RoleViewer,
+	"video":      RoleViewer,
+	"videos":     RoleViewer,
+	"completion": RoleViewer,
+	"search":     RoleViewer,
+	"memory":     RoleViewer,
+	"lint":       RoleViewer,
+	"save":       RoleContributor,
+	"vad":        RoleContributor,
+	"translate":  RoleContributor,
+	"review":     RoleContributor,
+	"comment":    RoleContributor,
+	"glossary":   RoleReviewer,
+	"restore":    RoleAdmin,
+	"account":    "",
+}
+
+// scopedCalls 는 platform, id, lang 으로 영상 하나를 다루는 호출, 범위 역할은 이 호출에만 쓴다
+// 용어집, 계정 같은 나머지 호출은 요청에 platform, id, lang 을 넣어도 전체 역할로만 확인한다
+var scopedCalls = map[string]bool{
+	"youtube":    true,
+	"subtitle":   true,
+	"waveform":   true,
+	"video":      true,
+	"completion": true,
+	"save":       true,
+	"vad":        true,
+	"translate":  true,
+	"review":     true,
+	"comment":    true,
+	"restore":    true,
+}
+
+// actionRoles 는 action 에 따라 필요한 역할이 다른 호출
+var actionRoles = map[string]map[string]string{
+	"glossary": {
+		"list": RoleViewer,
+	},
+	"review": {
+		"approve": RoleReviewer,
+		"reject":  RoleReviewer,
+		"changes": RoleReviewer,
+		"release": RoleAdmin,
+		"discard": RoleAdmin,
+	},
+	"comment": {
+		"list":      RoleViewer,
+		"resolve":   RoleReviewer,
+		"unresolve": RoleReviewer,
+	},
+	"account": {
+		"users": RoleAdmin,
+		"role":  RoleAdmin,
+	},
+}
+
+func ValidateRole(role string) error {
+	if roleRanks[role] == 0 {
+		return fmt.Errorf("invalid role: %s", role)
+	}
+
+	return nil
+}
+
+func RoleAtLeast(role, required string) bool {
+	return roleRanks[role] >= roleRanks[required]
+}
+
+func RequiredRole(call, action string) string {
+	if role, ok := actionRoles[call][action]; ok {
+		return role
+	}
+
+	if role, ok := callRoles[call]; ok {
+		return role
+	}
+
+	return RoleAdmin
+}
+
+func (g RoleGrant) Matches(platform, id, lang string) bool {
+	return (len(g.Platform) == 0 || g.Platform == platform) &&
+		(len(g.ID) == 0 || g.ID == id) &&
+		(len(g.Lang) == 0 || g.Lang == lang)
+}
+
+// AnonymousRole 은 로그인하지 않은 사람의 역할, 익명 저장을 막아두었으면 읽기만 할 수 있다
+func AnonymousRole(config AuthConfig) string {
+	if config.Anonymous == AnonymousDeny {
+		return RoleViewer
+	}
+
+	return RoleContributor
+}
+
+// RoleFor 는 전체 역할과 범위가 맞는 역할 중 가장 높은 것을 돌려준다, user 가 nil 이면 익명
+func RoleFor(user *User, config AuthConfig, platform, id, lang string) string {
+	if user == nil {
+		return AnonymousRole(config)
+	}
+
+	role := user.Role
+	if ValidateRole(role) != nil {
+		role = config.DefaultRole
+	}
+
+	for _, grant := range user.Grants {
+		if grant.Matches(platform, id, lang) && roleRanks[grant.Role] > roleRanks[role] {
+			role = grant.Role
+		}
+	}
+
+	return role
+}
+
+// Authorize 는 요청한 사람의 역할이 호출에 필요한 역할 이상인지 확인한다
+func Authorize(r *http.Request, user *User) (string, error) {
+	call := r.FormValue("call")
+	config := LoadAuthConfig()
+
+	role := RoleFor(user, config, "", "", "")
+	if scopedCalls[call] {
+		role = RoleFor(user, config, r.FormValue("platform"), r.FormValue("id"), r.FormValue("lang"))
+	}
+
+	if required := RequiredRole(call, r.FormValue("action")); len(required) != 0 && !RoleAtLeast(role, required) {
+		return role, fmt.Errorf("%s requires %s role", strings.TrimSpace(call+" "+r.FormValue("action")), required)
+	}
+
+	return role, nil
+}
+
+// SetUserRole 은 grant 에 범위가 없으면 전체 역할을 바꾸고, 있으면 그 범위의 역할을 바꾼다 (Role 이 비어 있으면 지운다)
+func SetUserRole(name string, grant RoleGrant) (*User, error) {
+	scoped := len(grant.Platform) != 0 || len(grant.ID) != 0 || len(grant.Lang) != 0
+
+	if len(grant.Role) != 0 || !scoped {
+		if err := ValidateRole(grant.Role); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(grant.Platform) != 0 && Sources[grant.Platform] == nil {
+		return nil, fmt.Errorf("unknown platform: %s", grant.Platform)
+	}
+
+	if len(grant.ID) != 0 && !mediaIDRegx.MatchString(grant.ID) {
+		return nil, fmt.Errorf("invalid id: %s", grant.ID)
+	}
+
+	if len(grant.Lang) != 0 {
+		if err := ValidateLang(grant.Lang); err != nil {
+			return nil, err
+		}
+	}
+
+	accountMu.Lock()
+	defer accountMu.Unlock()
+
+	user, err := LoadUser(name)
+	if err != nil {
+		return nil, err
+	}
+
+	if !scoped {
+		user.Role = grant.Role
+		return user, storeUser(user)
+	}
+
+	grants := []RoleGrant{}
+	for _, g := range user.Grants {
+		if g.Platform != grant.Platform || g.ID != grant.ID || g.Lang != grant.Lang {
+			grants = append(grants, g)
+		}
+	}
+
+	if len(grant.Role) != 0 {
+		grants = append(grants, grant)
+	}
+	user.Grants = grants
+
+	return user, storeUser(user)
+}
+
+func ListUsers(config AuthConfig) ([]AccountUser, error) {
+	dir, err := AccountStore.Path("users")
+	if err != nil {
+		return nil, err
+	}
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+
+	users := []AccountUser{}
+	for _, file := range files {
+		user, err := LoadUser(strings.TrimSuffix(filepath.Base(file), ".json"))
+		if err != nil {
+			fmt.Println(err)
+			continue
+		}
+
+		users = append(users, user.Account(config))
+	}
+
+	sort.Slice(users, func(i, j int) bool {
+		return strings.ToLower(users[i].Name) < strings.ToLower(users[j].Name)
+	})
+
+	return users, nil
+}
+
+func (user *User) Account(config AuthConfig) AccountUser {
+	account := AccountUser{
+		Name:   user.Name,
+		Role:   user.Role,
+		Grants: user.Grants,
+	}
+
+	if ValidateRole(account.Role) != nil {
+		account.Role = config.DefaultRole
+	}
+
+	if account.Grants == nil {
+		account.Grants = []RoleGrant{}
+	}
+
+	return account
+}
+
+// hasAdmin 은 전체 역할이 관리자인 사람이 한 명이라도 있는지 확인한다
+func hasAdmin() bool {
+	dir, err := AccountStore.Path("users")
+	if err != nil {
+		return false
+	}
+
+	files, _ := filepath.Glob(filepath.Join(dir, "*.json"))
+	for _, file := range files {
+		data, err := ioutil.ReadFile(file)
+		if err != nil {
+			continue
+		}
+
+		var user User
+		if err := json.Unmarshal(data, &user); err == nil && user.Role == RoleAdmin {
+			return true
+		}
+	}
+
+	return false
+}
+
+// decodeGrant 는 role 호출의 폼 값을 읽는다
+func decodeGrant(r *http.Request) RoleGrant {
+	return RoleGrant{
+		Platform: r.FormValue("platform"),
+		ID:       r.FormValue("id"),
+		Lang:     r.FormValue("lang"),
+		Role:     r.FormValue("role"),
+	}
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestAuthorizeScopedGrants(t *testing.T) {
+	user := &User{
+		Name: "scoped",
+		Role: RoleContributor,
+		Grants: []RoleGrant{
+			{Platform: "youtube", ID: "jNQXAC9IVRw", Role: RoleAdmin},
+			{Lang: "ja", Role: RoleReviewer},
+		},
+	}
+
+	tests := []struct {
+		form    url.Values
+		allowed bool
+		comment string
+	}{
+		{url.Values{"call": {"restore"}, "platform": {"youtube"}, "id": {"jNQXAC9IVRw"}, "lang": {"ko"}}, true, "restore on the granted video"},
+		{url.Values{"call": {"restore"}, "platform": {"youtube"}, "id": {"aaaaaaaaaaa"}, "lang": {"ko"}}, false, "restore on another video"},
+		{url.Values{"call": {"review"}, "action": {"approve"}, "platform": {"youtube"}, "id": {"aaaaaaaaaaa"}, "lang": {"ja"}}, true, "review in the granted language"},
+		{url.Values{"call": {"review"}, "action": {"approve"}, "platform": {"youtube"}, "id": {"aaaaaaaaaaa"}, "lang": {"ko"}}, false, "review in another language"},
+		{url.Values{"call": {"glossary"}, "action": {"add"}, "platform": {"youtube"}, "id": {"jNQXAC9IVRw"}, "lang": {"ja"}}, false, "glossary with a scoped grant"},
+		{url.Values{"call": {"glossary"}, "action": {"list"}, "platform": {"youtube"}, "id": {"jNQXAC9IVRw"}}, true, "glossary list"},
+		{url.Values{"call": {"account"}, "action": {"users"}, "platform": {"youtube"}, "id": {"jNQXAC9IVRw"}}, false, "user list with a scoped grant"},
+		{url.Values{"call": {"account"}, "action": {"role"}, "platform": {"youtube"}, "id": {"jNQXAC9IVRw"}, "role": {"admin"}}, false, "role change with a scoped grant"},
+		{url.Values{"call": {"save"}, "platform": {"youtube"}, "id": {"aaaaaaaaaaa"}, "lang": {"ko"}}, true, "save with the global role"},
+	}
+
+	for _, test := range tests {
+		r := httptest.NewRequest("POST", "/api", strings.NewReader(test.form.Encode()))
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+		_, err := Authorize(r, user)
+		if allowed := err == nil; allowed != test.allowed {
+			t.Errorf("%s: allowed = %v, want %v (%v)", test.comment, allowed, test.allowed, err)
+		}
+	}
+}
@@ -0,0 +1,368 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/asticode/go-astisub"
+	"golang.org/x/text/unicode/norm"
+)
+
+// SearchIndex 는 저장된 자막(과 이전 버전)을 문서 단위로 들고 있는 메모리 전문 검색 색인
+// 한글, 한자, 가나는 글자 2개씩(bigram), 나머지는 단어 단위로 나눈다
+type SearchIndex struct {
+	mu    sync.RWMutex
+	docs  map[string]*searchDoc
+	ready bool
+}
+
+type searchDoc struct {
+	platform string
+	id       string
+	lang     string
+	revision string
+
+	cues     []searchCue
+	postings map[string][]int
+}
+
+type searchCue struct {
+	index   int
+	startAt time.Duration
+	endAt   time.Duration
+	text    string
+	lower   string
+}
+
+type SearchHit struct {
+	Platform string        `json:"platform"`
+	ID       string        `json:"id"`
+	Lang     string        `json:"lang"`
+	Revision string        `json:"revision,omitempty"`
+	Index    int           `json:"index"`
+	StartAt  time.Duration `json:"startAt"`
+	EndAt    time.Duration `json:"endAt"`
+	Text     string        `json:"text"`
+	Pair     *SearchHit    `json:"pair,omitempty"`
+}
+
+type SearchQuery struct {
+	Text      string
+	Platform  string
+	Lang      string
+	Revisions bool
+}
+
+type SearchJSON struct {
+	Hits  []SearchHit `json:"hits"`
+	Total int         `json:"total"`
+	Code  int         `json:"code"`
+}
+
+var Search = NewSearchIndex()
+
+func NewSearchIndex() *SearchIndex {
+	return &SearchIndex{
+		docs: make(map[string]*searchDoc),
+	}
+}
+
+// 장음 부호 (ー) 는 가타카나 문자가 아니라서 따로 넣는다
+func isNgramRune(r rune) bool {
+	return unicode.In(r, unicode.Hangul, unicode.Han, unicode.Hiragana, unicode.Katakana) || r == 'ー'
+}
+
+// normalizeText 는 자모가 나뉜 한글 (NFD) 도 같은 글자로 찾도록 NFC 로 합치고 소문자로 바꾼다
+func normalizeText(text string) string {
+	return strings.ToLower(norm.NFC.String(text))
+}
+
+// Tokenize 는 색인과 검색어에 같이 쓰인다
+func Tokenize(text string) []string {
+	var tokens []string
+	var word, ngram []rune
+
+	flushWord := func() {
+		if len(word) != 0 {
+			tokens = append(tokens, string(word))
+			word = word[:0]
+		}
+	}
+
+	flushNgram := func() {
+		if len(ngram) == 1 {
+			tokens = append(tokens, string(ngram))
+		}
+		for i := 0; i+1 < len(ngram); i++ {
+			tokens = append(tokens, string(ngram[i:i+2]))
+		}
+		ngram = ngram[:0]
+	}
+
+	for _, r := range normalizeText(text) {
+		switch {
+		case isNgramRune(r):
+			flushWord()
+			ngram = append(ngram, r)
+		case unicode.IsLetter(r) || unicode.IsNumber(r):
+			flushNgram()
+			word = append(word, r)
+		default:
+			flushWord()
+			flushNgram()
+		}
+	}
+
+	flushWord()
+	flushNgram()
+
+	return tokens
+}
+
+func newSearchDoc(platform, id, lang, revision string, subs *astisub.Subtitles) *searchDoc {
+	doc := &searchDoc{
+		platform: platform,
+		id:       id,
+		lang:     lang,
+		revision: revision,
+		postings: make(map[string][]int),
+	}
+
+	for i, item := range subs.Items {
+		text := CueText(item)
+
+		doc.cues = append(doc.cues, searchCue{
+			index:   item.Index,
+			startAt: item.StartAt,
+			endAt:   item.EndAt,
+			text:    text,
+			lower:   normalizeText(text),
+		})
+
+		for _, token := range Tokenize(text) {
+			if cues := doc.postings[token]; len(cues) != 0 && cues[len(cues)-1] == i {
+				continue
+			}
+
+			doc.postings[token] = append(doc.postings[token], i)
+		}
+	}
+
+	return doc
+}
+
+func searchDocKey(platform, id, lang, revision string) string {
+	return strings.Join([]string{platform, id, lang, revision}, "/")
+}
+
+// Update 는 자막 파일 하나를 다시 읽어서 색인을 바꾼다, revision 이 비어 있으면 현재 자막
+func (s *SearchIndex) Update(platform, id, lang, revision, path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	subs, err := astisub.ReadFromSRT(file)
+	if err != nil {
+		return err
+	}
+
+	doc := newSearchDoc(platform, id, lang, revision, subs)
+
+	s.mu.Lock()
+	s.docs[searchDocKey(platform, id, lang, revision)] = doc
+	s.mu.Unlock()
+
+	return nil
+}
+
+// Build 는 SubtitleDir 아래 platform/id/lang.srt 와 platform/id/version/rN-lang.srt 를 모두 색인한다
+func (s *SearchIndex) Build() {
+	started := time.Now()
+
+	current, _ := filepath.Glob(SubtitleStore.Root + "/*/*/*.srt")
+	revisions, _ := filepath.Glob(SubtitleStore.Root + "/*/*/version/*.srt")
+
+	for _, path := range current {
+		rel, _ := filepath.Rel(SubtitleStore.Root, path)
+		parts := strings.Split(rel, string(filepath.Separator))
+
+		if err := s.Update(parts[0], parts[1], strings.TrimSuffix(parts[2], ".srt"), "", path); err != nil {
+			fmt.Println(err)
+		}
+	}
+
+	for _, path := range revisions {
+		rel, _ := filepath.Rel(SubtitleStore.Root, path)
+		parts := strings.Split(rel, string(filepath.Separator))
+
+		name := strings.SplitN(strings.TrimSuffix(parts[3], ".srt"), "-", 2)
+		if len(name) != 2 {
+			continue
+		}
+
+		if err := s.Update(parts[0], parts[1], name[1], name[0], path); err != nil {
+			fmt.Println(err)
+		}
+	}
+
+	s.mu.Lock()
+	s.ready = true
+	s.mu.Unlock()
+
+	fmt.Printf("검색 색인 완료: %d개 (%s)\n", len(current)+len(revisions), time.Since(started))
+}
+
+func (s *SearchIndex) Ready() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.ready
+}
+
+// Search 는 모든 토큰이 들어있는 자막 중에서, 띄어쓰기로 나눈 검색어가 모두 그대로 들어있는 것만 돌려준다
+func (s *SearchIndex) Search(query SearchQuery) []SearchHit {
+	tokens := Tokenize(query.Text)
+	terms := strings.Fields(normalizeText(query.Text))
+
+	hits := []SearchHit{}
+	if len(tokens) == 0 {
+		return hits
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, doc := range s.docs {
+		if len(query.Platform) != 0 && doc.platform != query.Platform {
+			continue
+		}
+		if len(query.Lang) != 0 && doc.lang != query.Lang {
+			continue
+		}
+		if !query.Revisions && len(doc.revision) != 0 {
+			continue
+		}
+
+		for _, i := range doc.match(tokens) {
+			cue := doc.cues[i]
+
+			matched := true
+			for _, term := range terms {
+				if !strings.Contains(cue.lower, term) {
+					matched = false
+					break
+				}
+			}
+
+			if matched {
+				hits = append(hits, doc.hit(i))
+			}
+		}
+	}
+
+	sortHits(hits)
+
+	return hits
+}
+
+// match 는 모든 토큰의 posting 을 교집합한다
+func (d *searchDoc) match(tokens []string) []int {
+	var result []int
+
+	for n, token := range tokens {
+		cues := d.postings[token]
+		if len(cues) == 0 {
+			return nil
+		}
+
+		if n == 0 {
+			result = append([]int(nil), cues...)
+			continue
+		}
+
+		var both []int
+		for i, j := 0, 0; i < len(result) && j < len(cues); {
+			switch {
+			case result[i] < cues[j]:
+				i++
+			case result[i] > cues[j]:
+				j++
+			default:
+				both = append(both, result[i])
+				i++
+				j++
+			}
+		}
+
+		result = both
+		if len(result) == 0 {
+			return nil
+		}
+	}
+
+	return result
+}
+
+func (d *searchDoc) hit(i int) SearchHit {
+	cue := d.cues[i]
+
+	return SearchHit{
+		Platform: d.platform,
+		ID:       d.id,
+		Lang:     d.lang,
+		Revision: d.revision,
+		Index:    cue.index,
+		StartAt:  cue.startAt,
+		EndAt:    cue.endAt,
+		Text:     cue.text,
+	}
+}
+
+// PairHits 는 "X 를 Y 로 번역한 곳" 처럼 source 검색 결과와 시간이 겹치는 target 결과를 짝지어 돌려준다
+func PairHits(source, target []SearchHit) []SearchHit {
+	paired := []SearchHit{}
+
+	for _, s := range source {
+		for i := range target {
+			t := &target[i]
+			if t.Platform != s.Platform || t.ID != s.ID || t.Revision != s.Revision || t.Lang == s.Lang {
+				continue
+			}
+
+			if t.StartAt < s.EndAt && s.StartAt < t.EndAt {
+				s.Pair = t
+				paired = append(paired, s)
+				break
+			}
+		}
+	}
+
+	return paired
+}
+
+func sortHits(hits []SearchHit) {
+	sort.Slice(hits, func(i, j int) bool {
+		a, b := hits[i], hits[j]
+
+		switch {
+		case a.Platform != b.Platform:
+			return a.Platform < b.Platform
+		case a.ID != b.ID:
+			return a.ID < b.ID
+		case a.Revision != b.Revision:
+			return a.Revision < b.Revision
+		case a.Lang != b.Lang:
+			return a.Lang < b.Lang
+		default:
+			return a.StartAt < b.StartAt
+		}
+	})
+}
@@ -0,0 +1,176 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+func TestTokenize(t *testing.T) {
+	tests := []struct {
+		text string
+		want []string
+	}{
+		{"", nil},
+		{"Hello, World!", []string{"hello", "world"}},
+		{"HELLO hello", []string{"hello", "hello"}},
+		{"안녕하세요", []string{"안녕", "녕하", "하세", "세요"}},
+		{"안", []string{"안"}},
+		{"자막 편집기", []string{"자막", "편집", "집기"}},
+		{"iPhone15를 샀다", []string{"iphone15", "를", "샀다"}},
+		{"東京タワー", []string{"東京", "京タ", "タワ", "ワー"}},
+		{"café 123", []string{"café", "123"}},
+		// 자모가 나뉜 한글 (NFD) 도 합친 글자와 같은 토큰이 된다
+		{norm.NFD.String("안녕하세요"), []string{"안녕", "녕하", "하세", "세요"}},
+		{norm.NFD.String("Café"), []string{"café"}},
+		{"... --- !!!", nil},
+	}
+
+	for _, test := range tests {
+		if got := Tokenize(test.text); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("Tokenize(%q) = %q, want %q", test.text, got, test.want)
+		}
+	}
+}
+
+// setupSearch 는 빈 색인을 Search 로 쓰고 자막 파일을 만들어 색인한다
+func setupSearch(t *testing.T, files map[[4]string]string) {
+	t.Helper()
+	setupAPI(t)
+
+	previous := Search
+	Search = NewSearchIndex()
+	t.Cleanup(func() { Search = previous })
+
+	for key, srt := range files {
+		platform, id, lang, revision := key[0], key[1], key[2], key[3]
+
+		segments := []string{platform, id, lang + ".srt"}
+		if len(revision) != 0 {
+			segments = []string{platform, id, "version", revision + "-" + lang + ".srt"}
+		}
+
+		if err := SubtitleStore.WriteFile([]byte(srt), segments...); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	Search.Build()
+}
+
+func hitTexts(hits []SearchHit) []string {
+	texts := []string{}
+	for _, hit := range hits {
+		texts = append(texts, hit.ID+"/"+hit.Lang+hit.Revision+": "+hit.Text)
+	}
+
+	return texts
+}
+
+func TestSearch(t *testing.T) {
+	setupSearch(t, map[[4]string]string{
+		{"youtube", "bbbbbbbbbbb", "ko", ""}:   "1\n00:00:01,000 --> 00:00:02,000\n안녕하세요 여러분\n\n2\n00:00:05,000 --> 00:00:06,000\n반갑습니다\n",
+		{"youtube", "aaaaaaaaaaa", "ko", ""}:   "1\n00:00:03,000 --> 00:00:04,000\n여러분 안녕하세요\n\n2\n00:00:01,000 --> 00:00:02,000\n안녕히 가세요\n",
+		{"youtube", "aaaaaaaaaaa", "en", ""}:   "1\n00:00:03,000 --> 00:00:04,000\nHello, everyone\n",
+		{"youtube", "aaaaaaaaaaa", "ko", "r1"}: "1\n00:00:03,000 --> 00:00:04,000\n안녕하세요 옛날\n",
+		{"local", "clip", "ko", ""}:            "1\n00:00:01,000 --> 00:00:02,000\n안녕하세요\n",
+	})
+
+	if !Search.Ready() {
+		t.Fatal("index is not ready after Build")
+	}
+
+	tests := []struct {
+		name  string
+		query SearchQuery
+		want  []string
+	}{
+		{"sorted by platform, id, revision, lang, time", SearchQuery{Text: "안녕하세요"}, []string{
+			"clip/ko: 안녕하세요",
+			"aaaaaaaaaaa/ko: 여러분 안녕하세요",
+			"bbbbbbbbbbb/ko: 안녕하세요 여러분",
+		}},
+		{"platform", SearchQuery{Text: "안녕하세요", Platform: "local"}, []string{"clip/ko: 안녕하세요"}},
+		{"revisions", SearchQuery{Text: "안녕하세요", Platform: "youtube", Revisions: true}, []string{
+			"aaaaaaaaaaa/ko: 여러분 안녕하세요",
+			"aaaaaaaaaaa/kor1: 안녕하세요 옛날",
+			"bbbbbbbbbbb/ko: 안녕하세요 여러분",
+		}},
+		// 띄어쓴 검색어는 순서와 상관없이 모두 들어 있어야 한다
+		{"all terms", SearchQuery{Text: "여러분 안녕", Platform: "youtube"}, []string{
+			"aaaaaaaaaaa/ko: 여러분 안녕하세요",
+			"bbbbbbbbbbb/ko: 안녕하세요 여러분",
+		}},
+		// 글자 2개씩 나눈 토큰이 모두 있어도 붙여 쓴 검색어가 그대로 없으면 뺀다
+		{"exact substring", SearchQuery{Text: "안녕가세"}, []string{}},
+		{"latin case folding", SearchQuery{Text: "EVERYONE", Lang: "en"}, []string{"aaaaaaaaaaa/en: Hello, everyone"}},
+		{"lang", SearchQuery{Text: "hello", Lang: "ko"}, []string{}},
+		{"punctuation only", SearchQuery{Text: "!!!"}, []string{}},
+		{"decomposed query", SearchQuery{Text: norm.NFD.String("반갑습니다")}, []string{"bbbbbbbbbbb/ko: 반갑습니다"}},
+	}
+
+	for _, test := range tests {
+		if got := hitTexts(Search.Search(test.query)); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: Search(%+v) = %q, want %q", test.name, test.query, got, test.want)
+		}
+	}
+
+	hits := Search.Search(SearchQuery{Text: "반갑습니다"})
+	if len(hits) != 1 || hits[0].Index != 2 || hits[0].StartAt != 5*time.Second || hits[0].EndAt != 6*time.Second {
+		t.Errorf("hit = %+v", hits)
+	}
+}
+
+// 다시 저장하면 현재 자막은 새 내용으로 바뀌고, 이전 내용은 버전으로 찾을 수 있다
+func TestSearchRepublish(t *testing.T) {
+	setupSearch(t, nil)
+
+	if _, err := PublishSubtitle("youtube", proxyTestID, "ko", "", []byte("1\n00:00:01,000 --> 00:00:02,000\n첫 번째 자막\n"), "kim", "203.0.113.7"); err != nil {
+		t.Fatal(err)
+	}
+
+	if hits := Search.Search(SearchQuery{Text: "첫 번째"}); len(hits) != 1 || len(hits[0].Revision) != 0 {
+		t.Fatalf("hits after first publish = %+v", hits)
+	}
+
+	if _, err := PublishSubtitle("youtube", proxyTestID, "ko", "", []byte("1\n00:00:01,000 --> 00:00:02,000\n두 번째 자막\n"), "kim", "203.0.113.7"); err != nil {
+		t.Fatal(err)
+	}
+
+	if hits := Search.Search(SearchQuery{Text: "첫 번째"}); len(hits) != 0 {
+		t.Errorf("old text still found in the current subtitle: %+v", hits)
+	}
+
+	if hits := Search.Search(SearchQuery{Text: "두 번째"}); len(hits) != 1 {
+		t.Errorf("new text hits = %+v", hits)
+	}
+
+	hits := Search.Search(SearchQuery{Text: "첫 번째", Revisions: true})
+	if len(hits) != 1 || len(hits[0].Revision) == 0 {
+		t.Errorf("old text in revisions = %+v", hits)
+	}
+}
+
+func TestPairHits(t *testing.T) {
+	source := []SearchHit{
+		{Platform: "youtube", ID: "a", Lang: "en", StartAt: time.Second, EndAt: 2 * time.Second, Text: "hello"},
+		{Platform: "youtube", ID: "a", Lang: "en", StartAt: 5 * time.Second, EndAt: 6 * time.Second, Text: "bye"},
+		{Platform: "youtube", ID: "b", Lang: "en", StartAt: time.Second, EndAt: 2 * time.Second, Text: "hello"},
+	}
+
+	target := []SearchHit{
+		// 시간이 닿기만 하고 겹치지 않는다
+		{Platform: "youtube", ID: "a", Lang: "ko", StartAt: 2 * time.Second, EndAt: 3 * time.Second, Text: "다음"},
+		{Platform: "youtube", ID: "a", Lang: "ko", StartAt: 1500 * time.Millisecond, EndAt: 3 * time.Second, Text: "안녕"},
+		{Platform: "youtube", ID: "a", Lang: "en", StartAt: 5 * time.Second, EndAt: 6 * time.Second, Text: "bye"},
+		{Platform: "youtube", ID: "b", Lang: "ko", Revision: "r1", StartAt: time.Second, EndAt: 2 * time.Second, Text: "옛날"},
+	}
+
+	paired := PairHits(source, target)
+
+	if len(paired) != 1 || paired[0].Text != "hello" || paired[0].ID != "a" || paired[0].Pair == nil || paired[0].Pair.Text != "안녕" {
+		t.Errorf("paired = %+v", paired)
+	}
+}
@@ -0,0 +1,307 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/kkdai/youtube/v2"
+)
+
+// VideoSource 는 platform 별로 영상 ID 확인, 재생 주소, 영상 정보를 가져온다
+type VideoSource interface {
+	ValidateID(id string) error
+	Resolve(id string) ([]Stream, error)
+	Metadata(id string) (*VideoMetadata, error)
+}
+
+type Stream struct {
+	URL      string `json:"url"`
+	Itag     int    `json:"itag,omitempty"`
+	MimeType string `json:"mimeType"`
+	Quality  string `json:"quality"`
+	Bitrate  int    `json:"bitrate,omitempty"`
+	Width    int    `json:"width,omitempty"`
+	Height   int    `json:"height,omitempty"`
+	HasAudio bool   `json:"hasAudio"`
+	HasVideo bool   `json:"hasVideo"`
+}
+
+// Playable 은 영상과 소리가 함께 들어있어 <video> 에 바로 넣을 수 있는지 확인한다
+func (s Stream) Playable() bool {
+	return s.HasAudio && s.HasVideo
+}
+
+type VideoMetadata struct {
+	Title     string        `json:"title"`
+	Duration  time.Duration `json:"duration"`
+	Channel   string        `json:"channel"`
+	Thumbnail string        `json:"thumbnail"`
+	FirstSeen time.Time     `json:"firstSeen"`
+	Project   string        `json:"project,omitempty"`
+}
+
+// youtube 요청 하나를 기다리는 시간
+const youtubeTimeout = 30 * time.Second
+
+var Sources = map[string]VideoSource{
+	"youtube": NewYoutubeSource(&http.Client{Timeout: youtubeTimeout}),
+	"http":    httpSource{},
+	"local":   localSource{},
+}
+
+var localMediaExts = []string{".mp4", ".webm", ".ogv", ".wav"}
+
+func GetSource(platform, id string) (VideoSource, error) {
+	source := Sources[platform]
+	if source == nil {
+		return nil, fmt.Errorf("unknown platform: %s", platform)
+	}
+
+	if err := source.ValidateID(id); err != nil {
+		return nil, err
+	}
+
+	return source, nil
+}
+
+// youtubeSource 는 kkdai/youtube 로 영상 정보를 가져오고, 서명이 걸린 주소도 풀어서 돌려준다
+type youtubeSource struct {
+	client *youtube.Client
+}
+
+// NewYoutubeSource 는 httpClient 로 youtube 에 요청한다, Transport 를 바꾸면 다른 서버 (녹화해둔 응답) 로 보낼 수 있다
+func NewYoutubeSource(httpClient *http.Client) youtubeSource {
+	return youtubeSource{client: &youtube.Client{HTTPClient: httpClient}}
+}
+
+var youtubeIDRegx = regexp.MustCompile(`^[A-Za-z0-9_-]{11}$`)
+
+func (youtubeSource) ValidateID(id string) error {
+	if !youtubeIDRegx.MatchString(id) {
+		return fmt.Errorf("invalid youtube id: %s", id)
+	}
+
+	return nil
+}
+
+func (s youtubeSource) Resolve(id string) ([]Stream, error) {
+	fmt.Printf("다운로드: https://www.youtube.com/watch?v=%s\n", id)
+
+	video, err := s.client.GetVideo(id)
+	if err != nil {
+		return nil, err
+	}
+
+	var streams []Stream
+	for i := range video.Formats {
+		format := &video.Formats[i]
+
+		streamURL, err := s.client.GetStreamURL(video, format)
+		if err != nil {
+			fmt.Printf("itag %d: %s\n", format.ItagNo, err)
+			continue
+		}
+
+		quality := format.QualityLabel
+		if len(quality) == 0 {
+			quality = format.Quality
+		}
+
+		streams = append(streams, Stream{
+			URL:      streamURL,
+			Itag:     format.ItagNo,
+			MimeType: format.MimeType,
+			Quality:  quality,
+			Bitrate:  format.Bitrate,
+			Width:    format.Width,
+			Height:   format.Height,
+			HasAudio: format.AudioChannels > 0,
+			HasVideo: strings.HasPrefix(format.MimeType, "video/"),
+		})
+	}
+
+	if len(streams) == 0 {
+		return nil, fmt.Errorf("no playable stream")
+	}
+
+	SortStreams(streams)
+
+	return streams, nil
+}
+
+func (s youtubeSource) Metadata(id string) (*VideoMetadata, error) {
+	video, err := s.client.GetVideo(id)
+	if err != nil {
+		return nil, err
+	}
+
+	metadata := &VideoMetadata{
+		Title:    video.Title,
+		Duration: video.Duration,
+		Channel:  video.Author,
+	}
+
+	if thumbnails := video.Thumbnails; len(thumbnails) != 0 {
+		metadata.Thumbnail = thumbnails[len(thumbnails)-1].URL
+	}
+
+	return metadata, nil
+}
+
+// SortStreams 는 <video> 하나로 재생할 수 있는(영상+소리) 스트림을 앞에, 그 안에서는 화질이 높은 순으로 정렬한다
+func SortStreams(streams []Stream) {
+	sort.SliceStable(streams, func(i, j int) bool {
+		a, b := streams[i], streams[j]
+
+		if a.Playable() != b.Playable() {
+			return a.Playable()
+		}
+
+		if a.Height != b.Height {
+			return a.Height > b.Height
+		}
+
+		return a.Bitrate > b.Bitrate
+	})
+}
+
+// httpSource 는 MediaDir/http/<id>.url 파일에 적힌 주소의 영상을 그대로 재생한다
+type httpSource struct{}
+
+var mediaIDRegx = regexp.MustCompile(`^[A-Za-z0-9_-]{1,64}$`)
+
+func (httpSource) ValidateID(id string) error {
+	if !mediaIDRegx.MatchString(id) {
+		return fmt.Errorf("invalid media id: %s", id)
+	}
+
+	return nil
+}
+
+func (httpSource) mediaURL(id string) (*url.URL, error) {
+	file, err := MediaStore.ReadFile("http", id+".url")
+	if err != nil {
+		return nil, err
+	}
+
+	u, err := url.Parse(strings.TrimSpace(string(file)))
+	if err != nil {
+		return nil, err
+	}
+
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("unsupported scheme: %s", u.Scheme)
+	}
+
+	return u, nil
+}
+
+func (s httpSource) Resolve(id string) ([]Stream, error) {
+	u, err := s.mediaURL(id)
+	if err != nil {
+		return nil, err
+	}
+
+	mimeType := mimeTypeByExt(filepath.Ext(u.Path))
+
+	return []Stream{{
+		URL:      u.String(),
+		MimeType: mimeType,
+		HasAudio: true,
+		HasVideo: !strings.HasPrefix(mimeType, "audio/"),
+	}}, nil
+}
+
+func (s httpSource) Metadata(id string) (*VideoMetadata, error) {
+	u, err := s.mediaURL(id)
+	if err != nil {
+		return nil, err
+	}
+
+	return &VideoMetadata{
+		Title:   filepath.Base(u.Path),
+		Channel: u.Host,
+	}, nil
+}
+
+// localSource 는 MediaDir/local 에 있는 파일을 미디어 프록시로 재생한다
+type localSource struct{}
+
+func (localSource) ValidateID(id string) error {
+	if !mediaIDRegx.MatchString(id) {
+		return fmt.Errorf("invalid media id: %s", id)
+	}
+
+	return nil
+}
+
+func (localSource) file(id string) (string, error) {
+	for _, ext := range localMediaExts {
+		name := id + ext
+		path, err := MediaStore.Path("local", name)
+		if err != nil {
+			return "", err
+		}
+
+		if _, err := os.Stat(path); err == nil {
+			return name, nil
+		}
+	}
+
+	return "", fmt.Errorf("media not found: %s", id)
+}
+
+func (s localSource) Resolve(id string) ([]Stream, error) {
+	name, err := s.file(id)
+	if err != nil {
+		return nil, err
+	}
+
+	mimeType := mimeTypeByExt(filepath.Ext(name))
+
+	return []Stream{{
+		URL:      "/media/proxy?platform=local&id=" + id,
+		MimeType: mimeType,
+		HasAudio: true,
+		HasVideo: !strings.HasPrefix(mimeType, "audio/"),
+	}}, nil
+}
+
+func (s localSource) Metadata(id string) (*VideoMetadata, error) {
+	name, err := s.file(id)
+	if err != nil {
+		return nil, err
+	}
+
+	metadata := &VideoMetadata{
+		Title: name,
+	}
+
+	if audio, err := LoadAudio("local", id); err == nil {
+		metadata.Duration = time.Duration(audio.Duration() * float64(time.Second))
+	}
+
+	return metadata, nil
+}
+
+func mimeTypeByExt(ext string) string {
+	switch strings.ToLower(ext) {
+	case ".mp4", ".m4v":
+		return "video/mp4"
+	case ".webm":
+		return "video/webm"
+	case ".ogv":
+		return "video/ogg"
+	case ".wav":
+		return "audio/wav"
+	default:
+		return ""
+	}
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+const fixtureYoutubeID = "jNQXAC9IVRw"
+
+// fixtureTransport 는 youtube 로 가는 요청을 testdata/youtube 의 응답을 돌려주는 서버로 보낸다
+type fixtureTransport struct {
+	server *url.URL
+	paths  []string
+}
+
+func (t *fixtureTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	t.paths = append(t.paths, r.URL.Path)
+
+	r = r.Clone(r.Context())
+	r.URL.Scheme = t.server.Scheme
+	r.URL.Host = t.server.Host
+	r.Host = t.server.Host
+
+	return http.DefaultTransport.RoundTrip(r)
+}
+
+func newFixtureYoutube(t *testing.T, handler http.Handler) (youtubeSource, *fixtureTransport) {
+	t.Helper()
+
+	if handler == nil {
+		fixture := func(name string) http.HandlerFunc {
+			return func(w http.ResponseWriter, r *http.Request) {
+				http.ServeFile(w, r, filepath.Join("testdata", "youtube", name))
+			}
+		}
+
+		mux := http.NewServeMux()
+		mux.Handle("/youtubei/v1/player", fixture("player.json"))
+		mux.Handle("/embed/", fixture("embed.html"))
+		mux.Handle("/s/player/", fixture("base.js"))
+		handler = mux
+	}
+
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	u, _ := url.Parse(server.URL)
+	transport := &fixtureTransport{server: u}
+
+	return NewYoutubeSource(&http.Client{Transport: transport, Timeout: 5 * time.Second}), transport
+}
+
+func TestYoutubeResolve(t *testing.T) {
+	source, transport := newFixtureYoutube(t, nil)
+
+	streams, err := source.Resolve(fixtureYoutubeID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(streams) != 3 {
+		t.Fatalf("streams = %d, want 3", len(streams))
+	}
+
+	// 영상과 소리가 함께 있는 스트림이 화질이 낮아도 앞에 온다
+	if first := streams[0]; first.Itag != 18 || !first.Playable() || first.Quality != "360p" {
+		t.Errorf("first stream = %+v, want playable itag 18", first)
+	}
+
+	byItag := make(map[int]Stream)
+	for _, stream := range streams {
+		byItag[stream.Itag] = stream
+	}
+
+	// 서명이 걸린 형식은 base.js 의 순서대로 풀어서 sp 이름으로 붙인다 (splice 2, reverse, swap 3)
+	ciphered, ok := byItag[137]
+	if !ok {
+		t.Fatal("ciphered itag 137 missing")
+	}
+
+	u, err := url.Parse(ciphered.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if sig := u.Query().Get("sig"); sig != "egfhdcba" {
+		t.Errorf("sig = %q, want %q", sig, "egfhdcba")
+	}
+
+	if u.Host != "rr1---sn-fixture.googlevideo.com" || u.Query().Get("itag") != "137" {
+		t.Errorf("ciphered url = %s", ciphered.URL)
+	}
+
+	if ciphered.HasAudio || !ciphered.HasVideo || ciphered.Height != 1080 {
+		t.Errorf("itag 137 = %+v, want video only 1080p", ciphered)
+	}
+
+	if audio := byItag[140]; !audio.HasAudio || audio.HasVideo {
+		t.Errorf("itag 140 = %+v, want audio only", audio)
+	}
+
+	fetchedPlayer := false
+	for _, path := range transport.paths {
+		if strings.HasSuffix(path, "/base.js") {
+			fetchedPlayer = true
+		}
+	}
+
+	if !fetchedPlayer {
+		t.Errorf("base.js was not fetched: %v", transport.paths)
+	}
+}
+
+func TestYoutubeMetadata(t *testing.T) {
+	source, _ := newFixtureYoutube(t, nil)
+
+	metadata, err := source.Metadata(fixtureYoutubeID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if metadata.Title != "Fixture video" || metadata.Channel != "Fixture channel" {
+		t.Errorf("metadata = %+v", metadata)
+	}
+
+	if metadata.Duration != 19*time.Second {
+		t.Errorf("duration = %s, want 19s", metadata.Duration)
+	}
+
+	if !strings.HasSuffix(metadata.Thumbnail, "/hqdefault.jpg") {
+		t.Errorf("thumbnail = %s, want the largest", metadata.Thumbnail)
+	}
+}
+
+func TestYoutubeResolveUpstreamError(t *testing.T) {
+	source, _ := newFixtureYoutube(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+	}))
+
+	if _, err := source.Resolve(fixtureYoutubeID); err == nil {
+		t.Error("Resolve succeeded against a failing upstream")
+	}
+}
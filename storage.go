@@ -0,0 +1,136 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	storageDirPerm  = 0755
+	storageFilePerm = 0644
+)
+
+// Storage 는 Root 밖으로 나가는 경로를 만들지 않고, 파일은 임시 파일에 다 쓴 뒤 이름을 바꿔서 저장한다
+type Storage struct {
+	Root string
+}
+
+var (
+	SubtitleStore = &Storage{Root: SubtitleDir}
+	MediaStore    = &Storage{Root: MediaDir}
+	GlossaryStore = &Storage{Root: GlossaryDir}
+)
+
+// EncodeSegment 는 경로 한 칸을 확인하고, 구분자나 % 같은 글자는 이스케이프한다
+func EncodeSegment(segment string) (string, error) {
+	if len(segment) == 0 || segment == "." || segment == ".." {
+		return "", fmt.Errorf("invalid path segment: %q", segment)
+	}
+
+	if !utf8.ValidString(segment) || strings.ContainsRune(segment, 0) {
+		return "", fmt.Errorf("invalid path segment: %q", segment)
+	}
+
+	return url.PathEscape(segment), nil
+}
+
+func (s *Storage) Path(segments ...string) (string, error) {
+	root := filepath.Clean(s.Root)
+	parts := []string{root}
+
+	for _, segment := range segments {
+		encoded, err := EncodeSegment(segment)
+		if err != nil {
+			return "", err
+		}
+
+		parts = append(parts, encoded)
+	}
+
+	path := filepath.Join(parts...)
+
+	// 이스케이프했으니 나갈 수 없지만 한 번 더 확인한다
+	rel, err := filepath.Rel(root, path)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("path escapes storage root: %s", path)
+	}
+
+	return path, nil
+}
+
+func (s *Storage) ReadFile(segments ...string) ([]byte, error) {
+	path, err := s.Path(segments...)
+	if err != nil {
+		return nil, err
+	}
+
+	return ioutil.ReadFile(path)
+}
+
+func (s *Storage) MkdirAll(segments ...string) (string, error) {
+	path, err := s.Path(segments...)
+	if err != nil {
+		return "", err
+	}
+
+	return path, os.MkdirAll(path, storageDirPerm)
+}
+
+// WriteFile 은 필요하면 상위 폴더를 만들고 파일을 통째로 바꾼다
+func (s *Storage) WriteFile(data []byte, segments ...string) error {
+	path, err := s.Path(segments...)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), storageDirPerm); err != nil {
+		return err
+	}
+
+	return WriteFileAtomic(path, data, storageFilePerm)
+}
+
+// WriteFileAtomic 은 같은 폴더의 임시 파일에 쓰고 rename 해서, 읽는 쪽이 반쯤 쓰인 파일을 보지 않게 한다
+func WriteFileAtomic(path string, data []byte, perm os.FileMode) error {
+	temp, err := ioutil.TempFile(filepath.Dir(path), "."+filepath.Base(path)+".tmp-")
+	if err != nil {
+		return err
+	}
+
+	succeeded := false
+	defer func() {
+		if !succeeded {
+			temp.Close()
+			os.Remove(temp.Name())
+		}
+	}()
+
+	if _, err := temp.Write(data); err != nil {
+		return err
+	}
+
+	if err := temp.Sync(); err != nil {
+		return err
+	}
+
+	if err := temp.Chmod(perm); err != nil {
+		return err
+	}
+
+	if err := temp.Close(); err != nil {
+		return err
+	}
+
+	if err := os.Rename(temp.Name(), path); err != nil {
+		return err
+	}
+
+	succeeded = true
+
+	return nil
+}
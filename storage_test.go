@@ -0,0 +1,181 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestEncodeSegment(t *testing.T) {
+	tests := []struct {
+		segment string
+		want    string
+		err     bool
+	}{
+		{segment: "ko.srt", want: "ko.srt"},
+		{segment: "jNQXAC9IVRw", want: "jNQXAC9IVRw"},
+		{segment: "", err: true},
+		{segment: ".", err: true},
+		{segment: "..", err: true},
+		{segment: "...", want: "..."},
+		{segment: "%2e%2e", want: "%252e%252e"},
+		{segment: "a/b", want: "a%2Fb"},
+		{segment: "../../etc/passwd", want: "..%2F..%2Fetc%2Fpasswd"},
+		{segment: "/etc/passwd", want: "%2Fetc%2Fpasswd"},
+		{segment: "a\x00b", err: true},
+		{segment: "\xff\xfe", err: true},
+		{segment: "자막", want: "%EC%9E%90%EB%A7%89"},
+	}
+
+	for _, test := range tests {
+		got, err := EncodeSegment(test.segment)
+		if test.err {
+			if err == nil {
+				t.Errorf("EncodeSegment(%q) = %q, want error", test.segment, got)
+			}
+
+			continue
+		}
+
+		if err != nil || got != test.want {
+			t.Errorf("EncodeSegment(%q) = %q, %v, want %q", test.segment, got, err, test.want)
+		}
+	}
+}
+
+func TestStoragePath(t *testing.T) {
+	root := t.TempDir()
+	store := &Storage{Root: root}
+
+	tests := []struct {
+		segments []string
+		want     string
+		err      bool
+	}{
+		{segments: []string{"youtube", "jNQXAC9IVRw", "ko.srt"}, want: "youtube/jNQXAC9IVRw/ko.srt"},
+		{segments: nil, want: ""},
+		{segments: []string{"youtube", ".."}, err: true},
+		{segments: []string{"..", "account"}, err: true},
+		{segments: []string{"youtube", "%2e%2e", "ko.srt"}, want: "youtube/%252e%252e/ko.srt"},
+		{segments: []string{"youtube", "../../../etc/passwd"}, want: "youtube/..%2F..%2F..%2Fetc%2Fpasswd"},
+		{segments: []string{"/etc/passwd"}, want: "%2Fetc%2Fpasswd"},
+		{segments: []string{"youtube", "a\x00b"}, err: true},
+		{segments: []string{"youtube", "\xc3\x28"}, err: true},
+		{segments: []string{"youtube", ""}, err: true},
+	}
+
+	for _, test := range tests {
+		got, err := store.Path(test.segments...)
+		if test.err {
+			if err == nil {
+				t.Errorf("Path(%q) = %q, want error", test.segments, got)
+			}
+
+			continue
+		}
+
+		want := filepath.Join(root, filepath.FromSlash(test.want))
+		if err != nil || got != want {
+			t.Errorf("Path(%q) = %q, %v, want %q", test.segments, got, err, want)
+		}
+
+		if rel, _ := filepath.Rel(root, got); strings.HasPrefix(rel, "..") {
+			t.Errorf("Path(%q) = %q escapes the root", test.segments, got)
+		}
+	}
+}
+
+// tempFiles 는 WriteFileAtomic 이 남긴 임시 파일
+func tempFiles(t *testing.T, dir string) []string {
+	t.Helper()
+
+	files, err := filepath.Glob(filepath.Join(dir, ".*.tmp-*"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return files
+}
+
+func TestWriteFileAtomic(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "ko.srt")
+
+	if err := os.WriteFile(path, []byte("old"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := WriteFileAtomic(path, []byte("new"), storageFilePerm); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil || string(data) != "new" {
+		t.Errorf("content = %q, %v, want %q", data, err, "new")
+	}
+
+	stat, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if stat.Mode().Perm() != storageFilePerm {
+		t.Errorf("mode = %v, want %v", stat.Mode().Perm(), os.FileMode(storageFilePerm))
+	}
+
+	if files := tempFiles(t, dir); len(files) != 0 {
+		t.Errorf("temporary files left: %v", files)
+	}
+}
+
+// rename 에 실패하면 (바꿀 자리에 폴더가 있으면) 원래 것은 그대로 두고 임시 파일을 지운다
+func TestWriteFileAtomicCleanup(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "ko.srt")
+
+	if err := os.MkdirAll(filepath.Join(path, "keep"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := WriteFileAtomic(path, []byte("new"), storageFilePerm); err == nil {
+		t.Fatal("WriteFileAtomic over a directory succeeded")
+	}
+
+	if stat, err := os.Stat(filepath.Join(path, "keep")); err != nil || !stat.IsDir() {
+		t.Errorf("existing directory was changed: %v", err)
+	}
+
+	if files := tempFiles(t, dir); len(files) != 0 {
+		t.Errorf("temporary files left: %v", files)
+	}
+}
+
+func TestWriteFileAtomicMissingDir(t *testing.T) {
+	dir := t.TempDir()
+
+	if err := WriteFileAtomic(filepath.Join(dir, "missing", "ko.srt"), []byte("new"), storageFilePerm); err == nil {
+		t.Fatal("WriteFileAtomic into a missing directory succeeded")
+	}
+
+	if files := tempFiles(t, dir); len(files) != 0 {
+		t.Errorf("temporary files left: %v", files)
+	}
+}
+
+func TestStorageWriteFile(t *testing.T) {
+	store := &Storage{Root: t.TempDir()}
+
+	if err := store.WriteFile([]byte("1"), "youtube", "jNQXAC9IVRw", "ko.srt"); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := store.ReadFile("youtube", "jNQXAC9IVRw", "ko.srt")
+	if err != nil || string(data) != "1" {
+		t.Errorf("ReadFile = %q, %v", data, err)
+	}
+
+	if err := store.WriteFile([]byte("1"), "youtube", "..", "ko.srt"); err == nil {
+		t.Error("WriteFile outside the root succeeded")
+	}
+}
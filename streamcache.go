@@ -0,0 +1,338 @@
+package main
+
+import (
+	"expvar"
+	"fmt"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	// 만료 시간을 알 수 없는 주소(로컬 파일 등)를 보관하는 시간
+	streamDefaultTTL = time.Hour
+	// 만료 이만큼 전부터는 캐시를 돌려주면서 뒤에서 새로 받아둔다
+	streamRefreshMargin = 5 * time.Minute
+
+	// 보관하는 영상 수, 넘으면 만료된 것부터 지우고 그래도 많으면 가장 먼저 만료되는 것을 지운다
+	streamCacheMaxEntries = 1000
+	// 영상 정보는 만료가 없으므로 오래 보관한 것부터 지운다
+	streamMetadataMaxEntries = 5000
+)
+
+var streamCacheStats = expvar.NewMap("stream_cache")
+
+// StreamCache 는 platform/id 별로 받아온 스트림 주소를 expire 값에 맞춰 보관하고, 같은 영상을 동시에 여러 번 받지 않게 한다
+type StreamCache struct {
+	mu       sync.Mutex
+	entries  map[string]*streamEntry
+	inflight map[string]*streamCall
+	metadata map[string]*VideoMetadata
+
+	// metadataOrder 는 metadata 에 넣은 순서
+	metadataOrder []string
+
+	maxEntries  int
+	maxMetadata int
+
+	now func() time.Time
+}
+
+type streamEntry struct {
+	streams []Stream
+	expires []time.Time
+}
+
+// streamCall 은 받아오는 중인 스트림 주소나 영상 정보
+type streamCall struct {
+	wg       sync.WaitGroup
+	streams  []Stream
+	metadata *VideoMetadata
+	err      error
+}
+
+var Streams = NewStreamCache()
+
+func NewStreamCache() *StreamCache {
+	return &StreamCache{
+		entries:  make(map[string]*streamEntry),
+		inflight: make(map[string]*streamCall),
+		metadata: make(map[string]*VideoMetadata),
+
+		maxEntries:  streamCacheMaxEntries,
+		maxMetadata: streamMetadataMaxEntries,
+
+		now: time.Now,
+	}
+}
+
+// StreamExpiry 는 googlevideo 주소의 expire 값(유닉스 초)을 읽는다
+func StreamExpiry(streamURL string, now time.Time) time.Time {
+	u, err := url.Parse(streamURL)
+	if err != nil {
+		return now.Add(streamDefaultTTL)
+	}
+
+	expire, err := strconv.ParseInt(u.Query().Get("expire"), 10, 64)
+	if err != nil {
+		return now.Add(streamDefaultTTL)
+	}
+
+	return time.Unix(expire, 0)
+}
+
+// expiry 는 itag 가 0 이면 모든 스트림 중 가장 먼저 만료되는 시간을 돌려준다
+func (e *streamEntry) expiry(itag int) (time.Time, bool) {
+	var expiry time.Time
+	var found bool
+
+	for i, s := range e.streams {
+		if itag != 0 && s.Itag != itag {
+			continue
+		}
+
+		if !found || e.expires[i].Before(expiry) {
+			expiry = e.expires[i]
+			found = true
+		}
+	}
+
+	return expiry, found
+}
+
+// Resolve 는 itag 가 0 이면 모든 스트림을, 아니면 그 itag 스트림만 돌려준다
+func (c *StreamCache) Resolve(platform, id string, itag int) ([]Stream, error) {
+	key := platform + "/" + id
+
+	c.mu.Lock()
+	if entry := c.entries[key]; entry != nil {
+		if expiry, found := entry.expiry(itag); found {
+			now := c.now()
+
+			if now.Before(expiry.Add(-streamRefreshMargin)) {
+				c.mu.Unlock()
+				streamCacheStats.Add("hits", 1)
+
+				return filterStreams(entry.streams, itag), nil
+			}
+
+			if now.Before(expiry) {
+				c.mu.Unlock()
+				streamCacheStats.Add("hits", 1)
+				streamCacheStats.Add("refreshes", 1)
+
+				go func() {
+					if _, err := c.resolve(platform, id); err != nil {
+						fmt.Println(err)
+					}
+				}()
+
+				return filterStreams(entry.streams, itag), nil
+			}
+		} else if expiry, _ := entry.expiry(0); c.now().Before(expiry.Add(-streamRefreshMargin)) {
+			// 새로 받은 목록에 없는 itag 는 다시 받아도 없으므로 밖으로 요청하지 않는다
+			c.mu.Unlock()
+			streamCacheStats.Add("hits", 1)
+
+			return nil, fmt.Errorf("format not found: %d", itag)
+		}
+	}
+	c.mu.Unlock()
+
+	streamCacheStats.Add("misses", 1)
+
+	streams, err := c.resolve(platform, id)
+	if err != nil {
+		return nil, err
+	}
+
+	streams = filterStreams(streams, itag)
+	if len(streams) == 0 {
+		return nil, fmt.Errorf("format not found: %d", itag)
+	}
+
+	return streams, nil
+}
+
+// resolve 는 같은 영상을 받는 중이면 그 결과를 기다린다
+func (c *StreamCache) resolve(platform, id string) ([]Stream, error) {
+	key := platform + "/" + id
+
+	c.mu.Lock()
+	if call := c.inflight[key]; call != nil {
+		c.mu.Unlock()
+		streamCacheStats.Add("deduplicated", 1)
+
+		call.wg.Wait()
+		return call.streams, call.err
+	}
+
+	call := &streamCall{}
+	call.wg.Add(1)
+	c.inflight[key] = call
+	c.mu.Unlock()
+
+	source, err := GetSource(platform, id)
+	if err == nil {
+		call.streams, call.err = source.Resolve(id)
+	} else {
+		call.err = err
+	}
+
+	c.mu.Lock()
+	delete(c.inflight, key)
+	if call.err == nil {
+		now := c.now()
+		entry := &streamEntry{
+			streams: call.streams,
+			expires: make([]time.Time, len(call.streams)),
+		}
+
+		for i, s := range call.streams {
+			entry.expires[i] = StreamExpiry(s.URL, now)
+		}
+
+		if _, ok := c.entries[key]; !ok {
+			c.evict(now)
+		}
+		c.entries[key] = entry
+	} else {
+		streamCacheStats.Add("errors", 1)
+	}
+	c.mu.Unlock()
+
+	call.wg.Done()
+
+	return call.streams, call.err
+}
+
+// lastExpiry 는 모든 스트림이 만료되는 시간
+func (e *streamEntry) lastExpiry() time.Time {
+	var last time.Time
+	for _, expiry := range e.expires {
+		if expiry.After(last) {
+			last = expiry
+		}
+	}
+
+	return last
+}
+
+// evict 는 새 영상을 넣기 전에 부른다, c.mu 를 잡고 있어야 한다
+func (c *StreamCache) evict(now time.Time) {
+	if len(c.entries) < c.maxEntries {
+		return
+	}
+
+	for key, entry := range c.entries {
+		if !now.Before(entry.lastExpiry()) {
+			delete(c.entries, key)
+			streamCacheStats.Add("evictions", 1)
+		}
+	}
+
+	for len(c.entries) >= c.maxEntries {
+		var oldest string
+		var oldestExpiry time.Time
+
+		for key, entry := range c.entries {
+			if expiry := entry.lastExpiry(); len(oldest) == 0 || expiry.Before(oldestExpiry) {
+				oldest, oldestExpiry = key, expiry
+			}
+		}
+
+		delete(c.entries, oldest)
+		streamCacheStats.Add("evictions", 1)
+	}
+}
+
+// Cached 는 Resolve 가 밖으로 요청하지 않고 캐시로 답할 수 있으면 true
+func (c *StreamCache) Cached(platform, id string, itag int) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry := c.entries[platform+"/"+id]
+	if entry == nil {
+		return false
+	}
+
+	expiry, found := entry.expiry(itag)
+
+	return found && c.now().Before(expiry)
+}
+
+// Invalidate 는 업스트림이 주소를 거부했을 때 캐시를 버린다
+func (c *StreamCache) Invalidate(platform, id string) {
+	c.mu.Lock()
+	delete(c.entries, platform+"/"+id)
+	c.mu.Unlock()
+
+	streamCacheStats.Add("invalidations", 1)
+}
+
+// Metadata 는 한 번 받은 영상 정보를 maxMetadata 개까지 보관한다, 같은 영상을 받는 중이면 그 결과를 기다린다
+func (c *StreamCache) Metadata(platform, id string) (*VideoMetadata, error) {
+	key := platform + "/" + id
+	// 스트림 주소와 같은 inflight 를 쓰므로 키를 나눈다
+	inflightKey := "metadata:" + key
+
+	c.mu.Lock()
+	if metadata := c.metadata[key]; metadata != nil {
+		c.mu.Unlock()
+		return metadata, nil
+	}
+
+	if call := c.inflight[inflightKey]; call != nil {
+		c.mu.Unlock()
+		streamCacheStats.Add("deduplicated", 1)
+
+		call.wg.Wait()
+		return call.metadata, call.err
+	}
+
+	call := &streamCall{}
+	call.wg.Add(1)
+	c.inflight[inflightKey] = call
+	c.mu.Unlock()
+
+	source, err := GetSource(platform, id)
+	if err == nil {
+		call.metadata, call.err = source.Metadata(id)
+	} else {
+		call.err = err
+	}
+
+	c.mu.Lock()
+	delete(c.inflight, inflightKey)
+	if call.err == nil {
+		if _, ok := c.metadata[key]; !ok {
+			for len(c.metadataOrder) != 0 && len(c.metadata) >= c.maxMetadata {
+				delete(c.metadata, c.metadataOrder[0])
+				c.metadataOrder = c.metadataOrder[1:]
+			}
+
+			c.metadataOrder = append(c.metadataOrder, key)
+		}
+		c.metadata[key] = call.metadata
+	}
+	c.mu.Unlock()
+
+	call.wg.Done()
+
+	return call.metadata, call.err
+}
+
+func filterStreams(streams []Stream, itag int) []Stream {
+	if itag == 0 {
+		return streams
+	}
+
+	for _, s := range streams {
+		if s.Itag == itag {
+			return []Stream{s}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// countingSource 는 youtube 대신 쓴다, 밖으로 나가는 요청 수를 세고 release 가 닫힐 때까지 기다린다
+type countingSource struct {
+	youtubeSource
+
+	resolves, metadata int32
+	release            chan struct{}
+}
+
+func (s *countingSource) Resolve(id string) ([]Stream, error) {
+	atomic.AddInt32(&s.resolves, 1)
+	<-s.release
+
+	expire := time.Now().Add(6 * time.Hour).Unix()
+
+	return []Stream{
+		{URL: fmt.Sprintf("https://r1.googlevideo.com/videoplayback?itag=18&expire=%d", expire), Itag: 18, HasAudio: true, HasVideo: true},
+		{URL: fmt.Sprintf("https://r1.googlevideo.com/videoplayback?itag=22&expire=%d", expire), Itag: 22, HasAudio: true, HasVideo: true},
+	}, nil
+}
+
+func (s *countingSource) Metadata(id string) (*VideoMetadata, error) {
+	atomic.AddInt32(&s.metadata, 1)
+	<-s.release
+
+	return &VideoMetadata{Title: "Me at the zoo", Duration: 19 * time.Second}, nil
+}
+
+func setupCountingSource(t *testing.T) *countingSource {
+	t.Helper()
+	setupAPI(t)
+
+	source := &countingSource{release: make(chan struct{})}
+	Sources["youtube"] = source
+
+	return source
+}
+
+// 받아둔 목록에 없는 itag 는 다시 받지 않는다
+func TestStreamCacheMissingItag(t *testing.T) {
+	source := setupCountingSource(t)
+	close(source.release)
+
+	cache := NewStreamCache()
+
+	if streams, err := cache.Resolve("youtube", proxyTestID, 22); err != nil || len(streams) != 1 || streams[0].Itag != 22 {
+		t.Fatalf("Resolve(22) = %v, %v", streams, err)
+	}
+
+	for i := 0; i < 10; i++ {
+		if _, err := cache.Resolve("youtube", proxyTestID, 137); err == nil {
+			t.Fatal("Resolve(137) found a missing format")
+		}
+	}
+
+	if n := atomic.LoadInt32(&source.resolves); n != 1 {
+		t.Errorf("upstream resolves = %d, want 1", n)
+	}
+
+	// 목록이 만료될 때가 되면 없는 itag 도 다시 받아서 확인한다
+	cache.now = func() time.Time { return time.Now().Add(6 * time.Hour) }
+
+	if _, err := cache.Resolve("youtube", proxyTestID, 137); err == nil {
+		t.Fatal("Resolve(137) found a missing format")
+	}
+
+	if n := atomic.LoadInt32(&source.resolves); n != 2 {
+		t.Errorf("upstream resolves after expiry = %d, want 2", n)
+	}
+}
+
+func TestStreamCacheResolveConcurrent(t *testing.T) {
+	source := setupCountingSource(t)
+	cache := NewStreamCache()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			if _, err := cache.Resolve("youtube", proxyTestID, 0); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+
+	time.Sleep(50 * time.Millisecond)
+	close(source.release)
+	wg.Wait()
+
+	if n := atomic.LoadInt32(&source.resolves); n != 1 {
+		t.Errorf("upstream resolves = %d, want 1", n)
+	}
+}
+
+// 편집기와 대시보드가 같은 영상 정보를 동시에 요청해도 한 번만 받는다
+func TestStreamCacheMetadataConcurrent(t *testing.T) {
+	source := setupCountingSource(t)
+	cache := NewStreamCache()
+
+	results := make([]*VideoMetadata, 8)
+
+	var wg sync.WaitGroup
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			metadata, err := cache.Metadata("youtube", proxyTestID)
+			if err != nil {
+				t.Error(err)
+			}
+
+			results[i] = metadata
+		}(i)
+	}
+
+	time.Sleep(50 * time.Millisecond)
+	close(source.release)
+	wg.Wait()
+
+	if n := atomic.LoadInt32(&source.metadata); n != 1 {
+		t.Errorf("upstream metadata requests = %d, want 1", n)
+	}
+
+	for _, metadata := range results {
+		if metadata == nil || metadata.Title != "Me at the zoo" {
+			t.Fatalf("metadata = %v", metadata)
+		}
+	}
+
+	if _, err := cache.Metadata("youtube", proxyTestID); err != nil || atomic.LoadInt32(&source.metadata) != 1 {
+		t.Errorf("cached metadata was requested again: %v", err)
+	}
+
+	// 스트림 주소는 영상 정보와 따로 받는다
+	if _, err := cache.Resolve("youtube", proxyTestID, 0); err != nil || atomic.LoadInt32(&source.resolves) != 1 {
+		t.Errorf("Resolve after Metadata = %v, resolves %d", err, atomic.LoadInt32(&source.resolves))
+	}
+}
+
+func TestStreamCacheMetadataLimit(t *testing.T) {
+	source := setupCountingSource(t)
+	close(source.release)
+
+	cache := NewStreamCache()
+	cache.maxMetadata = 2
+
+	for _, id := range []string{"aaaaaaaaaaa", "bbbbbbbbbbb", "ccccccccccc"} {
+		if _, err := cache.Metadata("youtube", id); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if len(cache.metadata) != 2 || cache.metadata["youtube/aaaaaaaaaaa"] != nil {
+		t.Errorf("metadata = %d entries, oldest kept = %v", len(cache.metadata), cache.metadata["youtube/aaaaaaaaaaa"] != nil)
+	}
+}
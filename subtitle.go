@@ -0,0 +1,155 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/asticode/go-astisub"
+)
+
+// 언어 코드 (ko, en, pt-BR, zh-Hant 등), 파일 이름에 그대로 쓰이므로 이것만 받는다
+var langRegx = regexp.MustCompile(`^[a-z]{2,3}(-[A-Za-z0-9]{2,8})?$`)
+
+func ValidateLang(lang string) error {
+	if !langRegx.MatchString(lang) {
+		return fmt.Errorf("invalid lang: %s", lang)
+	}
+
+	return nil
+}
+
+func SubtitlePath(platform, id, lang string) (string, error) {
+	return SubtitleStore.Path(platform, id, lang+".srt")
+}
+
+// subtitleFiles 는 영상의 언어별 자막 파일 (버전, 초안 제외)
+func subtitleFiles(platform, id string) ([]string, error) {
+	dir, err := SubtitleStore.Path(platform, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return filepath.Glob(filepath.Join(dir, "*.srt"))
+}
+
+func ReadSubtitle(platform, id, lang string) (*astisub.Subtitles, error) {
+	path, err := SubtitlePath(platform, id, lang)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return astisub.ReadFromSRT(file)
+}
+
+func ParseSRT(srt string) (*astisub.Subtitles, error) {
+	return astisub.ReadFromSRT(strings.NewReader(srt))
+}
+
+func FormatSRT(subs *astisub.Subtitles) (string, error) {
+	var buf bytes.Buffer
+	if err := subs.WriteToSRT(&buf); err != nil {
+		return "", err
+	}
+
+	return buf.String(), nil
+}
+
+// CueText 는 자막 한 개의 줄을 공백으로 이어 붙인다 (마지막 자막 뒤 빈 줄은 버린다)
+func CueText(item *astisub.Item) string {
+	var lines []string
+	for _, line := range item.Lines {
+		if text := strings.TrimSpace(line.String()); len(text) != 0 {
+			lines = append(lines, text)
+		}
+	}
+
+	return strings.Join(lines, " ")
+}
+
+// BestOverlap 은 startAt~endAt 과 가장 많이 겹치는 자막, 겹치는 게 없으면 nil
+func BestOverlap(items []*astisub.Item, startAt, endAt time.Duration) *astisub.Item {
+	var best *astisub.Item
+	var bestOverlap time.Duration
+
+	for _, item := range items {
+		start, end := startAt, endAt
+		if item.StartAt > start {
+			start = item.StartAt
+		}
+		if item.EndAt < end {
+			end = item.EndAt
+		}
+
+		if overlap := end - start; overlap > bestOverlap {
+			best = item
+			bestOverlap = overlap
+		}
+	}
+
+	return best
+}
+
+// PublishSubtitle 은 지금 자막을 version 폴더에 남기고 새 자막으로 바꾼 뒤, 진행률과 색인을 갱신한다
+// 돌려주는 번호는 이번 저장의 버전이고, version/rN-lang.srt 에는 그 저장 직전의 자막이 들어있다
+func PublishSubtitle(platform, id, lang, source string, subtitle []byte, editor, ip string) (int, error) {
+	if _, err := SubtitleStore.MkdirAll(platform, id, "version"); err != nil {
+		return 0, err
+	}
+
+	AddSubtitle(id, editor, ip, lang)
+	version := GetLastVersion(id)
+
+	if _, err := UpdateHistorySummary(platform, id); err != nil {
+		fmt.Println(err)
+	}
+
+	file, err := SubtitlePath(platform, id, lang)
+	if err != nil {
+		return 0, err
+	}
+
+	orgFile, err := SubtitleStore.Path(platform, id, "version", fmt.Sprintf("r%d-%s.srt", version, lang))
+	if err != nil {
+		return 0, err
+	}
+
+	input, _ := ioutil.ReadFile(file)
+
+	if err := WriteFileAtomic(orgFile, input, storageFilePerm); err != nil {
+		return 0, err
+	}
+
+	if err := WriteFileAtomic(file, subtitle, storageFilePerm); err != nil {
+		return 0, err
+	}
+
+	if _, err := UpdateCompletion(platform, id, source); err != nil {
+		fmt.Println(err)
+	}
+
+	if err := Memory.Update(platform, id); err != nil {
+		fmt.Println(err)
+	}
+
+	if err := Search.Update(platform, id, lang, "", file); err != nil {
+		fmt.Println(err)
+	}
+
+	if err := Search.Update(platform, id, lang, fmt.Sprintf("r%d", version), orgFile); err != nil {
+		fmt.Println(err)
+	}
+
+	return version, nil
+}
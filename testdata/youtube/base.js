@@ -0,0 +1,4 @@
+var Zq={Rv:function(a){a.reverse()},
+Sp:function(a,b){a.splice(0,b)},
+Sw:function(a,b){var c=a[0];a[0]=a[b%a.length];a[b%a.length]=c}};
+Yx=function(a){a=a.split("");Zq.Sp(a,2);Zq.Rv(a,1);Zq.Sw(a,3);return a.join("")};
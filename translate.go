@@ -0,0 +1,295 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/asticode/go-astisub"
+)
+
+// 번역기 설정 파일, 없으면 echo 를 쓴다
+// {"provider": "http", "endpoint": "...", "key": "..."} 또는 {"provider": "dictionary", "dictionary": "/path/to/dict.json"}
+var TranslatorConfigPath = filepath.Join(DataDir, "translator.json")
+
+// Translator 는 자막 여러 개를 한 번에 번역한다, 돌려주는 개수와 순서는 texts 와 같아야 한다
+type Translator interface {
+	Name() string
+	Translate(texts []string, sourceLang, targetLang string) ([]string, error)
+}
+
+type TranslatorConfig struct {
+	Provider   string `json:"provider"`
+	Endpoint   string `json:"endpoint"`
+	Key        string `json:"key"`
+	Dictionary string `json:"dictionary"`
+}
+
+// 설정으로 번역기를 만드는 함수, 새 번역기는 여기에 등록한다
+var Translators = map[string]func(config TranslatorConfig) (Translator, error){
+	"echo": func(TranslatorConfig) (Translator, error) {
+		return echoTranslator{}, nil
+	},
+	"dictionary": func(config TranslatorConfig) (Translator, error) {
+		return LoadDictionary(config.Dictionary)
+	},
+	"http": func(config TranslatorConfig) (Translator, error) {
+		if len(config.Endpoint) == 0 {
+			return nil, fmt.Errorf("translator endpoint is required")
+		}
+
+		return &httpTranslator{
+			endpoint: config.Endpoint,
+			key:      config.Key,
+			client:   &http.Client{Timeout: time.Minute},
+		}, nil
+	},
+}
+
+type MachineDraft struct {
+	Provider   string    `json:"provider"`
+	SourceLang string    `json:"sourceLang"`
+	CreatedAt  time.Time `json:"createdAt"`
+}
+
+type TranslateJSON struct {
+	Subtitle string `json:"subtitle"`
+	Draft    string `json:"draft"`
+	Provider string `json:"provider"`
+	Code     int    `json:"code"`
+}
+
+func LoadTranslatorConfig() TranslatorConfig {
+	config := TranslatorConfig{Provider: "echo"}
+
+	file, err := ioutil.ReadFile(TranslatorConfigPath)
+	if err != nil {
+		return config
+	}
+
+	if err := json.Unmarshal(file, &config); err != nil {
+		fmt.Println(err)
+	}
+
+	return config
+}
+
+// GetTranslator 는 설정 파일의 번역기를 쓴다, 요청으로 번역기를 바꾸면 설정된 endpoint 와 key 를 아무나 쓸 수 있으므로 받지 않는다
+func GetTranslator() (Translator, error) {
+	config := LoadTranslatorConfig()
+
+	newTranslator := Translators[config.Provider]
+	if newTranslator == nil {
+		return nil, fmt.Errorf("unknown translator: %s", config.Provider)
+	}
+
+	return newTranslator(config)
+}
+
+// TranslateSubtitles 는 자막 시간은 그대로 두고 내용만 번역한다, 빈 자막은 번역기에 보내지 않는다
+func TranslateSubtitles(translator Translator, subs *astisub.Subtitles, sourceLang, targetLang string) (*astisub.Subtitles, error) {
+	var texts []string
+	var indexes []int
+
+	for i, item := range subs.Items {
+		if text := CueText(item); len(text) != 0 {
+			texts = append(texts, text)
+			indexes = append(indexes, i)
+		}
+	}
+
+	translated, err := translator.Translate(texts, sourceLang, targetLang)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(translated) != len(texts) {
+		return nil, fmt.Errorf("%s returned %d texts for %d cues", translator.Name(), len(translated), len(texts))
+	}
+
+	result := astisub.NewSubtitles()
+	for _, item := range subs.Items {
+		result.Items = append(result.Items, &astisub.Item{
+			Index:   item.Index,
+			StartAt: item.StartAt,
+			EndAt:   item.EndAt,
+			Lines:   []astisub.Line{},
+		})
+	}
+
+	for n, i := range indexes {
+		result.Items[i].Lines = []astisub.Line{{Items: []astisub.LineItem{{Text: translated[n]}}}}
+	}
+
+	return result, nil
+}
+
+// StoreMachineDraft 는 기계 번역 결과를 자막이 아니라 초안으로 저장한다, 누군가 저장해야 자막이 된다
+func StoreMachineDraft(platform, id, lang, srt string, draft MachineDraft) error {
+	if err := SubtitleStore.WriteFile([]byte(srt), platform, id, "draft", lang+".srt"); err != nil {
+		return err
+	}
+
+	file, err := json.Marshal(draft)
+	if err != nil {
+		return err
+	}
+
+	return SubtitleStore.WriteFile(file, platform, id, "draft", lang+".json")
+}
+
+// echoTranslator 는 원문을 그대로 돌려준다 (번역기 없이 시간만 옮겨올 때)
+type echoTranslator struct{}
+
+func (echoTranslator) Name() string {
+	return "echo"
+}
+
+func (echoTranslator) Translate(texts []string, sourceLang, targetLang string) ([]string, error) {
+	return append([]string(nil), texts...), nil
+}
+
+// dictionaryTranslator 는 사전에 있는 표현을 긴 것부터 바꾼다, 항상 같은 결과가 나와서 시험용으로 쓴다
+type dictionaryTranslator struct {
+	phrases []string
+	words   map[string]string
+}
+
+func NewDictionary(words map[string]string) Translator {
+	d := &dictionaryTranslator{
+		words: make(map[string]string),
+	}
+
+	for phrase, translated := range words {
+		d.phrases = append(d.phrases, phrase)
+		d.words[strings.ToLower(phrase)] = translated
+	}
+
+	sort.Slice(d.phrases, func(i, j int) bool {
+		if len(d.phrases[i]) != len(d.phrases[j]) {
+			return len(d.phrases[i]) > len(d.phrases[j])
+		}
+
+		return d.phrases[i] < d.phrases[j]
+	})
+
+	return d
+}
+
+func LoadDictionary(path string) (Translator, error) {
+	file, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var words map[string]string
+	if err := json.Unmarshal(file, &words); err != nil {
+		return nil, err
+	}
+
+	return NewDictionary(words), nil
+}
+
+func (d *dictionaryTranslator) Name() string {
+	return "dictionary"
+}
+
+func (d *dictionaryTranslator) Translate(texts []string, sourceLang, targetLang string) ([]string, error) {
+	translated := make([]string, len(texts))
+
+	for i, text := range texts {
+		for _, phrase := range d.phrases {
+			text = replaceFold(text, phrase, d.words[strings.ToLower(phrase)])
+		}
+
+		translated[i] = text
+	}
+
+	return translated, nil
+}
+
+// replaceFold 는 대소문자를 무시하고 바꾼다, 빈 표현은 바꾸지 않는다
+func replaceFold(text, old, new string) string {
+	if len(old) == 0 {
+		return text
+	}
+
+	lower := strings.ToLower(text)
+	lowerOld := strings.ToLower(old)
+
+	// 소문자로 바꿨을 때 길이가 달라지는 글자가 있으면 위치를 맞출 수 없으므로 그대로 찾는다
+	if len(lower) != len(text) || len(lowerOld) != len(old) {
+		return strings.ReplaceAll(text, old, new)
+	}
+
+	var buf strings.Builder
+	for {
+		i := strings.Index(lower, lowerOld)
+		if i < 0 {
+			buf.WriteString(text)
+			return buf.String()
+		}
+
+		buf.WriteString(text[:i])
+		buf.WriteString(new)
+		text = text[i+len(old):]
+		lower = lower[i+len(old):]
+	}
+}
+
+// httpTranslator 는 {"source","target","texts"} 를 POST 하고 {"texts"} 를 받는 번역 서버를 쓴다
+type httpTranslator struct {
+	endpoint string
+	key      string
+	client   *http.Client
+}
+
+func (t *httpTranslator) Name() string {
+	return "http"
+}
+
+func (t *httpTranslator) Translate(texts []string, sourceLang, targetLang string) ([]string, error) {
+	body, err := json.Marshal(map[string]interface{}{
+		"source": sourceLang,
+		"target": targetLang,
+		"texts":  texts,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequest("POST", t.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	if len(t.key) != 0 {
+		req.Header.Set("Authorization", "Bearer "+t.key)
+	}
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != 200 {
+		return nil, fmt.Errorf("translator: %s", resp.Status)
+	}
+
+	var result struct {
+		Texts []string `json:"texts"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, err
+	}
+
+	return result.Texts, nil
+}
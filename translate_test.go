@@ -0,0 +1,207 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/asticode/go-astisub"
+)
+
+func TestEchoTranslator(t *testing.T) {
+	texts := []string{"Hello", "", "안녕"}
+
+	translated, err := echoTranslator{}.Translate(texts, "en", "ko")
+	if err != nil || !reflect.DeepEqual(translated, texts) {
+		t.Fatalf("echo = %q, %v", translated, err)
+	}
+
+	// 돌려준 슬라이스를 고쳐도 원문은 그대로다
+	translated[0] = "changed"
+	if texts[0] != "Hello" {
+		t.Error("echo returned the input slice")
+	}
+}
+
+func TestDictionaryTranslator(t *testing.T) {
+	d := NewDictionary(map[string]string{
+		"thank you":      "고마워",
+		"thank you very": "정말 고마워",
+		"hello":          "안녕",
+	})
+
+	tests := []struct {
+		text, want string
+	}{
+		{"Hello", "안녕"},
+		{"HELLO hello HeLLo", "안녕 안녕 안녕"},
+		// 긴 표현부터 바꾼다
+		{"Thank you very much", "정말 고마워 much"},
+		{"thank you", "고마워"},
+		{"nothing", "nothing"},
+		{"", ""},
+	}
+
+	var texts []string
+	for _, test := range tests {
+		texts = append(texts, test.text)
+	}
+
+	translated, err := d.Translate(texts, "en", "ko")
+	if err != nil || len(translated) != len(tests) {
+		t.Fatalf("dictionary = %q, %v", translated, err)
+	}
+
+	for i, test := range tests {
+		if translated[i] != test.want {
+			t.Errorf("dictionary(%q) = %q, want %q", test.text, translated[i], test.want)
+		}
+	}
+}
+
+func TestReplaceFold(t *testing.T) {
+	tests := []struct {
+		text, old, new, want string
+	}{
+		{"Hello World", "world", "세계", "Hello 세계"},
+		{"aAaA", "aa", "b", "bb"},
+		{"안녕 ABC 안녕", "abc", "x", "안녕 x 안녕"},
+		{"abc", "", "x", "abc"},
+		{"abc", "d", "x", "abc"},
+		// 소문자로 바꾸면 길이가 달라지는 글자 (İ) 가 있으면 대소문자를 가려서 찾는다
+		{"İstanbul istanbul", "istanbul", "x", "İstanbul x"},
+	}
+
+	for _, test := range tests {
+		if got := replaceFold(test.text, test.old, test.new); got != test.want {
+			t.Errorf("replaceFold(%q, %q, %q) = %q, want %q", test.text, test.old, test.new, got, test.want)
+		}
+	}
+}
+
+// fixedTranslator 는 정해진 결과를 돌려주고 받은 글을 남긴다
+type fixedTranslator struct {
+	texts  []string
+	result []string
+	err    error
+}
+
+func (f *fixedTranslator) Name() string {
+	return "fixed"
+}
+
+func (f *fixedTranslator) Translate(texts []string, sourceLang, targetLang string) ([]string, error) {
+	f.texts = texts
+	return f.result, f.err
+}
+
+func TestTranslateSubtitles(t *testing.T) {
+	s := time.Second
+
+	subs := memorySubs(
+		memoryCue(1*s, 2*s, "Hello"),
+		memoryCue(3*s, 4*s, ""),
+		memoryCue(5*s, 6*s, "Bye"),
+	)
+	for i, item := range subs.Items {
+		item.Index = i + 1
+	}
+
+	translator := &fixedTranslator{result: []string{"안녕", "잘 가"}}
+
+	translated, err := TranslateSubtitles(translator, subs, "en", "ko")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 빈 자막은 번역기에 보내지 않는다
+	if !reflect.DeepEqual(translator.texts, []string{"Hello", "Bye"}) {
+		t.Errorf("sent = %q", translator.texts)
+	}
+
+	if len(translated.Items) != len(subs.Items) {
+		t.Fatalf("items = %d, want %d", len(translated.Items), len(subs.Items))
+	}
+
+	want := []string{"안녕", "", "잘 가"}
+	for i, item := range translated.Items {
+		if item.Index != subs.Items[i].Index || item.StartAt != subs.Items[i].StartAt || item.EndAt != subs.Items[i].EndAt {
+			t.Errorf("item %d timing = %d %s-%s", i, item.Index, item.StartAt, item.EndAt)
+		}
+
+		if text := CueText(item); text != want[i] {
+			t.Errorf("item %d = %q, want %q", i, text, want[i])
+		}
+	}
+
+	// 원래 자막은 바뀌지 않는다
+	if CueText(subs.Items[0]) != "Hello" {
+		t.Errorf("source changed: %q", CueText(subs.Items[0]))
+	}
+
+	if _, err := TranslateSubtitles(&fixedTranslator{result: []string{"하나"}}, subs, "en", "ko"); err == nil {
+		t.Error("result length mismatch was accepted")
+	}
+
+	failure := errors.New("translator down")
+	if _, err := TranslateSubtitles(&fixedTranslator{err: failure}, subs, "en", "ko"); err != failure {
+		t.Errorf("translator error = %v", err)
+	}
+
+	if translated, err := TranslateSubtitles(&fixedTranslator{}, astisub.NewSubtitles(), "en", "ko"); err != nil || len(translated.Items) != 0 {
+		t.Errorf("empty subtitles = %+v, %v", translated, err)
+	}
+}
+
+// 요청에 provider 를 넣어도 설정 파일의 번역기를 쓴다
+func TestTranslateAPIProvider(t *testing.T) {
+	setupAPI(t)
+
+	previous := TranslatorConfigPath
+	TranslatorConfigPath = filepath.Join(t.TempDir(), "translator.json")
+	t.Cleanup(func() { TranslatorConfigPath = previous })
+
+	dictionary := filepath.Join(t.TempDir(), "dict.json")
+	if err := os.WriteFile(dictionary, []byte(`{"hello": "안녕"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	config, err := json.Marshal(TranslatorConfig{Provider: "dictionary", Dictionary: dictionary})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.WriteFile(TranslatorConfigPath, config, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := SubtitleStore.WriteFile([]byte(completionEnSRT), "youtube", proxyTestID, "en.srt"); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, provider := range []string{"", "echo", "http"} {
+		form := url.Values{"call": {"translate"}, "platform": {"youtube"}, "id": {proxyTestID}, "source": {"en"}, "lang": {"ko"}, "provider": {provider}}
+		r := httptest.NewRequest("POST", "/api", strings.NewReader(form.Encode()))
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		w := httptest.NewRecorder()
+
+		API(w, r)
+
+		var result TranslateJSON
+		if err := json.Unmarshal(w.Body.Bytes(), &result); err != nil || w.Code != http.StatusOK {
+			t.Fatalf("provider %q: %d %s", provider, w.Code, w.Body)
+		}
+
+		if result.Provider != "dictionary" || !strings.Contains(result.Subtitle, "안녕") {
+			t.Errorf("provider %q: translated with %q: %q", provider, result.Provider, result.Subtitle)
+		}
+	}
+}
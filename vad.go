@@ -0,0 +1,189 @@
+package main
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"github.com/asticode/go-astisub"
+)
+
+const (
+	vadFrame      = 25 * time.Millisecond
+	vadHop        = 10 * time.Millisecond
+	vadMinSpeech  = 200 * time.Millisecond
+	vadMinSilence = 300 * time.Millisecond
+	vadPadding    = 50 * time.Millisecond
+
+	// 잡음 바닥(하위 10% 에너지) 보다 이만큼 크면 음성으로 본다
+	vadEnergyThreshold = 12.0
+	// 에너지가 조금 모자라도 영교차율이 이 범위면 무성 자음(ㅅ, ㅎ 등)으로 보고 음성에 포함한다
+	vadWeakThreshold = 6.0
+	vadMinZCR        = 0.1
+	vadMaxZCR        = 0.5
+)
+
+type SpeechSegment struct {
+	StartAt time.Duration `json:"startAt"`
+	EndAt   time.Duration `json:"endAt"`
+}
+
+// DetectSpeech 는 프레임별 에너지와 영교차율로 음성 구간을 찾는다
+func DetectSpeech(audio *Audio) []SpeechSegment {
+	frame := int(vadFrame.Seconds() * float64(audio.Rate))
+	hop := int(vadHop.Seconds() * float64(audio.Rate))
+
+	if frame == 0 || hop == 0 || len(audio.Samples) < frame {
+		return nil
+	}
+
+	count := (len(audio.Samples)-frame)/hop + 1
+	energies := make([]float64, count)
+	zcrs := make([]float64, count)
+
+	for i := 0; i < count; i++ {
+		samples := audio.Samples[i*hop : i*hop+frame]
+
+		var sum float64
+		var crossings int
+		for j, s := range samples {
+			sum += float64(s) * float64(s)
+			if j > 0 && (s >= 0) != (samples[j-1] >= 0) {
+				crossings++
+			}
+		}
+
+		energies[i] = 10 * math.Log10(sum/float64(frame)+1e-10)
+		zcrs[i] = float64(crossings) / float64(frame)
+	}
+
+	sorted := append([]float64(nil), energies...)
+	sort.Float64s(sorted)
+	floor := sorted[len(sorted)/10]
+
+	voiced := make([]bool, count)
+	for i := range voiced {
+		switch {
+		case energies[i] > floor+vadEnergyThreshold:
+			voiced[i] = true
+		case energies[i] > floor+vadWeakThreshold && zcrs[i] >= vadMinZCR && zcrs[i] <= vadMaxZCR:
+			voiced[i] = true
+		}
+	}
+
+	var segments []SpeechSegment
+	for i := 0; i < count; {
+		if !voiced[i] {
+			i++
+			continue
+		}
+
+		start := i
+		for i < count && voiced[i] {
+			i++
+		}
+
+		segments = append(segments, SpeechSegment{
+			StartAt: time.Duration(start) * vadHop,
+			EndAt:   time.Duration(i-1)*vadHop + vadFrame,
+		})
+	}
+
+	return smoothSegments(segments, time.Duration(audio.Duration()*float64(time.Second)))
+}
+
+// 짧은 쉼은 합치고, 너무 짧은 구간은 버리고, 앞뒤로 여유를 둔다
+func smoothSegments(segments []SpeechSegment, duration time.Duration) []SpeechSegment {
+	var merged []SpeechSegment
+	for _, segment := range segments {
+		if len(merged) > 0 && segment.StartAt-merged[len(merged)-1].EndAt < vadMinSilence {
+			merged[len(merged)-1].EndAt = segment.EndAt
+			continue
+		}
+
+		merged = append(merged, segment)
+	}
+
+	var result []SpeechSegment
+	for _, segment := range merged {
+		if segment.EndAt-segment.StartAt < vadMinSpeech {
+			continue
+		}
+
+		segment.StartAt -= vadPadding
+		if segment.StartAt < 0 {
+			segment.StartAt = 0
+		}
+
+		segment.EndAt += vadPadding
+		if segment.EndAt > duration {
+			segment.EndAt = duration
+		}
+
+		result = append(result, segment)
+	}
+
+	return result
+}
+
+// SegmentsToSubtitles 는 음성 구간마다 빈 자막을 만든다
+func SegmentsToSubtitles(segments []SpeechSegment) *astisub.Subtitles {
+	subs := astisub.NewSubtitles()
+
+	for i, segment := range segments {
+		subs.Items = append(subs.Items, &astisub.Item{
+			Index:   i + 1,
+			StartAt: segment.StartAt,
+			EndAt:   segment.EndAt,
+		})
+	}
+
+	return subs
+}
+
+// SnapSubtitles 는 자막 시작/종료 시간을 tolerance 안에서 가장 가까운 음성 경계로 옮긴다
+func SnapSubtitles(subs *astisub.Subtitles, segments []SpeechSegment, tolerance time.Duration) int {
+	var starts, ends []time.Duration
+	for _, segment := range segments {
+		starts = append(starts, segment.StartAt)
+		ends = append(ends, segment.EndAt)
+	}
+
+	var snapped int
+	for _, item := range subs.Items {
+		startAt := nearestBoundary(starts, item.StartAt, tolerance)
+		endAt := nearestBoundary(ends, item.EndAt, tolerance)
+
+		if endAt <= startAt {
+			continue
+		}
+
+		if startAt != item.StartAt || endAt != item.EndAt {
+			snapped++
+		}
+
+		item.StartAt = startAt
+		item.EndAt = endAt
+	}
+
+	return snapped
+}
+
+func nearestBoundary(boundaries []time.Duration, t, tolerance time.Duration) time.Duration {
+	nearest := t
+	best := tolerance + 1
+
+	for _, boundary := range boundaries {
+		diff := boundary - t
+		if diff < 0 {
+			diff = -diff
+		}
+
+		if diff <= tolerance && diff < best {
+			nearest = boundary
+			best = diff
+		}
+	}
+
+	return nearest
+}
@@ -0,0 +1,184 @@
+package main
+
+import (
+	"math"
+	"math/rand"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/asticode/go-astisub"
+)
+
+const vadTestRate = 8000
+
+// toneAudio 는 parts 를 차례로 이어 붙인다, 값이 true 인 구간은 크기 0.5 의 440Hz, false 는 무음
+func toneAudio(parts ...interface{}) *Audio {
+	audio := &Audio{Rate: vadTestRate}
+
+	for i := 0; i < len(parts); i += 2 {
+		duration, tone := parts[i].(time.Duration), parts[i+1].(bool)
+		n := int(duration.Seconds() * vadTestRate)
+
+		for j := 0; j < n; j++ {
+			var s float32
+			if tone {
+				s = float32(0.5 * math.Sin(2*math.Pi*440*float64(len(audio.Samples))/vadTestRate))
+			}
+
+			audio.Samples = append(audio.Samples, s)
+		}
+	}
+
+	return audio
+}
+
+func TestDetectSpeech(t *testing.T) {
+	second := time.Second
+
+	tests := []struct {
+		name  string
+		audio *Audio
+		want  []SpeechSegment
+	}{
+		{"tone", toneAudio(second, false, second, true, second, false), []SpeechSegment{{930 * time.Millisecond, 2065 * time.Millisecond}}},
+		{"short gap merges", toneAudio(second, false, second, true, 100*time.Millisecond, false, second, true, second, false), []SpeechSegment{{930 * time.Millisecond, 3165 * time.Millisecond}}},
+		{"long gap splits", toneAudio(second, false, second, true, second, false, second, true, second, false), []SpeechSegment{{930 * time.Millisecond, 2065 * time.Millisecond}, {2930 * time.Millisecond, 4065 * time.Millisecond}}},
+		{"short blip dropped", toneAudio(second, false, 50*time.Millisecond, true, second, false), nil},
+		{"silence", toneAudio(3*second, false), nil},
+		{"too short", toneAudio(10*time.Millisecond, true), nil},
+		{"padding clipped", toneAudio(second, true, second, false), []SpeechSegment{{0, 1065 * time.Millisecond}}},
+	}
+
+	for _, test := range tests {
+		if got := DetectSpeech(test.audio); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: DetectSpeech = %v, want %v", test.name, got, test.want)
+		}
+	}
+}
+
+func TestSmoothSegments(t *testing.T) {
+	ms := time.Millisecond
+
+	tests := []struct {
+		name     string
+		segments []SpeechSegment
+		duration time.Duration
+		want     []SpeechSegment
+	}{
+		{"padding", []SpeechSegment{{1000 * ms, 2000 * ms}}, 5000 * ms, []SpeechSegment{{950 * ms, 2050 * ms}}},
+		{"clipped to the audio", []SpeechSegment{{20 * ms, 1000 * ms}, {4000 * ms, 4990 * ms}}, 5000 * ms, []SpeechSegment{{0, 1050 * ms}, {3950 * ms, 5000 * ms}}},
+		{"short gap merges", []SpeechSegment{{1000 * ms, 1500 * ms}, {1799 * ms, 2000 * ms}}, 5000 * ms, []SpeechSegment{{950 * ms, 2050 * ms}}},
+		{"long gap splits", []SpeechSegment{{1000 * ms, 1500 * ms}, {1800 * ms, 2000 * ms}}, 5000 * ms, []SpeechSegment{{950 * ms, 1550 * ms}, {1750 * ms, 2050 * ms}}},
+		{"merged blips are kept", []SpeechSegment{{1000 * ms, 1100 * ms}, {1200 * ms, 1300 * ms}}, 5000 * ms, []SpeechSegment{{950 * ms, 1350 * ms}}},
+		{"short blip dropped", []SpeechSegment{{1000 * ms, 1199 * ms}, {3000 * ms, 3500 * ms}}, 5000 * ms, []SpeechSegment{{2950 * ms, 3550 * ms}}},
+		{"empty", nil, 5000 * ms, nil},
+	}
+
+	for _, test := range tests {
+		if got := smoothSegments(test.segments, test.duration); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: smoothSegments = %v, want %v", test.name, got, test.want)
+		}
+	}
+}
+
+func TestNearestBoundary(t *testing.T) {
+	ms := time.Millisecond
+	boundaries := []time.Duration{1000 * ms, 2000 * ms, 2100 * ms}
+
+	tests := []struct {
+		t, tolerance, want time.Duration
+	}{
+		{1050 * ms, 100 * ms, 1000 * ms},
+		{1100 * ms, 100 * ms, 1000 * ms},
+		{1101 * ms, 100 * ms, 1101 * ms},
+		{2060 * ms, 100 * ms, 2100 * ms},
+		{2050 * ms, 100 * ms, 2000 * ms},
+		{500 * ms, 0, 500 * ms},
+		{1000 * ms, 0, 1000 * ms},
+	}
+
+	for _, test := range tests {
+		if got := nearestBoundary(boundaries, test.t, test.tolerance); got != test.want {
+			t.Errorf("nearestBoundary(%s, %s) = %s, want %s", test.t, test.tolerance, got, test.want)
+		}
+	}
+
+	if got := nearestBoundary(nil, 1500*ms, time.Second); got != 1500*ms {
+		t.Errorf("nearestBoundary without boundaries = %s", got)
+	}
+}
+
+func TestSnapSubtitles(t *testing.T) {
+	ms := time.Millisecond
+	segments := []SpeechSegment{{500 * ms, 1000 * ms}, {1150 * ms, 2000 * ms}, {3000 * ms, 4000 * ms}}
+
+	subs := astisub.NewSubtitles()
+	subs.Items = []*astisub.Item{
+		{StartAt: 1100 * ms, EndAt: 1900 * ms},
+		{StartAt: 2500 * ms, EndAt: 2700 * ms},
+		// 시작은 1150ms 로, 끝은 1000ms 로 가서 뒤집히므로 그대로 둔다
+		{StartAt: 1050 * ms, EndAt: 1100 * ms},
+		{StartAt: 2950 * ms, EndAt: 3600 * ms},
+	}
+
+	snapped := SnapSubtitles(subs, segments, 100*ms)
+
+	want := [][2]time.Duration{
+		{1150 * ms, 2000 * ms},
+		{2500 * ms, 2700 * ms},
+		{1050 * ms, 1100 * ms},
+		{3000 * ms, 3600 * ms},
+	}
+
+	for i, item := range subs.Items {
+		if item.StartAt != want[i][0] || item.EndAt != want[i][1] {
+			t.Errorf("item %d = %s --> %s, want %s --> %s", i, item.StartAt, item.EndAt, want[i][0], want[i][1])
+		}
+	}
+
+	if snapped != 2 {
+		t.Errorf("snapped = %d, want 2", snapped)
+	}
+}
+
+// 어떤 자막이든 tolerance 보다 멀리 옮기지 않고, 시작이 끝보다 늦어지지 않는다
+func TestSnapSubtitlesBounds(t *testing.T) {
+	random := rand.New(rand.NewSource(1))
+	tolerance := 200 * time.Millisecond
+
+	var segments []SpeechSegment
+	for at := time.Duration(0); at < time.Minute; {
+		start := at + time.Duration(random.Intn(2000))*time.Millisecond
+		end := start + time.Duration(200+random.Intn(3000))*time.Millisecond
+		segments = append(segments, SpeechSegment{start, end})
+		at = end
+	}
+
+	subs := astisub.NewSubtitles()
+	for i := 0; i < 500; i++ {
+		start := time.Duration(random.Intn(60000)) * time.Millisecond
+		end := start + time.Duration(1+random.Intn(3000))*time.Millisecond
+		subs.Items = append(subs.Items, &astisub.Item{StartAt: start, EndAt: end})
+	}
+
+	original := make([]astisub.Item, len(subs.Items))
+	for i, item := range subs.Items {
+		original[i] = *item
+	}
+
+	SnapSubtitles(subs, segments, tolerance)
+
+	for i, item := range subs.Items {
+		if item.EndAt <= item.StartAt {
+			t.Fatalf("item %d inverted: %s --> %s", i, item.StartAt, item.EndAt)
+		}
+
+		moved := []time.Duration{item.StartAt - original[i].StartAt, item.EndAt - original[i].EndAt}
+		for _, d := range moved {
+			if d > tolerance || d < -tolerance {
+				t.Fatalf("item %d moved %s: %s --> %s from %s --> %s", i, d, item.StartAt, item.EndAt, original[i].StartAt, original[i].EndAt)
+			}
+		}
+	}
+}
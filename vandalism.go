@@ -0,0 +1,323 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"unicode"
+
+	"github.com/asticode/go-astisub"
+)
+
+var VandalismConfigPath = filepath.Join(DataDir, "vandalism.json")
+
+// VandalismConfig 는 저장을 격리할 기준, 하나라도 넘으면 게시하지 않고 관리자 확인을 기다린다
+// 파일이 없거나 0 인 값은 기본값을 쓴다
+type VandalismConfig struct {
+	MinCues       int      `json:"minCues"`       // 게시된 자막이 이만큼은 있어야 삭제, 유사도를 본다 (짧은 자막은 다시 쓰는 일이 많다)
+	MaxDeleted    float64  `json:"maxDeleted"`    // 지운 자막 비율
+	MinSimilarity float64  `json:"minSimilarity"` // 게시된 자막과 글자 유사도
+	MaxCollapsed  float64  `json:"maxCollapsed"`  // 길이가 0 이하인 자막 비율, 전체 구간이 줄어든 비율
+	MaxRepeat     int      `json:"maxRepeat"`     // 같은 글자를 연달아 쓴 횟수
+	Patterns      []string `json:"patterns"`      // 새로 늘어나면 스팸으로 보는 정규식 (주소, 욕설)
+
+	patterns []*regexp.Regexp
+}
+
+var defaultVandalismPatterns = []string{
+	`(?i)https?://`,
+	`(?i)\bwww\.`,
+	`(?i)\b[a-z0-9-]+\.(com|net|org|io|xyz|kr)\b`,
+	`(?i)\b(fuck(ing|ed|er)?|shit(ty)?|bullshit)\b`,
+	`씨발|시발|병신|개새끼`,
+}
+
+// VandalismReport 의 Score 는 0~1, 가장 많이 넘은 기준의 값이고 Reasons 가 비어 있지 않으면 격리한다
+type VandalismReport struct {
+	Score      float64  `json:"score"`
+	Deleted    float64  `json:"deleted"`
+	Similarity float64  `json:"similarity"`
+	Collapsed  float64  `json:"collapsed"`
+	Spam       []string `json:"spam,omitempty"`
+	Reasons    []string `json:"reasons,omitempty"`
+}
+
+// vandalismRegexps 는 컴파일한 정규식, 설정 파일은 저장할 때마다 읽지만 같은 패턴을 다시 컴파일하지 않는다
+var vandalismRegexps sync.Map
+
+type vandalismRegexp struct {
+	regx *regexp.Regexp
+	err  error
+}
+
+// LoadVandalismConfig 는 패턴을 여기서 컴파일한다, 잘못된 패턴은 알리고 빼서 나머지 기준은 그대로 쓴다
+func LoadVandalismConfig() VandalismConfig {
+	config := VandalismConfig{}
+
+	file, err := ioutil.ReadFile(VandalismConfigPath)
+	if err == nil {
+		if err := json.Unmarshal(file, &config); err != nil {
+			fmt.Println(err)
+		}
+	}
+
+	if config.MinCues <= 0 {
+		config.MinCues = 5
+	}
+
+	if config.MaxDeleted <= 0 {
+		config.MaxDeleted = 0.5
+	}
+
+	if config.MinSimilarity <= 0 {
+		config.MinSimilarity = 0.2
+	}
+
+	if config.MaxCollapsed <= 0 {
+		config.MaxCollapsed = 0.3
+	}
+
+	if config.MaxRepeat <= 0 {
+		config.MaxRepeat = 15
+	}
+
+	if len(config.Patterns) == 0 {
+		config.Patterns = defaultVandalismPatterns
+	}
+
+	for _, pattern := range config.Patterns {
+		regx, err := compileVandalismPattern(pattern)
+		if err != nil {
+			fmt.Printf("잘못된 격리 패턴 (%s): %v\n", VandalismConfigPath, err)
+			continue
+		}
+
+		config.patterns = append(config.patterns, regx)
+	}
+
+	return config
+}
+
+func compileVandalismPattern(pattern string) (*regexp.Regexp, error) {
+	if cached, ok := vandalismRegexps.Load(pattern); ok {
+		compiled := cached.(vandalismRegexp)
+		return compiled.regx, compiled.err
+	}
+
+	regx, err := regexp.Compile(pattern)
+	vandalismRegexps.Store(pattern, vandalismRegexp{regx, err})
+
+	return regx, err
+}
+
+func (r *VandalismReport) Suspicious() bool {
+	return len(r.Reasons) != 0
+}
+
+func (r *VandalismReport) flag(score float64, reason string) {
+	r.Score = math.Max(r.Score, math.Min(score, 1))
+	r.Reasons = append(r.Reasons, reason)
+}
+
+// subtitleText 는 자막 전체 글자를 공백 없이 이어붙인다
+func subtitleText(s *astisub.Subtitles) string {
+	var b strings.Builder
+	for _, item := range s.Items {
+		for _, r := range CueText(item) {
+			if !unicode.IsSpace(r) {
+				b.WriteRune(unicode.ToLower(r))
+			}
+		}
+	}
+
+	return b.String()
+}
+
+func subtitleLines(s *astisub.Subtitles) string {
+	lines := make([]string, len(s.Items))
+	for i, item := range s.Items {
+		lines[i] = CueText(item)
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// TextSimilarity 는 두 글의 글자 2-gram 다이스 계수 (0~1), 띄어쓰기가 없는 한국어에도 쓸 수 있다
+func TextSimilarity(a, b string) float64 {
+	bigrams := func(s string) map[string]int {
+		runes := []rune(s)
+		grams := make(map[string]int)
+		for i := 0; i+1 < len(runes); i++ {
+			grams[string(runes[i:i+2])]++
+		}
+
+		return grams
+	}
+
+	ga, gb := bigrams(a), bigrams(b)
+
+	total, common := 0, 0
+	for gram, n := range ga {
+		total += n
+		if m := gb[gram]; m < n {
+			common += m
+		} else {
+			common += n
+		}
+	}
+	for _, n := range gb {
+		total += n
+	}
+
+	if total == 0 {
+		return 1
+	}
+
+	return 2 * float64(common) / float64(total)
+}
+
+// longestRepeat 는 같은 글자(공백 제외)가 연달아 나온 가장 긴 길이
+func longestRepeat(s string) int {
+	longest, run := 0, 0
+	var last rune
+
+	for _, r := range s {
+		if r == last && !unicode.IsSpace(r) {
+			run++
+		} else {
+			run = 1
+		}
+		last = r
+
+		if run > longest {
+			longest = run
+		}
+	}
+
+	return longest
+}
+
+func zeroLengthRatio(s *astisub.Subtitles) float64 {
+	if len(s.Items) == 0 {
+		return 0
+	}
+
+	zero := 0
+	for _, item := range s.Items {
+		if item.EndAt <= item.StartAt {
+			zero++
+		}
+	}
+
+	return float64(zero) / float64(len(s.Items))
+}
+
+func subtitleSpan(s *astisub.Subtitles) float64 {
+	if len(s.Items) == 0 {
+		return 0
+	}
+
+	first, last := s.Items[0].StartAt, s.Items[0].EndAt
+	for _, item := range s.Items {
+		if item.StartAt < first {
+			first = item.StartAt
+		}
+
+		if item.EndAt > last {
+			last = item.EndAt
+		}
+	}
+
+	return (last - first).Seconds()
+}
+
+// ScoreRevision 은 게시된 자막 (head) 과 새로 저장하려는 자막을 비교한다, config 는 패턴을 컴파일한 LoadVandalismConfig 의 결과
+func ScoreRevision(head, revision *astisub.Subtitles, config VandalismConfig) *VandalismReport {
+	report := &VandalismReport{Similarity: 1}
+
+	if len(head.Items) >= config.MinCues {
+		if removed := len(head.Items) - len(revision.Items); removed > 0 {
+			report.Deleted = float64(removed) / float64(len(head.Items))
+		}
+
+		if report.Deleted > config.MaxDeleted {
+			report.flag(report.Deleted, fmt.Sprintf("deleted %.0f%% of cues", report.Deleted*100))
+		}
+
+		report.Similarity = TextSimilarity(subtitleText(head), subtitleText(revision))
+		if report.Similarity < config.MinSimilarity {
+			report.flag(1-report.Similarity, fmt.Sprintf("text similarity dropped to %.0f%%", report.Similarity*100))
+		}
+	}
+
+	// 시간이 0 이하인 자막이 갑자기 많아졌거나, 자막 전체 구간이 줄어들었으면 시간을 망가뜨린 것
+	// 새 영상은 시간이 0 인 자막 하나로 시작하므로 짧은 자막은 보지 않는다
+	if len(revision.Items) >= config.MinCues {
+		report.Collapsed = zeroLengthRatio(revision) - zeroLengthRatio(head)
+
+		if headSpan := subtitleSpan(head); len(head.Items) >= config.MinCues && headSpan > 0 {
+			if shrink := 1 - subtitleSpan(revision)/headSpan; shrink > report.Collapsed {
+				report.Collapsed = shrink
+			}
+		}
+
+		if report.Collapsed > config.MaxCollapsed {
+			report.flag(report.Collapsed, fmt.Sprintf("timing collapsed by %.0f%%", report.Collapsed*100))
+		}
+	}
+
+	// 원래 있던 주소나 말은 문제 삼지 않고 새로 늘어난 것만 본다
+	headText, revisionText := subtitleLines(head), subtitleLines(revision)
+	for _, regx := range config.patterns {
+		if len(regx.FindAllStringIndex(revisionText, -1)) > len(regx.FindAllStringIndex(headText, -1)) {
+			report.Spam = append(report.Spam, regx.String())
+		}
+	}
+
+	if longestRepeat(revisionText) > config.MaxRepeat && longestRepeat(headText) <= config.MaxRepeat {
+		report.Spam = append(report.Spam, "repeated characters")
+	}
+
+	if len(report.Spam) != 0 {
+		report.flag(1, fmt.Sprintf("spam patterns: %s", strings.Join(report.Spam, ", ")))
+	}
+
+	return report
+}
+
+// CheckVandalism 은 지금 게시된 자막과 비교한다, 게시된 자막이 없으면 빈 자막과 비교한다
+// 게시된 자막이 있는데 새 자막을 SRT 로 읽을 수 없으면 자막 전체를 지운 것으로 본다
+func CheckVandalism(platform, id, lang string, subtitle []byte) (*VandalismReport, error) {
+	head := astisub.NewSubtitles()
+
+	current, err := SubtitleStore.ReadFile(platform, id, lang+".srt")
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	if len(current) != 0 {
+		if head, err = astisub.ReadFromSRT(bytes.NewReader(current)); err != nil {
+			return nil, err
+		}
+	}
+
+	revision, err := astisub.ReadFromSRT(bytes.NewReader(subtitle))
+	if err != nil {
+		report := &VandalismReport{}
+		if len(head.Items) != 0 {
+			report.Deleted = 1
+			report.flag(1, "not a valid SRT")
+		}
+
+		return report, nil
+	}
+
+	return ScoreRevision(head, revision, LoadVandalismConfig()), nil
+}
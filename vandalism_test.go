@@ -0,0 +1,235 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/asticode/go-astisub"
+)
+
+// vandalismConfig 는 설정 파일이 없을 때의 기본 설정
+func vandalismConfig(t *testing.T) VandalismConfig {
+	t.Helper()
+
+	previous := VandalismConfigPath
+	VandalismConfigPath = filepath.Join(t.TempDir(), "vandalism.json")
+	t.Cleanup(func() { VandalismConfigPath = previous })
+
+	return LoadVandalismConfig()
+}
+
+// vandalismSubs 는 1초 간격으로 texts 를 하나씩 담은 자막
+func vandalismSubs(texts ...string) *astisub.Subtitles {
+	var items []*astisub.Item
+	for i, text := range texts {
+		start := time.Duration(i) * time.Second
+		items = append(items, memoryCue(start, start+time.Second, text))
+	}
+
+	return memorySubs(items...)
+}
+
+func TestLoadVandalismConfig(t *testing.T) {
+	config := vandalismConfig(t)
+
+	if config.MinCues != 5 || config.MaxRepeat != 15 || len(config.patterns) != len(defaultVandalismPatterns) {
+		t.Errorf("default config = %+v", config)
+	}
+
+	// 잘못된 패턴은 빼고 나머지는 쓴다
+	if err := os.WriteFile(VandalismConfigPath, []byte(`{"minCues": 2, "patterns": ["(", "(?i)casino"]}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	config = LoadVandalismConfig()
+	if config.MinCues != 2 || len(config.patterns) != 1 || config.patterns[0].String() != "(?i)casino" {
+		t.Errorf("config = %+v", config)
+	}
+
+	// 한 번 컴파일한 패턴은 다시 읽어도 같은 정규식을 쓴다
+	if again := LoadVandalismConfig(); again.patterns[0] != config.patterns[0] {
+		t.Error("pattern was compiled again")
+	}
+}
+
+func TestVandalismProfanity(t *testing.T) {
+	config := vandalismConfig(t)
+	head := vandalismSubs("안녕하세요")
+
+	tests := []struct {
+		text string
+		spam bool
+	}{
+		{"Shitake mushrooms", false},
+		{"Scunthorpe", false},
+		{"this is shit", true},
+		{"SHIT!", true},
+		{"what the fuck", true},
+		{"fucking great", true},
+		{"bullshit", true},
+		{"이런 씨발", true},
+	}
+
+	for _, test := range tests {
+		report := ScoreRevision(head, vandalismSubs(test.text), config)
+		if report.Suspicious() != test.spam {
+			t.Errorf("%q: suspicious = %v, want %v (%v)", test.text, report.Suspicious(), test.spam, report.Reasons)
+		}
+	}
+}
+
+func TestScoreRevisionSpam(t *testing.T) {
+	config := vandalismConfig(t)
+
+	tests := []struct {
+		name           string
+		head, revision *astisub.Subtitles
+		spam           []string
+	}{
+		{
+			"new url",
+			vandalismSubs("안녕하세요"),
+			vandalismSubs("안녕하세요", "https://spam.xyz 에 오세요"),
+			[]string{`(?i)https?://`, `(?i)\b[a-z0-9-]+\.(com|net|org|io|xyz|kr)\b`},
+		},
+		{
+			// 원래 있던 주소는 고쳐도 문제 삼지 않는다
+			"url already present",
+			vandalismSubs("자세한 내용은 https://example.com"),
+			vandalismSubs("자세한 내용은 https://example.com 에서"),
+			nil,
+		},
+		{
+			"one more url",
+			vandalismSubs("https://example.com"),
+			vandalismSubs("https://example.com", "https://example.com"),
+			[]string{`(?i)https?://`, `(?i)\b[a-z0-9-]+\.(com|net|org|io|xyz|kr)\b`},
+		},
+		{
+			"profanity already present",
+			vandalismSubs("shit happens"),
+			vandalismSubs("Shit happens!"),
+			nil,
+		},
+		{
+			"repeated characters",
+			vandalismSubs("좋아요"),
+			vandalismSubs("좋아요ㅋㅋㅋㅋㅋㅋㅋㅋㅋㅋㅋㅋㅋㅋㅋㅋ"),
+			[]string{"repeated characters"},
+		},
+	}
+
+	for _, test := range tests {
+		report := ScoreRevision(test.head, test.revision, config)
+		if !reflect.DeepEqual(report.Spam, test.spam) {
+			t.Errorf("%s: spam = %q, want %q", test.name, report.Spam, test.spam)
+		}
+
+		if report.Suspicious() != (len(test.spam) != 0) || (len(test.spam) != 0 && report.Score != 1) {
+			t.Errorf("%s: report = %+v", test.name, report)
+		}
+	}
+}
+
+func TestScoreRevisionThresholds(t *testing.T) {
+	config := vandalismConfig(t)
+
+	var texts []string
+	for i := 0; i < config.MinCues; i++ {
+		texts = append(texts, fmt.Sprintf("자막 %d번째 줄입니다", i))
+	}
+	head := vandalismSubs(texts...)
+
+	if report := ScoreRevision(head, vandalismSubs(texts...), config); report.Suspicious() || report.Similarity != 1 || report.Score != 0 {
+		t.Errorf("same subtitle = %+v", report)
+	}
+
+	// 절반 넘게 지웠다
+	report := ScoreRevision(head, vandalismSubs(texts[:2]...), config)
+	if report.Deleted != 0.6 || !report.Suspicious() {
+		t.Errorf("deleted = %+v", report)
+	}
+
+	// 자막 수는 같지만 내용이 모두 바뀌었다
+	report = ScoreRevision(head, vandalismSubs("a", "b", "c", "d", "e"), config)
+	if report.Similarity >= config.MinSimilarity || !report.Suspicious() {
+		t.Errorf("similarity = %+v", report)
+	}
+
+	// MinCues 보다 짧은 자막은 지우거나 다시 써도 보지 않는다
+	short := vandalismSubs(texts[:config.MinCues-1]...)
+	if report := ScoreRevision(short, vandalismSubs("완전히 다른 내용"), config); report.Suspicious() || report.Deleted != 0 || report.Similarity != 1 {
+		t.Errorf("short head = %+v", report)
+	}
+}
+
+func TestScoreRevisionCollapsed(t *testing.T) {
+	config := vandalismConfig(t)
+
+	texts := []string{"하나", "둘", "셋", "넷", "다섯", "여섯", "일곱", "여덟", "아홉", "열"}
+	head := vandalismSubs(texts...)
+
+	// 10개 중 4개의 길이를 0 으로
+	zero := vandalismSubs(texts...)
+	for _, item := range zero.Items[:4] {
+		item.EndAt = item.StartAt
+	}
+
+	if report := ScoreRevision(head, zero, config); report.Collapsed != 0.4 || !report.Suspicious() {
+		t.Errorf("zero length = %+v", report)
+	}
+
+	// 2개만이면 기준 (0.3) 보다 적다
+	two := vandalismSubs(texts...)
+	for _, item := range two.Items[:2] {
+		item.EndAt = item.StartAt
+	}
+
+	if report := ScoreRevision(head, two, config); report.Collapsed != 0.2 || report.Suspicious() {
+		t.Errorf("two zero length = %+v", report)
+	}
+
+	// 10초였던 전체 구간을 5초로 줄였다
+	squeezed := vandalismSubs(texts...)
+	for _, item := range squeezed.Items {
+		item.StartAt /= 2
+		item.EndAt /= 2
+	}
+
+	if report := ScoreRevision(head, squeezed, config); report.Collapsed != 0.5 || !report.Suspicious() {
+		t.Errorf("squeezed = %+v", report)
+	}
+
+	// 새 영상은 시간이 0 인 자막 하나로 시작한다
+	start := memorySubs(memoryCue(0, 0, ""))
+	if report := ScoreRevision(astisub.NewSubtitles(), start, config); report.Suspicious() || report.Collapsed != 0 {
+		t.Errorf("new video = %+v", report)
+	}
+}
+
+func TestLongestRepeat(t *testing.T) {
+	tests := []struct {
+		text string
+		want int
+	}{
+		{"", 0},
+		{"a", 1},
+		{"abc", 1},
+		{"aab", 2},
+		{"ㅋㅋㅋㅋ 좋아", 4},
+		{"aa aa", 2},
+		{"a     a", 1},
+		{"!!!!!", 5},
+		{"aaabbbbcc", 4},
+	}
+
+	for _, test := range tests {
+		if got := longestRepeat(test.text); got != test.want {
+			t.Errorf("longestRepeat(%q) = %d, want %d", test.text, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,213 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// 피크 하나당 샘플 수 (확대 단계별)
+var WaveformLevels = []int{256, 1024, 4096, 16384}
+
+type WaveformLevel struct {
+	SamplesPerPeak int    `json:"samplesPerPeak"`
+	Min            []int8 `json:"min"`
+	Max            []int8 `json:"max"`
+}
+
+type Waveform struct {
+	Rate     int             `json:"rate"`
+	Duration float64         `json:"duration"`
+	ModTime  time.Time       `json:"modTime"`
+	Levels   []WaveformLevel `json:"levels"`
+}
+
+type WaveformJSON struct {
+	Rate           int     `json:"rate"`
+	Duration       float64 `json:"duration"`
+	SamplesPerPeak int     `json:"samplesPerPeak"`
+	Min            []int8  `json:"min"`
+	Max            []int8  `json:"max"`
+	Code           int     `json:"code"`
+}
+
+// 메모리에 보관하는 파형 수, 넘으면 먼저 넣은 것부터 지운다
+const waveformCacheMaxEntries = 100
+
+// waveformCall 은 계산 중인 파형, 같은 영상을 요청하면 이 결과를 기다린다
+type waveformCall struct {
+	wg       sync.WaitGroup
+	waveform *Waveform
+	err      error
+}
+
+// waveformMu 는 아래 맵만 보호한다, 계산하는 동안에는 잡지 않으므로 다른 영상의 요청을 막지 않는다
+var (
+	waveformMu       sync.Mutex
+	waveformCache    = make(map[string]*Waveform)
+	waveformOrder    []string
+	waveformInflight = make(map[string]*waveformCall)
+)
+
+// GetWaveform 은 메모리 -> 디스크 캐시 순으로 찾고, 원본이 더 새로우면 다시 계산한다
+func GetWaveform(platform, id string) (*Waveform, error) {
+	path, err := MediaPath(platform, id)
+	if err != nil {
+		return nil, err
+	}
+
+	stat, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	key := platform + "/" + id
+
+	waveformMu.Lock()
+	if waveform := waveformCache[key]; waveform != nil && waveform.ModTime.Equal(stat.ModTime()) {
+		waveformMu.Unlock()
+		return waveform, nil
+	}
+
+	if call := waveformInflight[key]; call != nil {
+		waveformMu.Unlock()
+
+		call.wg.Wait()
+		return call.waveform, call.err
+	}
+
+	call := &waveformCall{}
+	call.wg.Add(1)
+	waveformInflight[key] = call
+	waveformMu.Unlock()
+
+	call.waveform, call.err = loadWaveform(platform, id, stat.ModTime())
+
+	waveformMu.Lock()
+	delete(waveformInflight, key)
+	if call.err == nil {
+		storeWaveform(key, call.waveform)
+	}
+	waveformMu.Unlock()
+
+	call.wg.Done()
+
+	return call.waveform, call.err
+}
+
+// loadWaveform 은 디스크 캐시가 원본과 같은 시간이면 그것을, 아니면 새로 계산해서 저장한다
+func loadWaveform(platform, id string, modTime time.Time) (*Waveform, error) {
+	if file, err := MediaStore.ReadFile(platform, id+".peaks.json"); err == nil {
+		var waveform Waveform
+		if err := json.Unmarshal(file, &waveform); err == nil && waveform.ModTime.Equal(modTime) {
+			return &waveform, nil
+		}
+	}
+
+	audio, err := LoadAudio(platform, id)
+	if err != nil {
+		return nil, err
+	}
+
+	waveform := ComputeWaveform(audio)
+	waveform.ModTime = modTime
+
+	if file, err := json.Marshal(waveform); err == nil {
+		if err := MediaStore.WriteFile(file, platform, id+".peaks.json"); err != nil {
+			fmt.Println(err)
+		}
+	}
+
+	return waveform, nil
+}
+
+// storeWaveform 은 waveformMu 를 잡고 부른다
+func storeWaveform(key string, waveform *Waveform) {
+	if _, ok := waveformCache[key]; !ok {
+		for len(waveformOrder) != 0 && len(waveformCache) >= waveformCacheMaxEntries {
+			delete(waveformCache, waveformOrder[0])
+			waveformOrder = waveformOrder[1:]
+		}
+
+		waveformOrder = append(waveformOrder, key)
+	}
+
+	waveformCache[key] = waveform
+}
+
+// ComputeWaveform 은 가장 촘촘한 단계를 샘플에서 직접 구하고, 나머지 단계는 그 결과를 합쳐서 만든다
+func ComputeWaveform(audio *Audio) *Waveform {
+	waveform := &Waveform{
+		Rate:     audio.Rate,
+		Duration: audio.Duration(),
+	}
+
+	base := WaveformLevels[0]
+	count := (len(audio.Samples) + base - 1) / base
+	minPeaks := make([]float32, count)
+	maxPeaks := make([]float32, count)
+
+	for i := 0; i < count; i++ {
+		end := (i + 1) * base
+		if end > len(audio.Samples) {
+			end = len(audio.Samples)
+		}
+
+		min, max := audio.Samples[i*base], audio.Samples[i*base]
+		for _, s := range audio.Samples[i*base : end] {
+			if s < min {
+				min = s
+			}
+			if s > max {
+				max = s
+			}
+		}
+
+		minPeaks[i], maxPeaks[i] = min, max
+	}
+
+	for _, samplesPerPeak := range WaveformLevels {
+		step := samplesPerPeak / base
+		level := WaveformLevel{
+			SamplesPerPeak: samplesPerPeak,
+			Min:            make([]int8, (count+step-1)/step),
+			Max:            make([]int8, (count+step-1)/step),
+		}
+
+		for i := range level.Min {
+			end := (i + 1) * step
+			if end > count {
+				end = count
+			}
+
+			min, max := minPeaks[i*step], maxPeaks[i*step]
+			for j := i * step; j < end; j++ {
+				if minPeaks[j] < min {
+					min = minPeaks[j]
+				}
+				if maxPeaks[j] > max {
+					max = maxPeaks[j]
+				}
+			}
+
+			level.Min[i] = quantizePeak(min)
+			level.Max[i] = quantizePeak(max)
+		}
+
+		waveform.Levels = append(waveform.Levels, level)
+	}
+
+	return waveform
+}
+
+func quantizePeak(v float32) int8 {
+	if v > 1 {
+		v = 1
+	} else if v < -1 {
+		v = -1
+	}
+
+	return int8(v * 127)
+}
@@ -0,0 +1,234 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"reflect"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestComputeWaveform(t *testing.T) {
+	// 첫 256개는 0.5 하나, 다음 256개는 -1 하나, 마지막 44개는 0.25 와 -0.25
+	samples := make([]float32, 556)
+	samples[10] = 0.5
+	samples[300] = -1
+	samples[520] = 0.25
+	samples[521] = -0.25
+
+	waveform := ComputeWaveform(&Audio{Rate: 8000, Samples: samples})
+
+	if waveform.Rate != 8000 || waveform.Duration != 556.0/8000 {
+		t.Errorf("rate = %d, duration = %v", waveform.Rate, waveform.Duration)
+	}
+
+	if len(waveform.Levels) != len(WaveformLevels) {
+		t.Fatalf("levels = %d, want %d", len(waveform.Levels), len(WaveformLevels))
+	}
+
+	tests := []struct {
+		level    int
+		min, max []int8
+	}{
+		{0, []int8{0, -127, -31}, []int8{63, 0, 31}},
+		{1, []int8{-127}, []int8{63}},
+		{3, []int8{-127}, []int8{63}},
+	}
+
+	for _, test := range tests {
+		level := waveform.Levels[test.level]
+		if level.SamplesPerPeak != WaveformLevels[test.level] {
+			t.Errorf("level %d: samplesPerPeak = %d", test.level, level.SamplesPerPeak)
+		}
+
+		if !reflect.DeepEqual(level.Min, test.min) || !reflect.DeepEqual(level.Max, test.max) {
+			t.Errorf("level %d: min = %v, max = %v, want %v, %v", test.level, level.Min, level.Max, test.min, test.max)
+		}
+	}
+}
+
+func TestQuantizePeak(t *testing.T) {
+	tests := []struct {
+		v    float32
+		want int8
+	}{
+		{0, 0},
+		{1, 127},
+		{-1, -127},
+		{2, 127},
+		{-2, -127},
+		{0.5, 63},
+	}
+
+	for _, test := range tests {
+		if got := quantizePeak(test.v); got != test.want {
+			t.Errorf("quantizePeak(%v) = %d, want %d", test.v, got, test.want)
+		}
+	}
+}
+
+// setupWaveform 은 testdata/tone.wav 를 MediaStore 의 local/clip.wav 로 복사하고 메모리 캐시를 비운다
+func setupWaveform(t *testing.T) string {
+	t.Helper()
+	setupAPI(t)
+
+	waveformMu.Lock()
+	waveformCache, waveformOrder = make(map[string]*Waveform), nil
+	waveformMu.Unlock()
+
+	data, err := os.ReadFile("testdata/tone.wav")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := MediaStore.WriteFile(data, "local", "clip.wav"); err != nil {
+		t.Fatal(err)
+	}
+
+	path, err := MediaPath("local", "clip")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return path
+}
+
+func TestGetWaveform(t *testing.T) {
+	path := setupWaveform(t)
+
+	waveform, err := GetWaveform("local", "clip")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 앞의 0.5초는 무음, 뒤는 0.5 크기
+	level := waveform.Levels[0]
+	if level.Max[0] != 0 || level.Max[len(level.Max)-1] != 63 || waveform.Duration != 1 {
+		t.Errorf("max = %d ... %d, duration = %v", level.Max[0], level.Max[len(level.Max)-1], waveform.Duration)
+	}
+
+	if again, err := GetWaveform("local", "clip"); err != nil || again != waveform {
+		t.Errorf("second call was not served from memory: %v", err)
+	}
+
+	// 디스크 캐시는 원본과 수정 시간이 같으면 그대로 쓴다
+	file, err := MediaStore.ReadFile("local", "clip.peaks.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var cached Waveform
+	if err := json.Unmarshal(file, &cached); err != nil {
+		t.Fatal(err)
+	}
+
+	cached.Rate = 1
+	if file, err = json.Marshal(cached); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := MediaStore.WriteFile(file, "local", "clip.peaks.json"); err != nil {
+		t.Fatal(err)
+	}
+
+	waveformMu.Lock()
+	waveformCache, waveformOrder = make(map[string]*Waveform), nil
+	waveformMu.Unlock()
+
+	if waveform, err := GetWaveform("local", "clip"); err != nil || waveform.Rate != 1 {
+		t.Fatalf("disk cache was not used: %v", err)
+	}
+
+	// 원본이 바뀌면 메모리와 디스크 캐시를 모두 버리고 다시 계산한다
+	modTime := time.Now().Add(time.Hour).Truncate(time.Second)
+	if err := os.Chtimes(path, modTime, modTime); err != nil {
+		t.Fatal(err)
+	}
+
+	waveform, err = GetWaveform("local", "clip")
+	if err != nil || waveform.Rate != 8000 || !waveform.ModTime.Equal(modTime) {
+		t.Fatalf("waveform was not recomputed after the source changed: %v", err)
+	}
+
+	file, err = MediaStore.ReadFile("local", "clip.peaks.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := json.Unmarshal(file, &cached); err != nil || cached.Rate != 8000 || !cached.ModTime.Equal(modTime) {
+		t.Errorf("disk cache was not rewritten: %v", err)
+	}
+}
+
+func TestGetWaveformMissing(t *testing.T) {
+	setupWaveform(t)
+
+	if _, err := GetWaveform("local", "missing"); err == nil {
+		t.Error("waveform of a missing file")
+	}
+
+	if err := MediaStore.WriteFile([]byte("garbage"), "local", "broken.wav"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := GetWaveform("local", "broken"); err == nil {
+		t.Error("waveform of a broken file")
+	}
+
+	waveformMu.Lock()
+	defer waveformMu.Unlock()
+
+	if len(waveformCache) != 0 || len(waveformInflight) != 0 {
+		t.Errorf("failed waveforms were kept: %d cached, %d inflight", len(waveformCache), len(waveformInflight))
+	}
+}
+
+// 같은 영상을 동시에 요청하면 한 번만 계산해서 같은 결과를 나눈다
+func TestGetWaveformConcurrent(t *testing.T) {
+	setupWaveform(t)
+
+	results := make([]*Waveform, 8)
+
+	var wg sync.WaitGroup
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			waveform, err := GetWaveform("local", "clip")
+			if err != nil {
+				t.Error(err)
+			}
+
+			results[i] = waveform
+		}(i)
+	}
+	wg.Wait()
+
+	for _, waveform := range results {
+		if waveform == nil || waveform.Duration != 1 {
+			t.Fatalf("waveform = %v", waveform)
+		}
+	}
+}
+
+func TestWaveformCacheLimit(t *testing.T) {
+	setupWaveform(t)
+
+	waveformMu.Lock()
+	defer waveformMu.Unlock()
+
+	for i := 0; i < waveformCacheMaxEntries+10; i++ {
+		storeWaveform("local/"+strconv.Itoa(i), &Waveform{})
+	}
+
+	if len(waveformCache) != waveformCacheMaxEntries || len(waveformOrder) != waveformCacheMaxEntries {
+		t.Errorf("cache = %d entries, order = %d, want %d", len(waveformCache), len(waveformOrder), waveformCacheMaxEntries)
+	}
+
+	if waveformCache["local/0"] != nil || waveformCache["local/"+strconv.Itoa(waveformCacheMaxEntries+9)] == nil {
+		t.Error("oldest entries were not evicted first")
+	}
+}